@@ -0,0 +1,93 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// AppendedRun is one comparison's entry in a file written by -output-json-append
+type AppendedRun struct {
+	Label       string `json:"label"`
+	Differences []Diff `json:"differences"`
+}
+
+// Timing of the lock taken while appending; a lock older than
+// appendLockStale was left behind by a crashed run and is removed
+const (
+	appendLockRetry   = 10 * time.Millisecond
+	appendLockTimeout = 30 * time.Second
+	appendLockStale   = time.Minute
+)
+
+// lockFile takes an exclusive lock on filePath by creating filePath.lock,
+// waiting while another process holds it. It returns a function releasing
+// the lock.
+func lockFile(filePath string) (func(), error) {
+	lockPath := filePath + ".lock"
+	deadline := time.Now().Add(appendLockTimeout)
+	for {
+		lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			lock.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > appendLockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", lockPath)
+		}
+		time.Sleep(appendLockRetry)
+	}
+}
+
+// appendDifferencesJSON adds a run to the JSON array in filePath, creating
+// the file if it is missing or empty. The file is locked while it is read
+// and rewritten so parallel runs don't lose each other's entries.
+func appendDifferencesJSON(run AppendedRun, filePath string) error {
+	unlock, err := lockFile(filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var runs []json.RawMessage
+	data, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &runs); err != nil {
+			return fmt.Errorf("%s is not a JSON array: %v", filePath, err)
+		}
+	}
+
+	differences, err := marshalDifferences(run.Differences)
+	if err != nil {
+		return err
+	}
+	entry, err := json.Marshal(struct {
+		Label       string          `json:"label"`
+		Differences json.RawMessage `json:"differences"`
+	}{run.Label, differences})
+	if err != nil {
+		return err
+	}
+
+	output, err := json.MarshalIndent(append(runs, entry), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, output, 0644)
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestAppendDifferencesJSON(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "results.json")
+
+	// An empty file is started as a new array, like a missing one
+	if err := os.WriteFile(filePath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	diffs := []Diff{{Path: "a", Type: ValueMismatch, Value1: 1.0, Value2: 2.0}}
+	if err := appendDifferencesJSON(AppendedRun{Label: "first", Differences: diffs}, filePath); err != nil {
+		t.Fatalf("first append: %v", err)
+	}
+	if err := appendDifferencesJSON(AppendedRun{Label: "second", Differences: []Diff{}}, filePath); err != nil {
+		t.Fatalf("second append: %v", err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runs []struct {
+		Label       string                   `json:"label"`
+		Differences []map[string]interface{} `json:"differences"`
+	}
+	if err := json.Unmarshal(data, &runs); err != nil {
+		t.Fatalf("appended file is not valid JSON: %v\n%s", err, data)
+	}
+	if len(runs) != 2 || runs[0].Label != "first" || runs[1].Label != "second" {
+		t.Fatalf("unexpected runs: %s", data)
+	}
+	if len(runs[0].Differences) != 1 || runs[0].Differences[0]["path"] != "a" {
+		t.Errorf("first run differences = %v", runs[0].Differences)
+	}
+	if len(runs[1].Differences) != 0 {
+		t.Errorf("second run differences = %v, want none", runs[1].Differences)
+	}
+	if _, err := os.Stat(filePath + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+
+	// A file holding something other than an array is not overwritten
+	if err := os.WriteFile(filePath, []byte(`{"a": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendDifferencesJSON(AppendedRun{Label: "third"}, filePath); err == nil {
+		t.Error("expected an error appending to a JSON object")
+	}
+}
+
+func TestAppendDifferencesJSONConcurrent(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "results.json")
+
+	const runs = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- appendDifferencesJSON(AppendedRun{Label: fmt.Sprintf("run %d", i), Differences: []Diff{}}, filePath)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []AppendedRun
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("appended file is not valid JSON: %v", err)
+	}
+	if len(entries) != runs {
+		t.Errorf("got %d entries, want %d", len(entries), runs)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ArchiveEntryDiff is the result of comparing one entry name across two archives
+type ArchiveEntryDiff struct {
+	Name        string
+	OnlyIn      int    // 1 or 2 if the entry exists in only one archive, 0 if in both
+	Differences []Diff // Differences between the two entries
+	Err         error  // Set if either entry could not be parsed
+}
+
+// isArchivePath reports whether a file name looks like a zip or tar archive
+func isArchivePath(filePath string) bool {
+	name := strings.ToLower(filePath)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// readArchive reads the JSON entries of a zip, tar or gzipped tar archive,
+// keyed by entry name. The format is detected from the file contents.
+// Only entries ending in .json (or .xml when xml is set) are included.
+func readArchive(filePath string, xml bool) (map[string][]byte, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFileRead, err)
+	}
+
+	ext := ".json"
+	if xml {
+		ext = ".xml"
+	}
+	wanted := func(name string) bool {
+		return strings.HasSuffix(strings.ToLower(name), ext)
+	}
+
+	// Zip archives start with a local file header
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return readZipEntries(data, wanted)
+	}
+
+	// Anything else is read as a tar stream, gunzipping it first if needed
+	var r io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip archive: %v", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return readTarEntries(r, wanted)
+}
+
+// readZipEntries reads the wanted regular files of a zip archive
+func readZipEntries(data []byte, wanted func(string) bool) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %v", err)
+	}
+
+	entries := make(map[string][]byte)
+	for _, file := range zr.File {
+		if !file.Mode().IsRegular() || !wanted(file.Name) {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %v", file.Name, err)
+		}
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", file.Name, err)
+		}
+		entries[path.Clean(file.Name)] = content
+	}
+
+	return entries, nil
+}
+
+// readTarEntries reads the wanted regular files of a tar stream
+func readTarEntries(r io.Reader, wanted func(string) bool) (map[string][]byte, error) {
+	tr := tar.NewReader(r)
+
+	entries := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar archive: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg || !wanted(header.Name) {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", header.Name, err)
+		}
+		entries[path.Clean(header.Name)] = content
+	}
+
+	return entries, nil
+}
+
+// compareArchives pairs the entries of two archives by name and compares each
+// pair. Entries are returned in name order; identical pairs are included with
+// no differences.
+func compareArchives(entries1, entries2 map[string][]byte, readOptions ReadOptions, options CompareOptions) []ArchiveEntryDiff {
+	names := make([]string, 0, len(entries1)+len(entries2))
+	for name := range entries1 {
+		names = append(names, name)
+	}
+	for name := range entries2 {
+		if _, ok := entries1[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// Per-entry validation messages would only clutter the report
+	readOptions.Concise = true
+
+	results := make([]ArchiveEntryDiff, 0, len(names))
+	for _, name := range names {
+		data1, ok1 := entries1[name]
+		data2, ok2 := entries2[name]
+		if !ok2 {
+			results = append(results, ArchiveEntryDiff{Name: name, OnlyIn: 1})
+			continue
+		}
+		if !ok1 {
+			results = append(results, ArchiveEntryDiff{Name: name, OnlyIn: 2})
+			continue
+		}
+
+		file1, err := parseJSONData(data1, name, readOptions)
+		if err != nil {
+			results = append(results, ArchiveEntryDiff{Name: name, Err: fmt.Errorf("first archive: %w", err)})
+			continue
+		}
+		file2, err := parseJSONData(data2, name, readOptions)
+		if err != nil {
+			results = append(results, ArchiveEntryDiff{Name: name, Err: fmt.Errorf("second archive: %w", err)})
+			continue
+		}
+
+		var differences []Diff
+		if readOptions.MultiDoc {
+			differences = compareDocuments(file1.Documents, file2.Documents, options)
+		} else {
+			differences = findDifferencesWithOptions(file1.Data, file2.Data, "", options)
+		}
+		results = append(results, ArchiveEntryDiff{Name: name, Differences: differences})
+	}
+
+	return results
+}
+
+// archiveEntryFails reports whether an entry result should make the comparison
+// fail: it is missing from one archive, could not be parsed, or has a
+// difference at or above the minimum severity
+func archiveEntryFails(result ArchiveEntryDiff, min Severity) bool {
+	return result.OnlyIn != 0 || result.Err != nil || hasSeverity(result.Differences, min)
+}
+
+// formatArchiveEntry formats the result for one archive entry under a header
+// naming the entry, or returns "" if the entries are identical
+func formatArchiveEntry(result ArchiveEntryDiff) string {
+	var sb strings.Builder
+	switch {
+	case result.OnlyIn == 1:
+		fmt.Fprintf(&sb, "== %s ==\nentry exists only in first archive\n", result.Name)
+	case result.OnlyIn == 2:
+		fmt.Fprintf(&sb, "== %s ==\nentry exists only in second archive\n", result.Name)
+	case result.Err != nil:
+		fmt.Fprintf(&sb, "== %s ==\nerror with %v\n", result.Name, result.Err)
+	case len(result.Differences) > 0:
+		fmt.Fprintf(&sb, "== %s ==\n", result.Name)
+		for _, diff := range result.Differences {
+			sb.WriteString(formatDiffText(diff))
+		}
+	}
+	return sb.String()
+}
+
+// formatChangedEntry formats an archive entry as one line of a name-only
+// report: "D name" if only in the first archive, "A name" if only in the
+// second, "M name" if the entries differ and "E name" if either could not be
+// parsed. It returns "" if the entries are identical.
+func formatChangedEntry(result ArchiveEntryDiff) string {
+	switch {
+	case result.OnlyIn == 1:
+		return fmt.Sprintf("D %s\n", result.Name)
+	case result.OnlyIn == 2:
+		return fmt.Sprintf("A %s\n", result.Name)
+	case result.Err != nil:
+		return fmt.Sprintf("E %s\n", result.Name)
+	case len(result.Differences) > 0:
+		return fmt.Sprintf("M %s\n", result.Name)
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestZip(t *testing.T, path string, files map[string]string) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create zip entry: %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+}
+
+func writeTestTarGz(t *testing.T, path string, files map[string]string) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write tar: %v", err)
+	}
+}
+
+func TestCompareArchives(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "a.zip")
+	tarPath := filepath.Join(dir, "b.tar.gz")
+
+	writeTestZip(t, zipPath, map[string]string{
+		"same.json":        `{"a": 1}`,
+		"data/change.json": `{"name": "John"}`,
+		"removed.json":     `{}`,
+		"bad.json":         `{"a": 1}`,
+		"README.md":        `not json`,
+	})
+	writeTestTarGz(t, tarPath, map[string]string{
+		"same.json":        `{"a": 1}`,
+		"data/change.json": `{"name": "Jane"}`,
+		"added.json":       `[]`,
+		"bad.json":         `{"a": `,
+	})
+
+	if !isArchivePath(zipPath) || !isArchivePath(tarPath) || isArchivePath("a.json") {
+		t.Error("Unexpected result from isArchivePath")
+	}
+
+	entries1, err := readArchive(zipPath, false)
+	if err != nil {
+		t.Fatalf("Failed to read zip: %v", err)
+	}
+	entries2, err := readArchive(tarPath, false)
+	if err != nil {
+		t.Fatalf("Failed to read tar: %v", err)
+	}
+	if _, ok := entries1["README.md"]; ok {
+		t.Error("Expected non-JSON entries to be skipped")
+	}
+
+	results := compareArchives(entries1, entries2, ReadOptions{}, CompareOptions{})
+	names := []string{"added.json", "bad.json", "data/change.json", "removed.json", "same.json"}
+	if len(results) != len(names) {
+		t.Fatalf("Expected %d entries, got %v", len(names), results)
+	}
+	for i, result := range results {
+		if result.Name != names[i] {
+			t.Errorf("Expected entry %d to be %s, got %s", i, names[i], result.Name)
+		}
+	}
+
+	if results[0].OnlyIn != 2 || results[3].OnlyIn != 1 {
+		t.Errorf("Expected entries only in one archive, got %v and %v", results[0], results[3])
+	}
+	if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "second archive") {
+		t.Errorf("Expected a parse error for the second archive, got %v", results[1].Err)
+	}
+	if len(results[2].Differences) != 1 || results[2].Differences[0].Path != "name" {
+		t.Errorf("Expected a single difference at name, got %v", results[2].Differences)
+	}
+	if archiveEntryFails(results[4], SeverityInfo) || formatArchiveEntry(results[4]) != "" {
+		t.Error("Expected identical entries to pass and print nothing")
+	}
+
+	expected := "== data/change.json ==\nname: value mismatch\n- John\n+ Jane\n"
+	if got := formatArchiveEntry(results[2]); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if got := formatArchiveEntry(results[0]); got != "== added.json ==\nentry exists only in second archive\n" {
+		t.Errorf("Unexpected entry output %q", got)
+	}
+
+	var changed strings.Builder
+	for _, result := range results {
+		changed.WriteString(formatChangedEntry(result))
+	}
+	if got := changed.String(); got != "A added.json\nE bad.json\nM data/change.json\nD removed.json\n" {
+		t.Errorf("Unexpected changed entries %q", got)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ArrayGroup rolls up the differences inside one array, e.g.
+// "hobbies: 3 added, 2 removed, 5 changed"
+type ArrayGroup struct {
+	Path    string           // Path of the array
+	Added   int              // Elements only in the second file
+	Removed int              // Elements only in the first file
+	Changed int              // Elements present in both files that differ
+	ByType  map[DiffType]int // Differences in the array, by type
+}
+
+// groupArrayDiffs splits differences into groups by the array they are in and
+// the differences that aren't in an array. A difference nested in several
+// arrays belongs to the innermost one. Groups are sorted by path.
+func groupArrayDiffs(differences []Diff) ([]ArrayGroup, []Diff) {
+	groups := make(map[string]*ArrayGroup)
+	group := func(path string) *ArrayGroup {
+		if groups[path] == nil {
+			groups[path] = &ArrayGroup{Path: path, ByType: make(map[DiffType]int)}
+		}
+		return groups[path]
+	}
+
+	// Elements are counted once however many differences they hold
+	changed := make(map[string]bool)
+	var rest []Diff
+	for _, diff := range differences {
+		switch diff.Type {
+		case ArrayLength:
+			// Positional arrays report the elements past the shorter one by length
+			g := group(diff.Path)
+			len1, _ := diff.Value1.(int)
+			len2, _ := diff.Value2.(int)
+			if len2 > len1 {
+				g.Added += len2 - len1
+			} else {
+				g.Removed += len1 - len2
+			}
+			g.ByType[diff.Type]++
+			continue
+		case ArrayDiffsTruncated:
+			g := group(diff.Path)
+			remaining, _ := diff.Value1.(int)
+			g.Changed += remaining
+			g.ByType[diff.Type]++
+			continue
+		}
+
+		array, element, ok := arrayElement(diff.Path)
+		if !ok {
+			rest = append(rest, diff)
+			continue
+		}
+		g := group(array)
+		switch {
+		case diff.Path == element && diff.Type == KeyOnlyInSecond:
+			g.Added++
+		case diff.Path == element && diff.Type == KeyOnlyInFirst:
+			g.Removed++
+		case !changed[element]:
+			changed[element] = true
+			g.Changed++
+		}
+		g.ByType[diff.Type]++
+	}
+
+	var sorted []ArrayGroup
+	for _, g := range groups {
+		sorted = append(sorted, *g)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	return sorted, rest
+}
+
+// arrayElement returns the path of the innermost array element containing
+// path, or path itself if it is an element, along with the array's path
+func arrayElement(path string) (string, string, bool) {
+	for p := path; p != ""; p = parentPath(p) {
+		if strings.HasSuffix(p, "]") {
+			return parentPath(p), p, true
+		}
+	}
+	return "", "", false
+}
+
+// formatArrayGroup renders an array's rollup as one line, e.g.
+// "hobbies: 3 added, 2 removed, 5 changed (value_mismatch: 5, array_length: 1)"
+func formatArrayGroup(g ArrayGroup) string {
+	var counts []string
+	for _, count := range []struct {
+		n    int
+		kind string
+	}{{g.Added, "added"}, {g.Removed, "removed"}, {g.Changed, "changed"}} {
+		if count.n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", count.n, count.kind))
+		}
+	}
+
+	// List types in their declaration order so the line is stable
+	var types []string
+	for t := ValueMismatch; t.String() != "unknown"; t++ {
+		if g.ByType[t] > 0 {
+			types = append(types, fmt.Sprintf("%s: %d", t, g.ByType[t]))
+		}
+	}
+	return fmt.Sprintf("%s: %s (%s)\n", displayPath(g.Path), strings.Join(counts, ", "), strings.Join(types, ", "))
+}
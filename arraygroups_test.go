@@ -0,0 +1,54 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupArrayDiffs(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"name":    "a",
+		"hobbies": []interface{}{"x", "y", map[string]interface{}{"k": 1.0, "j": 2.0}},
+		"tags":    []interface{}{1.0, 2.0, 3.0},
+	}
+	obj2 := map[string]interface{}{
+		"name":    "b",
+		"hobbies": []interface{}{"x", "Y", map[string]interface{}{"k": 2.0, "j": 3.0}, "w", "v"},
+		"tags":    []interface{}{1.0},
+	}
+
+	groups, rest := groupArrayDiffs(findDifferencesWithOptions(obj1, obj2, "", CompareOptions{}))
+	want := []ArrayGroup{
+		{Path: "hobbies", Added: 2, Changed: 2, ByType: map[DiffType]int{ValueMismatch: 3, ArrayLength: 1}},
+		{Path: "tags", Removed: 2, ByType: map[DiffType]int{ArrayLength: 1}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("groups = %+v, want %+v", groups, want)
+	}
+	if len(rest) != 1 || rest[0].Path != "name" {
+		t.Errorf("rest = %v, want only name", rest)
+	}
+
+	if got, want := formatArrayGroup(groups[0]), "hobbies: 2 added, 2 changed (value_mismatch: 3, array_length: 1)\n"; got != want {
+		t.Errorf("formatArrayGroup = %q, want %q", got, want)
+	}
+}
+
+func TestGroupArrayDiffsUnordered(t *testing.T) {
+	arr1 := []interface{}{"a", "b", "c"}
+	arr2 := []interface{}{"c", "d", "a", "e"}
+
+	groups, rest := groupArrayDiffs(findDifferencesWithOptions(arr1, arr2, "", CompareOptions{IgnoreOrderPaths: []string{""}}))
+	if len(rest) != 0 || len(groups) != 1 {
+		t.Fatalf("got groups %+v and rest %v, want one group", groups, rest)
+	}
+	if g := groups[0]; g.Path != "" || g.Added != 2 || g.Removed != 1 || g.Changed != 0 {
+		t.Errorf("group = %+v, want 2 added and 1 removed at the root", g)
+	}
+	if got, want := formatArrayGroup(groups[0]), ".: 2 added, 1 removed (key_only_in_first: 1, key_only_in_second: 2)\n"; got != want {
+		t.Errorf("formatArrayGroup = %q, want %q", got, want)
+	}
+}
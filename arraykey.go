@@ -0,0 +1,163 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ArrayKeyChoice records the key inferred to match the elements of an array
+type ArrayKeyChoice struct {
+	Path    string
+	Key     string
+	Matched int // Number of elements found in both arrays
+}
+
+// arrayKeyIndex maps each element's identity value, encoded as JSON, to its index.
+// It fails if an element is not an object, lacks key, has a non-scalar value
+// there, or shares its value with another element.
+func arrayKeyIndex(arr []interface{}, key string) (map[string]int, bool) {
+	index := make(map[string]int, len(arr))
+	for i, elem := range arr {
+		obj, ok := elem.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		val, ok := obj[key]
+		if !ok || val == nil || isComplex(val) {
+			return nil, false
+		}
+		encoded, err := json.Marshal(val)
+		if err != nil {
+			return nil, false
+		}
+		if _, dup := index[string(encoded)]; dup {
+			return nil, false
+		}
+		index[string(encoded)] = i
+	}
+	return index, true
+}
+
+// inferArrayKey finds the field that best identifies the elements of two
+// arrays of objects: present in every element with a unique scalar value in
+// each array, and with the most values found in both arrays. At least half
+// of the shorter array must match for a key to be chosen.
+func inferArrayKey(arr1, arr2 []interface{}) (string, int, bool) {
+	if len(arr1) == 0 || len(arr2) == 0 {
+		return "", 0, false
+	}
+	first, ok := arr1[0].(map[string]interface{})
+	if !ok {
+		return "", 0, false
+	}
+
+	// Candidates are the fields of the first element, tried in sorted order so ties are stable
+	candidates := make([]string, 0, len(first))
+	for key := range first {
+		candidates = append(candidates, key)
+	}
+	sort.Strings(candidates)
+
+	bestKey, bestMatched := "", 0
+	for _, key := range candidates {
+		index1, ok := arrayKeyIndex(arr1, key)
+		if !ok {
+			continue
+		}
+		index2, ok := arrayKeyIndex(arr2, key)
+		if !ok {
+			continue
+		}
+
+		matched := 0
+		for id := range index1 {
+			if _, ok := index2[id]; ok {
+				matched++
+			}
+		}
+		if matched > bestMatched {
+			bestKey, bestMatched = key, matched
+		}
+	}
+
+	shorter := len(arr1)
+	if len(arr2) < shorter {
+		shorter = len(arr2)
+	}
+	if bestMatched == 0 || bestMatched*2 < shorter {
+		return "", 0, false
+	}
+	return bestKey, bestMatched, true
+}
+
+// compareKeyedArrays compares two arrays of objects by matching elements with
+// the same value for key instead of by position. Matched and removed elements
+// are reported at their index in the first array, added elements at their
+// index in the second.
+func compareKeyedArrays(arr1, arr2 []interface{}, key, path string, options CompareOptions) []Diff {
+	differences := []Diff{}
+	index1, _ := arrayKeyIndex(arr1, key)
+	index2, _ := arrayKeyIndex(arr2, key)
+
+	for i, elem1 := range arr1 {
+		newPath := fmt.Sprintf("%s[%d]", path, i)
+		id, _ := json.Marshal(elem1.(map[string]interface{})[key])
+		j, ok := index2[string(id)]
+		if !ok {
+			differences = append(differences, Diff{
+				Path:       newPath,
+				Type:       KeyOnlyInFirst,
+				Value1:     elem1,
+				Value2:     nil,
+				ParentType: ParentArray,
+			})
+			continue
+		}
+		if !valuesEqual(elem1, arr2[j], newPath, options) {
+			differences = append(differences, findDifferencesWithParent(elem1, arr2[j], newPath, ParentArray, options)...)
+		}
+	}
+
+	for j, elem2 := range arr2 {
+		id, _ := json.Marshal(elem2.(map[string]interface{})[key])
+		if _, ok := index1[string(id)]; !ok {
+			differences = append(differences, Diff{
+				Path:       fmt.Sprintf("%s[%d]", path, j),
+				Type:       KeyOnlyInSecond,
+				Value1:     nil,
+				Value2:     elem2,
+				ParentType: ParentArray,
+			})
+		}
+	}
+
+	return differences
+}
+
+// prefixArrayKeyChoices prepends prefix to the path of every choice
+func prefixArrayKeyChoices(choices []ArrayKeyChoice, prefix string) []ArrayKeyChoice {
+	for i := range choices {
+		choices[i].Path = prefixPath(prefix, choices[i].Path)
+	}
+	return choices
+}
+
+// formatArrayKeyReport renders which key was chosen to match each array
+func formatArrayKeyReport(choices []ArrayKeyChoice) string {
+	if len(choices) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\nMatched the elements of %d arrays by an inferred key:\n", len(choices))
+	for _, choice := range choices {
+		fmt.Fprintf(&sb, "%s: matched by %s (%d elements in both)\n", displayPath(choice.Path), choice.Key, choice.Matched)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"testing"
+)
+
+func TestInferArrayKey(t *testing.T) {
+	arr1 := []interface{}{
+		map[string]interface{}{"id": 1.0, "type": "user", "name": "John"},
+		map[string]interface{}{"id": 2.0, "type": "user", "name": "Jane"},
+		map[string]interface{}{"id": 3.0, "type": "admin", "name": "Bob"},
+	}
+	arr2 := []interface{}{
+		map[string]interface{}{"id": 4.0, "type": "user", "name": "Alice"},
+		map[string]interface{}{"id": 1.0, "type": "user", "name": "John"},
+		map[string]interface{}{"id": 2.0, "type": "user", "name": "Janet"},
+	}
+
+	// "type" isn't unique and "name" matches fewer elements than "id"
+	key, matched, ok := inferArrayKey(arr1, arr2)
+	if !ok || key != "id" || matched != 2 {
+		t.Fatalf("Expected id with 2 matches, got %q, %d, %v", key, matched, ok)
+	}
+
+	// Arrays without a shared unique field have no key
+	if _, _, ok := inferArrayKey([]interface{}{1.0, 2.0}, []interface{}{2.0, 1.0}); ok {
+		t.Error("Expected no key for arrays of numbers")
+	}
+	unrelated := []interface{}{map[string]interface{}{"id": 7.0}, map[string]interface{}{"id": 8.0}}
+	if _, _, ok := inferArrayKey(arr1, unrelated); ok {
+		t.Error("Expected no key when too few elements match")
+	}
+}
+
+func TestAutoArrayKey(t *testing.T) {
+	obj1 := map[string]interface{}{"users": []interface{}{
+		map[string]interface{}{"id": "a", "age": 30.0},
+		map[string]interface{}{"id": "b", "age": 40.0},
+		map[string]interface{}{"id": "c", "age": 50.0},
+	}}
+	obj2 := map[string]interface{}{"users": []interface{}{
+		map[string]interface{}{"id": "new", "age": 20.0},
+		map[string]interface{}{"id": "a", "age": 30.0},
+		map[string]interface{}{"id": "b", "age": 41.0},
+	}}
+
+	// Positionally, every element differs
+	if diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{}); len(diffs) != 6 {
+		t.Errorf("Expected 6 positional differences, got %v", diffs)
+	}
+
+	var choices []ArrayKeyChoice
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{AutoArrayKey: true, ArrayKeys: &choices})
+	expected := []string{
+		"users[1].age: value mismatch - 40 vs 41",
+		"users[2]: key exists only in first file",
+		"users[0]: key exists only in second file",
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d differences, got %v", len(expected), diffs)
+	}
+	for i, diff := range diffs {
+		if got := formatDiff(diff); got != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], got)
+		}
+	}
+
+	if len(choices) != 1 || choices[0].Path != "users" || choices[0].Key != "id" {
+		t.Fatalf("Expected users to be matched by id, got %v", choices)
+	}
+	report := formatArrayKeyReport(prefixArrayKeyChoices(choices, "data"))
+	if report != "\nMatched the elements of 1 arrays by an inferred key:\ndata.users: matched by id (2 elements in both)\n\n" {
+		t.Errorf("Unexpected report %q", report)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// diffKey identifies a difference by path, type and values.
+// Values are compared by their JSON encoding so that differences loaded from a
+// baseline file match freshly computed ones regardless of Go number types.
+func diffKey(diff Diff) string {
+	value1, _ := json.Marshal(diff.Value1)
+	value2, _ := json.Marshal(diff.Value2)
+	return diff.Path + "\x00" + diff.Type.String() + "\x00" + string(value1) + "\x00" + string(value2)
+}
+
+// loadBaseline reads previously accepted differences from a JSON file written
+// in the -output-json format. A missing file is treated as an empty baseline.
+func loadBaseline(filePath string) ([]Diff, error) {
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %v", err)
+	}
+
+	var baseline []Diff
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("invalid baseline: %v", err)
+	}
+	return baseline, nil
+}
+
+// filterBaseline removes differences that are already accepted in the baseline
+func filterBaseline(differences, baseline []Diff) []Diff {
+	if len(baseline) == 0 {
+		return differences
+	}
+
+	known := make(map[string]bool, len(baseline))
+	for _, diff := range baseline {
+		known[diffKey(diff)] = true
+	}
+
+	filtered := []Diff{}
+	for _, diff := range differences {
+		if !known[diffKey(diff)] {
+			filtered = append(filtered, diff)
+		}
+	}
+	return filtered
+}
+
+// appendBaseline adds accepted differences to the baseline file, skipping any
+// that are already recorded
+func appendBaseline(filePath string, accepted []Diff) error {
+	baseline, err := loadBaseline(filePath)
+	if err != nil {
+		return err
+	}
+
+	baseline = append(baseline, filterBaseline(accepted, baseline)...)
+	return writeDifferencesJSON(baseline, filePath)
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	file1, err := ReadAndValidateJSON("examples/example1.json", true)
+	if err != nil {
+		t.Fatalf("Failed to read examples/example1.json: %v", err)
+	}
+
+	file5, err := ReadAndValidateJSON("examples/example5.json", true)
+	if err != nil {
+		t.Fatalf("Failed to read examples/example5.json: %v", err)
+	}
+
+	diffs := findDifferencesWithOptions(file1.Data, file5.Data, "", CompareOptions{})
+	baselinePath := filepath.Join(t.TempDir(), "baseline.json")
+
+	// A missing baseline file filters nothing
+	baseline, err := loadBaseline(baselinePath)
+	if err != nil {
+		t.Fatalf("Failed to load missing baseline: %v", err)
+	}
+	if len(filterBaseline(diffs, baseline)) != len(diffs) {
+		t.Error("Expected a missing baseline to keep all differences")
+	}
+
+	// Accepted differences are suppressed on the next run, including numeric values
+	if err := appendBaseline(baselinePath, diffs[:3]); err != nil {
+		t.Fatalf("Failed to write baseline: %v", err)
+	}
+	if err := appendBaseline(baselinePath, diffs[:4]); err != nil {
+		t.Fatalf("Failed to append to baseline: %v", err)
+	}
+	baseline, err = loadBaseline(baselinePath)
+	if err != nil {
+		t.Fatalf("Failed to load baseline: %v", err)
+	}
+	if len(baseline) != 4 {
+		t.Errorf("Expected 4 baseline entries without duplicates, got %d", len(baseline))
+	}
+
+	remaining := filterBaseline(diffs, baseline)
+	if len(remaining) != len(diffs)-4 {
+		t.Errorf("Expected %d remaining differences, got %d: %v", len(diffs)-4, len(remaining), remaining)
+	}
+}
+
+func TestReviewDifferences(t *testing.T) {
+	diffs := []Diff{
+		{Path: "address.city", Type: ValueMismatch, Value1: "New York", Value2: "Boston"},
+		{Path: "address.street", Type: ValueMismatch, Value1: "Main St", Value2: "Elm St"},
+		{Path: "address.zip", Type: KeyOnlyInFirst, Value1: "10001"},
+		{Path: "age", Type: ValueMismatch, Value1: 30.0, Value2: 31.0},
+		{Path: "hobbies[1]", Type: ValueMismatch, Value1: "cycling", Value2: "swimming"},
+		{Path: "name", Type: ValueMismatch, Value1: "John", Value2: "Jane"},
+	}
+
+	// Accept the first, skip the rest of address, step over age, accept hobbies[1], then quit
+	var out bytes.Buffer
+	accepted := reviewDifferences(diffs, strings.NewReader("a\ns\n\na\nq\na\n"), &out)
+
+	if len(accepted) != 2 || accepted[0].Path != "address.city" || accepted[1].Path != "hobbies[1]" {
+		t.Errorf("Expected address.city and hobbies[1] to be accepted, got %v", accepted)
+	}
+	if strings.Contains(out.String(), "address.zip") {
+		t.Error("Expected the rest of address to be skipped")
+	}
+
+	// Closing the input ends the review
+	out.Reset()
+	accepted = reviewDifferences(diffs, strings.NewReader("a\n"), &out)
+	if len(accepted) != 1 || !strings.Contains(out.String(), "[2/6]") || strings.Contains(out.String(), "[3/6]") {
+		t.Errorf("Expected the review to stop when input closes, got %v", accepted)
+	}
+}
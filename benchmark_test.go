@@ -0,0 +1,225 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// generateDocument builds a representative document: an object holding an
+// array of n user records, each with nested objects, arrays and mixed leaf types.
+// Records whose index is a multiple of changeEvery get different values
+// (0 for no changes).
+func generateDocument(n, changeEvery int) map[string]interface{} {
+	users := make([]interface{}, n)
+	for i := range users {
+		changed := changeEvery > 0 && i%changeEvery == 0
+		age := float64(20 + i%50)
+		city := "New York"
+		if changed {
+			age++
+			city = "Boston"
+		}
+
+		users[i] = map[string]interface{}{
+			"id":     float64(i),
+			"name":   fmt.Sprintf("User %d", i),
+			"email":  fmt.Sprintf("user%d@example.com", i),
+			"age":    age,
+			"active": i%2 == 0,
+			"score":  float64(i) * 1.5,
+			"notes":  nil,
+			"address": map[string]interface{}{
+				"street": fmt.Sprintf("%d Main St", i),
+				"city":   city,
+				"zip":    fmt.Sprintf("%05d", i),
+			},
+			"tags": []interface{}{"alpha", "beta", fmt.Sprintf("tag%d", i%10)},
+		}
+	}
+
+	return map[string]interface{}{
+		"version": "1.0.0",
+		"count":   float64(n),
+		"users":   users,
+	}
+}
+
+func BenchmarkFindDifferencesIdentical(b *testing.B) {
+	doc1 := generateDocument(1000, 0)
+	doc2 := generateDocument(1000, 0)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FindDifferences(doc1, doc2, "", false, false, false, false, false, false, nil, nil, 0)
+	}
+}
+
+func BenchmarkFindDifferencesChanged(b *testing.B) {
+	doc1 := generateDocument(1000, 0)
+	doc2 := generateDocument(1000, 10)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FindDifferences(doc1, doc2, "", false, false, false, false, false, false, nil, nil, 0)
+	}
+}
+
+func BenchmarkFindDifferencesMostlyIdentical(b *testing.B) {
+	doc1 := generateDocument(1000, 0)
+	doc2 := generateDocument(1000, 1000) // Only the first record differs
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FindDifferences(doc1, doc2, "", false, false, false, false, false, false, nil, nil, 0)
+	}
+}
+
+func BenchmarkFindDifferencesMostlyIdenticalNested(b *testing.B) {
+	// Wrap the records in ten levels of objects so the one difference is deep
+	nest := func(doc map[string]interface{}) interface{} {
+		var value interface{} = doc
+		for i := 0; i < 10; i++ {
+			value = map[string]interface{}{"meta": map[string]interface{}{"level": float64(i)}, "data": value}
+		}
+		return value
+	}
+	doc1 := nest(generateDocument(1000, 0))
+	doc2 := nest(generateDocument(1000, 1000))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		findDifferencesWithOptions(doc1, doc2, "", CompareOptions{})
+	}
+}
+
+// generateRepeatedDocument builds an array of n orders that all share the
+// same warehouse record, built afresh for each order as a parser would. In
+// the second document the warehouse has moved, so every order differs in
+// the same way.
+func generateRepeatedDocument(n int, moved bool) []interface{} {
+	warehouse := func() map[string]interface{} {
+		city := "Leeds"
+		if moved {
+			city = "York"
+		}
+		shelves := make([]interface{}, 20)
+		for i := range shelves {
+			shelves[i] = map[string]interface{}{"Aisle": float64(i), "Label": fmt.Sprintf("Shelf %d", i), "City": city}
+		}
+		return map[string]interface{}{
+			"Name":    "North",
+			"Address": map[string]interface{}{"Street": "1 Mill Lane", "City": city, "Zip": "LS1 1AA"},
+			"Shelves": shelves,
+		}
+	}
+
+	orders := make([]interface{}, n)
+	for i := range orders {
+		orders[i] = map[string]interface{}{"id": float64(i), "warehouse": warehouse()}
+	}
+	return orders
+}
+
+func benchmarkRepeatedSubtrees(b *testing.B, cache bool) {
+	doc1 := generateRepeatedDocument(1000, false)
+	doc2 := generateRepeatedDocument(1000, true)
+	options := CompareOptions{IgnoreCase: true, IgnoreCaseValues: true, CacheSubtrees: cache}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		findDifferencesWithOptions(doc1, doc2, "", options)
+	}
+}
+
+func BenchmarkFindDifferencesRepeatedSubtrees(b *testing.B) {
+	benchmarkRepeatedSubtrees(b, false)
+}
+
+func BenchmarkFindDifferencesRepeatedSubtreesCached(b *testing.B) {
+	benchmarkRepeatedSubtrees(b, true)
+}
+
+func BenchmarkFindDifferencesWithOptions(b *testing.B) {
+	doc1 := generateDocument(1000, 0)
+	doc2 := generateDocument(1000, 10)
+	options := CompareOptions{
+		IgnoreCase:           true,
+		IgnoreCaseValues:     true,
+		IgnoreNumericType:    true,
+		LevenshteinKeys:      map[string]bool{"version": true},
+		LevenshteinThreshold: 3,
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		findDifferencesWithOptions(doc1, doc2, "", options)
+	}
+}
+
+func BenchmarkCompareMaps(b *testing.B) {
+	user1 := generateDocument(1, 0)["users"].([]interface{})[0].(map[string]interface{})
+	user2 := generateDocument(1, 1)["users"].([]interface{})[0].(map[string]interface{})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		compareMaps(user1, user2, "user", CompareOptions{})
+	}
+}
+
+func BenchmarkCompareArrays(b *testing.B) {
+	arr1 := make([]interface{}, 10000)
+	arr2 := make([]interface{}, 10000)
+	for i := range arr1 {
+		arr1[i] = float64(i)
+		arr2[i] = float64(i + i%2)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		compareArrays(arr1, arr2, "values", ParentRoot, CompareOptions{})
+	}
+}
+
+func BenchmarkCompareLeaves(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		compareLeaves("New York", "Boston", "city", ParentObject, CompareOptions{})
+	}
+}
+
+func TestTraversalUnits(t *testing.T) {
+	// compareMaps reports keys by their path under the given prefix
+	diffs := compareMaps(
+		map[string]interface{}{"a": 1.0, "b": 2.0},
+		map[string]interface{}{"a": 1.0, "c": 3.0},
+		"root",
+		CompareOptions{},
+	)
+	if len(diffs) != 2 || diffs[0].Path != "root.b" || diffs[0].Type != KeyOnlyInFirst ||
+		diffs[1].Path != "root.c" || diffs[1].Type != KeyOnlyInSecond {
+		t.Errorf("Unexpected compareMaps differences: %v", diffs)
+	}
+
+	// compareArrays reports the length and element differences with the given parent
+	diffs = compareArrays([]interface{}{1.0, 2.0}, []interface{}{1.0, 3.0, 4.0}, "list", ParentObject, CompareOptions{})
+	if len(diffs) != 2 || diffs[0].Type != ArrayLength || diffs[0].ParentType != ParentObject ||
+		diffs[1].Path != "list[1]" || diffs[1].ParentType != ParentArray {
+		t.Errorf("Unexpected compareArrays differences: %v", diffs)
+	}
+
+	// compareLeaves applies the value options
+	if diffs := compareLeaves("Boston", "boston", "city", ParentObject, CompareOptions{IgnoreCaseValues: true}); len(diffs) != 0 {
+		t.Errorf("Expected no differences ignoring case, got %v", diffs)
+	}
+	if diffs := compareLeaves("Boston", "boston", "city", ParentObject, CompareOptions{KeysOnly: true}); len(diffs) != 0 {
+		t.Errorf("Expected leaves to be skipped in keys-only mode, got %v", diffs)
+	}
+
+	// The generated fixtures differ only where requested
+	diffs = findDifferencesWithOptions(generateDocument(100, 0), generateDocument(100, 10), "", CompareOptions{})
+	if len(diffs) != 20 {
+		t.Errorf("Expected 20 differences between the fixtures, got %d", len(diffs))
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// Bounds on the memory used by CacheSubtrees: each map is cleared when it
+// reaches its limit
+const (
+	maxCachedComparisons = 4096
+	maxCachedHashes      = 1 << 16
+)
+
+// comparisonKey identifies one comparison of two objects or arrays by the
+// hashes of their content. The depth and parent type are included because
+// they change the differences reported for the same pair of values.
+type comparisonKey struct {
+	hash1, hash2 uint64
+	depth        int
+	parent       ParentType
+}
+
+// hashEntry holds a hashed value so the address it is keyed by isn't reused
+type hashEntry struct {
+	value interface{}
+	hash  uint64
+}
+
+// cachedComparison is the differences found between a pair of values, with
+// paths relative to the values
+type cachedComparison struct {
+	val1, val2  interface{}
+	differences []Diff
+}
+
+// comparisonCache memoizes the differences between repeated pairs of
+// objects or arrays. Differences are stored with paths relative to the
+// compared values and rebased onto the path of each later comparison.
+type comparisonCache struct {
+	seed    maphash.Seed
+	hashes  map[uintptr]hashEntry
+	results map[comparisonKey]cachedComparison
+}
+
+// newComparisonCache returns a cache for one comparison if options allow
+// caching, or nil. Caching is disabled when the differences found under a
+// path may depend on the path itself (path-scoped options), or when the
+// comparison records state as it goes (fuzzy matches, numeric promotions,
+// sampling and inferred array keys), or passes the differences it drops
+// to a hook, which a cached result would skip.
+func newComparisonCache(options CompareOptions) *comparisonCache {
+	if !options.CacheSubtrees || options.OnDiff != nil ||
+		options.FuzzyMatches != nil || options.Promotions != nil || options.FloatTolerance > 0 ||
+		options.SampleArrays > 0 || options.AutoArrayKey ||
+		len(options.EnumValues) > 0 || len(options.RegexMatches) > 0 || len(options.LevenshteinKeys) > 0 ||
+		len(options.SemverKeys) > 0 || len(options.UUIDKeys) > 0 || len(options.CurrencyKeys) > 0 || len(options.NumericRules) > 0 || len(options.UnitKeys) > 0 || len(options.ExecComparators) > 0 ||
+		len(options.RenameKeys) > 0 || len(options.RequiredKeys) > 0 || len(options.OneofGroups) > 0 || len(options.IgnoreExtraAt) > 0 ||
+		len(options.IgnoreWhen) > 0 || len(options.DerivedFields) > 0 || len(options.IgnoreOrderPaths) > 0 {
+		return nil
+	}
+	return &comparisonCache{
+		seed:    maphash.MakeSeed(),
+		hashes:  make(map[uintptr]hashEntry),
+		results: make(map[comparisonKey]cachedComparison),
+	}
+}
+
+// lookup returns a copy of the cached differences between val1 and val2,
+// rebased onto path. The values are checked against those cached under key,
+// so a hash collision can't return another pair's differences.
+func (c *comparisonCache) lookup(key comparisonKey, val1, val2 interface{}, path string) ([]Diff, bool) {
+	cached, ok := c.results[key]
+	if !ok || !jsonEqual(cached.val1, val1) || !jsonEqual(cached.val2, val2) {
+		return nil, false
+	}
+	differences := make([]Diff, len(cached.differences))
+	for i, diff := range cached.differences {
+		diff.Path = prefixPath(path, diff.Path)
+		differences[i] = diff
+	}
+	return differences, true
+}
+
+// store records the differences found comparing val1 and val2 at path under key
+func (c *comparisonCache) store(key comparisonKey, val1, val2 interface{}, path string, differences []Diff) {
+	if len(c.results) >= maxCachedComparisons {
+		c.results = make(map[comparisonKey]cachedComparison)
+	}
+	relative := make([]Diff, len(differences))
+	for i, diff := range differences {
+		diff.Path = relativePath(diff.Path, path)
+		relative[i] = diff
+	}
+	c.results[key] = cachedComparison{val1: val1, val2: val2, differences: relative}
+}
+
+// relativePath returns the part of a path under parent, e.g. "[0].id" for
+// "items[0].id" under "items"
+func relativePath(path, parent string) string {
+	if parent == "" {
+		return path
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(path, parent), ".")
+}
+
+// hash returns a hash of the content of an object or array. Hashes are
+// remembered by address, so each value is only hashed once even when it is
+// compared at several levels.
+func (c *comparisonCache) hash(val interface{}) uint64 {
+	var addr uintptr
+	switch v := val.(type) {
+	case map[string]interface{}:
+		addr = reflect.ValueOf(v).Pointer()
+	case []interface{}:
+		if len(v) > 0 {
+			addr = reflect.ValueOf(v).Pointer()
+		}
+	}
+	if addr != 0 {
+		// A slice shares its address with a shorter slice of the same array
+		if entry, ok := c.hashes[addr]; ok && sameContainer(entry.value, val) {
+			return entry.hash
+		}
+	}
+
+	var h maphash.Hash
+	h.SetSeed(c.seed)
+	var sum uint64
+	switch v := val.(type) {
+	case map[string]interface{}:
+		// Entries are hashed separately and summed, so key order doesn't matter
+		h.WriteByte('o')
+		sum = h.Sum64()
+		for key, elem := range v {
+			h.Reset()
+			h.WriteString(key)
+			c.writeValue(&h, elem)
+			sum += h.Sum64()
+		}
+	case []interface{}:
+		h.WriteByte('a')
+		for _, elem := range v {
+			c.writeValue(&h, elem)
+		}
+		sum = h.Sum64()
+	default:
+		c.writeValue(&h, val)
+		sum = h.Sum64()
+	}
+
+	if addr != 0 {
+		if len(c.hashes) >= maxCachedHashes {
+			c.hashes = make(map[uintptr]hashEntry)
+		}
+		c.hashes[addr] = hashEntry{value: val, hash: sum}
+	}
+	return sum
+}
+
+// sameContainer reports whether two objects or arrays are the same value
+// in memory, not merely equal
+func sameContainer(a, b interface{}) bool {
+	switch v := a.(type) {
+	case map[string]interface{}:
+		w, ok := b.(map[string]interface{})
+		return ok && reflect.ValueOf(v).Pointer() == reflect.ValueOf(w).Pointer()
+	case []interface{}:
+		w, ok := b.([]interface{})
+		return ok && len(v) == len(w) && reflect.ValueOf(v).Pointer() == reflect.ValueOf(w).Pointer()
+	}
+	return false
+}
+
+// writeValue writes an unambiguous encoding of val to h: a type tag, then
+// the value, with objects and arrays written as their hash
+func (c *comparisonCache) writeValue(h *maphash.Hash, val interface{}) {
+	var buf [8]byte
+	writeString := func(s string) {
+		binary.LittleEndian.PutUint64(buf[:], uint64(len(s)))
+		h.Write(buf[:])
+		h.WriteString(s)
+	}
+
+	switch v := val.(type) {
+	case nil:
+		h.WriteByte('n')
+	case bool:
+		if v {
+			h.WriteByte('t')
+		} else {
+			h.WriteByte('f')
+		}
+	case float64:
+		h.WriteByte('d')
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+		h.Write(buf[:])
+	case json.Number:
+		h.WriteByte('N')
+		writeString(string(v))
+	case string:
+		h.WriteByte('s')
+		writeString(v)
+	case map[string]interface{}, []interface{}:
+		h.WriteByte('h')
+		binary.LittleEndian.PutUint64(buf[:], c.hash(v))
+		h.Write(buf[:])
+	default:
+		h.WriteByte('?')
+		writeString(fmt.Sprintf("%T:%#v", v, v))
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCacheSubtrees(t *testing.T) {
+	address := func(city string) map[string]interface{} {
+		return map[string]interface{}{"city": city, "lines": []interface{}{"1 Mill Lane", city}}
+	}
+	obj1 := map[string]interface{}{
+		"home": address("Leeds"),
+		"work": address("Leeds"),
+		"past": []interface{}{address("Leeds"), address("Leeds")},
+		"same": address("Hull"),
+	}
+	obj2 := map[string]interface{}{
+		"home": address("York"),
+		"work": address("York"),
+		"past": []interface{}{address("York"), address("York")},
+		"same": address("Hull"),
+	}
+
+	want := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{})
+	got := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{CacheSubtrees: true})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("With CacheSubtrees got %v, want %v", got, want)
+	}
+
+	// Cached differences are rebased onto the root, with no leading dot
+	got = findDifferencesWithOptions([]interface{}{address("Leeds"), obj1["past"]}, []interface{}{address("York"), obj2["past"]}, "", CompareOptions{CacheSubtrees: true})
+	if len(got) != 6 || got[0].Path != "[0].city" || got[2].Path != "[1][0].city" {
+		t.Errorf("Unexpected paths with CacheSubtrees: %v", got)
+	}
+}
+
+func TestNewComparisonCache(t *testing.T) {
+	if newComparisonCache(CompareOptions{}) != nil {
+		t.Error("Expected no cache unless CacheSubtrees is set")
+	}
+	if newComparisonCache(CompareOptions{CacheSubtrees: true}) == nil {
+		t.Error("Expected a cache with CacheSubtrees")
+	}
+
+	// Options whose results depend on the path or that record state disable it
+	for name, options := range map[string]CompareOptions{
+		"regex":     {RegexMatches: map[string]string{"id": ".*"}},
+		"rename":    {RenameKeys: map[string]string{"a": "b"}},
+		"required":  {RequiredKeys: map[string]map[string]bool{"": {"id": true}}},
+		"tolerance": {FloatTolerance: 0.1},
+		"fuzzy":     {FuzzyMatches: &[]FuzzyMatch{}},
+		"sample":    {SampleArrays: 5},
+		"array key": {AutoArrayKey: true},
+	} {
+		options.CacheSubtrees = true
+		if newComparisonCache(options) != nil {
+			t.Errorf("Expected no cache with %s", name)
+		}
+	}
+}
+
+func TestComparisonCacheBounded(t *testing.T) {
+	cache := newComparisonCache(CompareOptions{CacheSubtrees: true})
+	for i := 0; i < maxCachedComparisons+10; i++ {
+		key := comparisonKey{hash1: uint64(i)}
+		cache.store(key, nil, nil, "", []Diff{})
+	}
+	if len(cache.results) > maxCachedComparisons {
+		t.Errorf("Cache grew to %d entries, limit is %d", len(cache.results), maxCachedComparisons)
+	}
+
+	// A hash collision doesn't return another pair's differences
+	cache.store(comparisonKey{hash1: 1}, "a", "b", "x", []Diff{{Path: "x"}})
+	if _, ok := cache.lookup(comparisonKey{hash1: 1}, "a", "c", "y"); ok {
+		t.Error("Expected a miss for different values under the same key")
+	}
+	if diffs, ok := cache.lookup(comparisonKey{hash1: 1}, "a", "b", "y"); !ok || diffs[0].Path != "y" {
+		t.Errorf("Expected the cached difference rebased onto y, got %v", diffs)
+	}
+}
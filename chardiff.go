@@ -0,0 +1,94 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"strings"
+)
+
+// charDiffMinLength is the length, in runes, from which mismatched strings get an inline diff
+const charDiffMinLength = 20
+
+// charDiffMaxCells bounds the size of the LCS table so huge strings don't exhaust memory
+const charDiffMaxCells = 4 << 20
+
+// inlineDiff marks the changed spans between two strings, wdiff style:
+// "[-removed-]" for text only in a and "{+added+}" for text only in b.
+// Unchanged text is kept as is. It uses a longest common subsequence over runes.
+func inlineDiff(a, b string) string {
+	runes1, runes2 := []rune(a), []rune(b)
+	n, m := len(runes1), len(runes2)
+
+	// lcs[i][j] is the length of the LCS of runes1[i:] and runes2[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if runes1[i] == runes2[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var sb, removed, added strings.Builder
+	flush := func() {
+		if removed.Len() > 0 {
+			sb.WriteString("[-" + removed.String() + "-]")
+			removed.Reset()
+		}
+		if added.Len() > 0 {
+			sb.WriteString("{+" + added.String() + "+}")
+			added.Reset()
+		}
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && runes1[i] == runes2[j]:
+			flush()
+			sb.WriteRune(runes1[i])
+			i++
+			j++
+		case j == m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			removed.WriteRune(runes1[i])
+			i++
+		default:
+			added.WriteRune(runes2[j])
+			j++
+		}
+	}
+	flush()
+
+	return sb.String()
+}
+
+// formatCharDiff renders an inline diff line for a value mismatch between two
+// long strings, or "" when the difference is not one
+func formatCharDiff(diff Diff) string {
+	if diff.Type != ValueMismatch {
+		return ""
+	}
+	str1, isStr1 := diff.Value1.(string)
+	str2, isStr2 := diff.Value2.(string)
+	if !isStr1 || !isStr2 {
+		return ""
+	}
+
+	len1, len2 := len([]rune(str1)), len([]rune(str2))
+	if len1 < charDiffMinLength && len2 < charDiffMinLength {
+		return ""
+	}
+	if (len1+1)*(len2+1) > charDiffMaxCells {
+		return ""
+	}
+
+	return "~ " + inlineDiff(str1, str2) + "\n"
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"testing"
+)
+
+func TestInlineDiff(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected string
+	}{
+		{"same", "same", "same"},
+		{"The quick brown fox", "The quick red fox", "The quick [-b-]r[-own-]{+ed+} fox"},
+		{"abc", "abxc", "ab{+x+}c"},
+		{"abc", "", "[-abc-]"},
+		{"", "abc", "{+abc+}"},
+		{"café au lait", "cafe au lait", "caf[-é-]{+e+} au lait"},
+	}
+
+	for _, tc := range testCases {
+		if result := inlineDiff(tc.a, tc.b); result != tc.expected {
+			t.Errorf("inlineDiff(%q, %q) = %q, want %q", tc.a, tc.b, result, tc.expected)
+		}
+	}
+}
+
+func TestFormatCharDiff(t *testing.T) {
+	long := Diff{Path: "bio", Type: ValueMismatch, Value1: "Software engineer in New York", Value2: "Software engineer in Boston"}
+	if line := formatCharDiff(long); line == "" || line[:2] != "~ " {
+		t.Errorf("Expected an inline diff line for long strings, got %q", line)
+	}
+
+	for _, diff := range []Diff{
+		{Path: "city", Type: ValueMismatch, Value1: "New York", Value2: "Boston"},
+		{Path: "age", Type: ValueMismatch, Value1: 30.0, Value2: 31.0},
+		{Path: "bio", Type: KeyOnlyInFirst, Value1: "Software engineer in New York"},
+	} {
+		if line := formatCharDiff(diff); line != "" {
+			t.Errorf("Expected no inline diff for %v, got %q", diff, line)
+		}
+	}
+}
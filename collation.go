@@ -0,0 +1,61 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// parseCollation validates a BCP 47 language tag naming a collation, e.g. de-DE
+func parseCollation(tag string) (language.Tag, error) {
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return language.Und, fmt.Errorf("invalid collation %q: %v", tag, err)
+	}
+	return parsed, nil
+}
+
+// newCollator returns the collator for options.Collation, or nil if no
+// collation is set. A loose collator also ignores diacritics, case and width.
+func newCollator(options CompareOptions) *collate.Collator {
+	if options.Collation == "" {
+		return nil
+	}
+	tag, err := parseCollation(options.Collation)
+	if err != nil {
+		return nil
+	}
+	if options.CollationLoose {
+		return collate.New(tag, collate.Loose)
+	}
+	return collate.New(tag)
+}
+
+// collatorFor returns the collator shared by a comparison, creating one for
+// callers that compare values outside findDifferencesWithOptions
+func collatorFor(options CompareOptions) *collate.Collator {
+	if options.collator != nil {
+		return options.collator
+	}
+	return newCollator(options)
+}
+
+// collatedEqual reports whether two strings rank equal under the collation
+// of options. The boolean result is false if either value is not a string
+// or no collation is set.
+func collatedEqual(val1, val2 interface{}, options CompareOptions) (bool, bool) {
+	str1, ok1 := val1.(string)
+	str2, ok2 := val2.(string)
+	if !ok1 || !ok2 || options.Collation == "" {
+		return false, false
+	}
+	collator := collatorFor(options)
+	if collator == nil {
+		return false, false
+	}
+	return collator.CompareString(str1, str2) == 0, true
+}
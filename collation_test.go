@@ -0,0 +1,44 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import "testing"
+
+func TestCollation(t *testing.T) {
+	tests := []struct {
+		val1, val2 string
+		loose      bool
+		equal      bool
+	}{
+		{"Müller", "Müller", false, true},
+		{"Müller", "Mu\u0308ller", false, true}, // Canonically equivalent forms
+		{"Müller", "Muller", false, false},
+		{"Müller", "Muller", true, true},
+		{"Ä", "a", true, true},
+		{"Ä", "b", true, false},
+	}
+	for _, test := range tests {
+		options := CompareOptions{Collation: "de-DE", CollationLoose: test.loose}
+		if got := valuesEqual(test.val1, test.val2, "name", options); got != test.equal {
+			t.Errorf("valuesEqual(%q, %q, loose=%v) = %v, want %v", test.val1, test.val2, test.loose, got, test.equal)
+		}
+	}
+
+	// Without a collation strings are compared byte for byte
+	if valuesEqual("Müller", "Mu\u0308ller", "name", CompareOptions{}) {
+		t.Error("Expected byte comparison to tell the forms apart")
+	}
+
+	// The collation decides which elements of unordered arrays pair up
+	obj1 := map[string]interface{}{"cities": []interface{}{"Zürich", "Köln"}}
+	obj2 := map[string]interface{}{"cities": []interface{}{"koln", "zurich"}}
+	options := CompareOptions{Collation: "de", CollationLoose: true, IgnoreOrderPaths: []string{"cities"}}
+	if diffs := findDifferencesWithOptions(obj1, obj2, "", options); len(diffs) != 0 {
+		t.Errorf("Expected the cities to pair up, got %v", diffs)
+	}
+
+	if _, err := parseCollation("not a locale!"); err == nil {
+		t.Error("Expected an invalid tag to be rejected")
+	}
+}
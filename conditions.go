@@ -0,0 +1,88 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConditionalIgnore suppresses differences at a path within an object while a
+// sibling field has a given value in both documents
+type ConditionalIgnore struct {
+	Field string // Sibling key whose value is checked
+	Value string // Value the sibling must have, compared in its printed form
+	Path  string // Key or relative path, within the same object, whose differences are ignored
+}
+
+// parseConditionalIgnore parses a rule in the form field=value:path,
+// e.g. "status=cancelled:discount"
+func parseConditionalIgnore(rule string) (ConditionalIgnore, error) {
+	condition, guarded, ok := cutLast(rule, ":")
+	if !ok || guarded == "" {
+		return ConditionalIgnore{}, fmt.Errorf("invalid ignore-when rule %q, expected field=value:path", rule)
+	}
+
+	field, value, ok := strings.Cut(condition, "=")
+	if !ok || field == "" {
+		return ConditionalIgnore{}, fmt.Errorf("invalid ignore-when rule %q, expected field=value:path", rule)
+	}
+
+	return ConditionalIgnore{Field: field, Value: value, Path: guarded}, nil
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// matches reports whether the rule's condition holds in an object
+func (c ConditionalIgnore) matches(obj map[string]interface{}) bool {
+	val, ok := obj[c.Field]
+	if !ok {
+		return false
+	}
+	if str, isStr := val.(string); isStr {
+		return str == c.Value
+	}
+	return fmt.Sprint(val) == c.Value
+}
+
+// guardedPaths returns the paths, relative to the object at path, whose
+// differences are suppressed because a rule's condition holds in both objects
+func guardedPaths(map1, map2 map[string]interface{}, path string, rules []ConditionalIgnore) []string {
+	var guarded []string
+	for _, rule := range rules {
+		if rule.matches(map1) && rule.matches(map2) {
+			guarded = append(guarded, joinPath(path, rule.Path))
+		}
+	}
+	return guarded
+}
+
+// filterGuarded removes differences at or under any of the guarded paths,
+// passing those it removes to the OnDiff hook of options
+func filterGuarded(differences []Diff, guarded []string, options CompareOptions) []Diff {
+	filtered := differences[:0]
+	var suppressed []Diff
+	for _, diff := range differences {
+		isGuarded := false
+		for _, guardedPath := range guarded {
+			if isUnderPath(diff.Path, guardedPath) {
+				isGuarded = true
+				break
+			}
+		}
+		if isGuarded {
+			suppressed = append(suppressed, diff)
+		} else {
+			filtered = append(filtered, diff)
+		}
+	}
+	notifyFiltered(suppressed, options)
+	return filtered
+}
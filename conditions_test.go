@@ -0,0 +1,60 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"testing"
+)
+
+func TestParseConditionalIgnore(t *testing.T) {
+	rule, err := parseConditionalIgnore("status=cancelled:discount")
+	if err != nil {
+		t.Fatalf("Failed to parse rule: %v", err)
+	}
+	if rule != (ConditionalIgnore{Field: "status", Value: "cancelled", Path: "discount"}) {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+
+	for _, invalid := range []string{"status=cancelled", "status:discount", "=x:discount", "status=x:"} {
+		if _, err := parseConditionalIgnore(invalid); err == nil {
+			t.Errorf("Expected an error parsing %q", invalid)
+		}
+	}
+}
+
+func TestIgnoreWhen(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"orders": []interface{}{
+			map[string]interface{}{"status": "cancelled", "discount": 5.0, "total": 10.0, "pricing": map[string]interface{}{"tax": 1.0}},
+			map[string]interface{}{"status": "open", "discount": 5.0, "total": 10.0},
+			map[string]interface{}{"status": "cancelled", "discount": 5.0, "flagged": true},
+		},
+	}
+	obj2 := map[string]interface{}{
+		"orders": []interface{}{
+			map[string]interface{}{"status": "cancelled", "discount": 7.0, "total": 10.0, "pricing": map[string]interface{}{"tax": 2.0}},
+			map[string]interface{}{"status": "open", "discount": 7.0, "total": 10.0},
+			map[string]interface{}{"status": "open", "discount": 7.0, "flagged": false},
+		},
+	}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		IgnoreWhen: []ConditionalIgnore{
+			{Field: "status", Value: "cancelled", Path: "discount"},
+			{Field: "status", Value: "cancelled", Path: "pricing.tax"},
+			{Field: "flagged", Value: "true", Path: "discount"},
+		},
+	})
+
+	// Only the first order matches the condition in both documents
+	expected := []string{"orders[1].discount", "orders[2].discount", "orders[2].flagged", "orders[2].status"}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d differences, got %d: %v", len(expected), len(diffs), diffs)
+	}
+	for i, diff := range diffs {
+		if diff.Path != expected[i] {
+			t.Errorf("Expected difference at %s, got %s", expected[i], diff.Path)
+		}
+	}
+}
@@ -0,0 +1,633 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes comparison options loaded from a YAML file.
+// Keys mirror the command-line flag names so a config file reads like a saved command line.
+type Config struct {
+	IgnoreCase           bool              `yaml:"ignore-case"`
+	IgnoreWhitespaceKeys bool              `yaml:"ignore-whitespace-keys"`
+	NormalizeNumericKeys bool              `yaml:"normalize-numeric-keys"`
+	NormalizeKeyStyle    []string          `yaml:"normalize-key-style"`
+	IgnoreCaseValues     bool              `yaml:"ignore-case-values"`
+	ReportCaseDiffs      bool              `yaml:"report-case-diffs"`
+	FoldUnicode          bool              `yaml:"fold-unicode"`
+	Collation            string            `yaml:"collation"`
+	CollationLoose       bool              `yaml:"collation-loose"`
+	IgnoreNumericType    bool              `yaml:"ignore-numeric-type"`
+	CoerceLeftNumStrings bool              `yaml:"coerce-left-numeric-strings"`
+	FloatTolerance       float64           `yaml:"float-tolerance"`
+	ParseGroupedNumbers  bool              `yaml:"parse-grouped-numbers"`
+	DecimalSeparator     string            `yaml:"decimal-separator"`
+	GroupSeparator       string            `yaml:"group-separator"`
+	NormalizeNumStrings  bool              `yaml:"normalize-numeric-strings"`
+	IgnoreBooleanType    bool              `yaml:"ignore-boolean-type"`
+	NumericBooleans      bool              `yaml:"numeric-booleans"`
+	IgnoreNullValues     bool              `yaml:"ignore-null"`
+	Proto                bool              `yaml:"proto"`
+	ProtoEnums           map[string]string `yaml:"proto-enum"`
+	CoerceNumericObjects bool              `yaml:"coerce-numeric-object-to-array"`
+	UnwrapSingletons     bool              `yaml:"unwrap-singleton-arrays"`
+	UnwrapValueKey       string            `yaml:"unwrap-value-key"`
+	WildcardValue        string            `yaml:"wildcard-value"`
+	DeepTypeMismatch     bool              `yaml:"deep-type-mismatch"`
+	KeysOnly             bool              `yaml:"keys-only"`
+	ArrayTypeCheck       bool              `yaml:"array-type-check"`
+	RegexMatches         map[string]string `yaml:"regex-match"`
+	LevenshteinKeys      []string          `yaml:"levenshtein-key"`
+	LevenshteinThreshold int               `yaml:"levenshtein-threshold"`
+	SemverKeys           []string          `yaml:"semver-key"`
+	UUIDKeys             []string          `yaml:"uuid-key"`
+	CurrencyKeys         []string          `yaml:"currency-key"`
+	UnitKeys             map[string]string `yaml:"unit-key"`
+	NumericRules         map[string]string `yaml:"numeric-rule"`
+	NormalizeType        []string          `yaml:"normalize-type"`
+	IgnoreKeyNames       []string          `yaml:"ignore-key"`
+	RenameKeys           map[string]string `yaml:"rename"`
+	StripKeyPrefix       []string          `yaml:"strip-key-prefix"`
+	Aliases              []string          `yaml:"alias"`
+	SampleArrays         int               `yaml:"sample-arrays"`
+	Seed                 int64             `yaml:"seed"`
+	AutoArrayKey         bool              `yaml:"auto-array-key"`
+	CacheSubtrees        bool              `yaml:"cache-subtrees"`
+	Severity             map[string]string `yaml:"severity"`
+	Tag                  []string          `yaml:"tag"`
+	Weight               []string          `yaml:"weight"`
+	IgnorePath           []string          `yaml:"ignore-path"`
+	OnlyPath             []string          `yaml:"only-path"`
+	IgnoreOrderFor       []string          `yaml:"ignore-order-for"`
+	MaxArrayDiffs        int               `yaml:"max-array-diffs"`
+	MaxDepth             int               `yaml:"max-depth"`
+	ArrayLengthTolerance int               `yaml:"array-length-tolerance"`
+	Required             []string          `yaml:"required"`
+	Oneof                []string          `yaml:"oneof"`
+	IgnoreExtraAt        []string          `yaml:"ignore-extra-at"`
+	IgnoreDefaultExtras  bool              `yaml:"ignore-default-extras"`
+	DefaultTypes         []string          `yaml:"default-types"`
+	IgnoreWhen           []string          `yaml:"ignore-when"`
+	Derived              []string          `yaml:"derived"`
+}
+
+// LoadConfig reads and validates a YAML config file
+func LoadConfig(filePath string) (*Config, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	// Reject unknown keys so a typo doesn't silently disable an option
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	config := &Config{}
+	if err := decoder.Decode(config); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return config, nil
+}
+
+// Validate checks that the config values are usable
+func (c *Config) Validate() error {
+	if c.LevenshteinThreshold < 0 {
+		return fmt.Errorf("levenshtein-threshold must not be negative")
+	}
+	if c.FloatTolerance < 0 {
+		return fmt.Errorf("float-tolerance must not be negative")
+	}
+	if len(c.DefaultTypes) > 0 && !c.IgnoreDefaultExtras {
+		return fmt.Errorf("default-types requires ignore-default-extras")
+	}
+	if _, err := parseDefaultTypes(c.DefaultTypes); err != nil {
+		return err
+	}
+	if c.SampleArrays < 0 {
+		return fmt.Errorf("sample-arrays must not be negative")
+	}
+	if c.DecimalSeparator != "" && c.DecimalSeparator == c.GroupSeparator {
+		return fmt.Errorf("decimal-separator and group-separator must differ")
+	}
+	if c.MaxArrayDiffs < 0 {
+		return fmt.Errorf("max-array-diffs must not be negative")
+	}
+	if c.MaxDepth < 0 {
+		return fmt.Errorf("max-depth must not be negative")
+	}
+	if c.ArrayLengthTolerance < 0 {
+		return fmt.Errorf("array-length-tolerance must not be negative")
+	}
+	if c.Collation != "" {
+		if _, err := parseCollation(c.Collation); err != nil {
+			return err
+		}
+	}
+	for key, pattern := range c.RegexMatches {
+		if _, err := compileRegex(pattern); err != nil {
+			return fmt.Errorf("regex-match for %s: %w", key, err)
+		}
+	}
+	for _, rule := range c.IgnoreWhen {
+		if _, err := parseConditionalIgnore(rule); err != nil {
+			return err
+		}
+	}
+	for _, spec := range c.Derived {
+		if _, err := parseDerivedField(spec); err != nil {
+			return err
+		}
+	}
+	for key, unit := range c.UnitKeys {
+		if !validUnit(unit) {
+			return fmt.Errorf("unit-key for %s: unknown unit %q, expected bytes or si", key, unit)
+		}
+	}
+	for key, terms := range c.NumericRules {
+		if _, err := parseNumericRuleTerms(terms); err != nil {
+			return fmt.Errorf("numeric-rule for %s: %v", key, err)
+		}
+	}
+	for path, mapping := range c.ProtoEnums {
+		if _, err := parseEnumMapping(mapping); err != nil {
+			return fmt.Errorf("proto-enum for %s: %v", path, err)
+		}
+	}
+	for old, renamed := range c.RenameKeys {
+		if old == "" || renamed == "" {
+			return fmt.Errorf("rename entries must have a non-empty old and new name")
+		}
+	}
+	if err := checkRenameTargets(c.RenameKeys); err != nil {
+		return err
+	}
+	for _, spec := range c.StripKeyPrefix {
+		if _, _, err := parseStripKeyPrefix(spec); err != nil {
+			return err
+		}
+	}
+	for _, group := range c.Aliases {
+		if _, err := parseAliasGroup(group); err != nil {
+			return err
+		}
+	}
+	for path, name := range c.Severity {
+		if _, err := parseSeverity(name); err != nil {
+			return fmt.Errorf("severity for %s: %v", path, err)
+		}
+	}
+	for _, rule := range c.Tag {
+		if _, _, err := parseTagRule(rule); err != nil {
+			return err
+		}
+	}
+	for _, expr := range append(append(append([]string(nil), c.IgnorePath...), c.OnlyPath...), c.IgnoreOrderFor...) {
+		if err := validatePathExpr(expr); err != nil {
+			return err
+		}
+	}
+	if _, err := parseKeyStyles(c.NormalizeKeyStyle); err != nil {
+		return err
+	}
+	for _, rule := range c.Weight {
+		if _, _, err := parseWeight(rule); err != nil {
+			return err
+		}
+	}
+	for _, spec := range c.Required {
+		if _, _, err := parseRequiredKeys(spec); err != nil {
+			return err
+		}
+	}
+	for _, spec := range c.Oneof {
+		if _, _, err := parseOneof(spec); err != nil {
+			return err
+		}
+	}
+	for _, spec := range c.NormalizeType {
+		if _, _, err := parseTypeNormalizer(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompareOptions converts the config into comparison options
+func (c *Config) CompareOptions() CompareOptions {
+	levenshteinKeys := make(map[string]bool)
+	for _, key := range c.LevenshteinKeys {
+		levenshteinKeys[key] = true
+	}
+
+	semverKeys := make(map[string]bool)
+	for _, key := range c.SemverKeys {
+		semverKeys[key] = true
+	}
+
+	uuidKeys := make(map[string]bool)
+	for _, key := range c.UUIDKeys {
+		uuidKeys[key] = true
+	}
+
+	currencyKeys := make(map[string]bool)
+	for _, key := range c.CurrencyKeys {
+		currencyKeys[key] = true
+	}
+
+	ignoreExtraAt := make(map[string]bool)
+	for _, objPath := range c.IgnoreExtraAt {
+		if objPath == "." {
+			objPath = ""
+		}
+		ignoreExtraAt[objPath] = true
+	}
+
+	var defaultExtraTypes map[string]bool
+	if c.IgnoreDefaultExtras {
+		names := c.DefaultTypes
+		if len(names) == 0 {
+			names = defaultTypeNames
+		}
+		defaultExtraTypes, _ = parseDefaultTypes(names)
+	}
+
+	numericRules := make(map[string]NumericRule)
+	for key, terms := range c.NumericRules {
+		if rule, err := parseNumericRuleTerms(terms); err == nil {
+			numericRules[key] = rule
+		}
+	}
+
+	enumValues := make(map[string]map[string]float64)
+	for path, mapping := range c.ProtoEnums {
+		if values, err := parseEnumMapping(mapping); err == nil {
+			enumValues[path] = values
+		}
+	}
+
+	keyAliases := make(map[string]string)
+	for _, group := range c.Aliases {
+		aliases, _ := parseAliasGroup(group)
+		for alias, canonical := range aliases {
+			keyAliases[alias] = canonical
+		}
+	}
+
+	severityOverrides := make(map[string]Severity)
+	for path, name := range c.Severity {
+		if severity, err := parseSeverity(name); err == nil {
+			if path == "." {
+				path = ""
+			}
+			severityOverrides[path] = severity
+		}
+	}
+
+	pathTags := make(map[string][]string)
+	for _, rule := range c.Tag {
+		if path, tag, err := parseTagRule(rule); err == nil {
+			pathTags[path] = append(pathTags[path], tag)
+		}
+	}
+
+	keyStyles, _ := parseKeyStyles(c.NormalizeKeyStyle)
+
+	weights := make(map[string]float64)
+	for _, rule := range c.Weight {
+		if path, weight, err := parseWeight(rule); err == nil {
+			weights[path] = weight
+		}
+	}
+
+	typeNormalizers := make(map[string]Normalizer)
+	for _, spec := range c.NormalizeType {
+		addTypeNormalizer(typeNormalizers, spec)
+	}
+
+	requiredKeys := make(map[string]map[string]bool)
+	for _, spec := range c.Required {
+		addRequiredKeys(requiredKeys, spec)
+	}
+
+	oneofGroups := make(map[string][][]string)
+	for _, spec := range c.Oneof {
+		addOneof(oneofGroups, spec)
+	}
+
+	var ignoreWhen []ConditionalIgnore
+	for _, rule := range c.IgnoreWhen {
+		if parsed, err := parseConditionalIgnore(rule); err == nil {
+			ignoreWhen = append(ignoreWhen, parsed)
+		}
+	}
+
+	var derivedFields []DerivedField
+	for _, spec := range c.Derived {
+		if parsed, err := parseDerivedField(spec); err == nil {
+			derivedFields = append(derivedFields, parsed)
+		}
+	}
+
+	var stripLeft, stripRight string
+	for _, spec := range c.StripKeyPrefix {
+		if side, prefix, err := parseStripKeyPrefix(spec); err == nil && side == "left" {
+			stripLeft = prefix
+		} else if err == nil {
+			stripRight = prefix
+		}
+	}
+
+	options := CompareOptions{
+		IgnoreCase:           c.IgnoreCase,
+		IgnoreWhitespaceKeys: c.IgnoreWhitespaceKeys,
+		NormalizeNumericKeys: c.NormalizeNumericKeys,
+		KeyStyles:            keyStyles,
+		IgnoreCaseValues:     c.IgnoreCaseValues,
+		ReportCaseDiffs:      c.ReportCaseDiffs,
+		FoldUnicode:          c.FoldUnicode,
+		Collation:            c.Collation,
+		CollationLoose:       c.CollationLoose,
+		IgnoreNumericType:    c.IgnoreNumericType,
+		CoerceLeftNumStrings: c.CoerceLeftNumStrings,
+		FloatTolerance:       c.FloatTolerance,
+		NumericRules:         numericRules,
+		ParseGroupedNumbers:  c.ParseGroupedNumbers,
+		DecimalSeparator:     c.DecimalSeparator,
+		GroupSeparator:       c.GroupSeparator,
+		NormalizeNumStrings:  c.NormalizeNumStrings,
+		IgnoreBooleanType:    c.IgnoreBooleanType,
+		NumericBooleans:      c.NumericBooleans,
+		IgnoreNullValues:     c.IgnoreNullValues,
+		EnumValues:           enumValues,
+		CoerceNumericObjects: c.CoerceNumericObjects,
+		UnwrapSingletons:     c.UnwrapSingletons,
+		UnwrapValueKey:       c.UnwrapValueKey,
+		WildcardValue:        c.WildcardValue,
+		DeepTypeMismatch:     c.DeepTypeMismatch,
+		KeysOnly:             c.KeysOnly,
+		ArrayTypeCheck:       c.ArrayTypeCheck,
+		RegexMatches:         copyStringMap(c.RegexMatches),
+		LevenshteinKeys:      levenshteinKeys,
+		LevenshteinThreshold: c.LevenshteinThreshold,
+		SemverKeys:           semverKeys,
+		UUIDKeys:             uuidKeys,
+		CurrencyKeys:         currencyKeys,
+		UnitKeys:             copyStringMap(c.UnitKeys),
+		TypeNormalizers:      typeNormalizers,
+		IgnoreKeyNames:       append([]string(nil), c.IgnoreKeyNames...),
+		RenameKeys:           copyStringMap(c.RenameKeys),
+		StripKeyPrefixLeft:   stripLeft,
+		StripKeyPrefixRight:  stripRight,
+		KeyAliases:           keyAliases,
+		SampleArrays:         c.SampleArrays,
+		SampleSeed:           c.Seed,
+		AutoArrayKey:         c.AutoArrayKey,
+		CacheSubtrees:        c.CacheSubtrees,
+		SeverityOverrides:    severityOverrides,
+		PathTags:             pathTags,
+		IgnorePaths:          append([]string(nil), c.IgnorePath...),
+		OnlyPaths:            append([]string(nil), c.OnlyPath...),
+		IgnoreOrderPaths:     append([]string(nil), c.IgnoreOrderFor...),
+		Weights:              weights,
+		MaxArrayDiffs:        c.MaxArrayDiffs,
+		MaxDepth:             c.MaxDepth,
+		ArrayLengthTolerance: c.ArrayLengthTolerance,
+		RequiredKeys:         requiredKeys,
+		OneofGroups:          oneofGroups,
+		IgnoreExtraAt:        ignoreExtraAt,
+		DefaultExtraTypes:    defaultExtraTypes,
+		IgnoreWhen:           ignoreWhen,
+		DerivedFields:        derivedFields,
+	}
+	if c.Proto {
+		options = applyProtoPreset(options)
+	}
+	return options
+}
+
+// mergeFlags overlays command-line options on top of the config's options. An
+// explicitly set -proto decides the preset on its own, so -proto=false drops the
+// preset of a config with proto: true instead of keeping its settings.
+func (c *Config) mergeFlags(cli CompareOptions, setFlags map[string]bool) CompareOptions {
+	config := *c
+	if setFlags["proto"] {
+		config.Proto = false
+	}
+	return mergeOptions(config.CompareOptions(), cli, setFlags)
+}
+
+// mergeOptions overlays command-line options on top of config options.
+// Scalar options are taken from the command line only when their flag was set
+// explicitly (setFlags holds the names of those flags); map options are merged,
+// with command-line entries replacing config entries for the same key.
+func mergeOptions(config, cli CompareOptions, setFlags map[string]bool) CompareOptions {
+	merged := config
+
+	if setFlags["ignore-case"] {
+		merged.IgnoreCase = cli.IgnoreCase
+	}
+	if setFlags["ignore-whitespace-keys"] {
+		merged.IgnoreWhitespaceKeys = cli.IgnoreWhitespaceKeys
+	}
+	if setFlags["normalize-numeric-keys"] {
+		merged.NormalizeNumericKeys = cli.NormalizeNumericKeys
+	}
+	if setFlags["ignore-case-values"] {
+		merged.IgnoreCaseValues = cli.IgnoreCaseValues
+	}
+	if setFlags["report-case-diffs"] {
+		merged.ReportCaseDiffs = cli.ReportCaseDiffs
+	}
+	if setFlags["fold-unicode"] {
+		merged.FoldUnicode = cli.FoldUnicode
+	}
+	if setFlags["collation"] {
+		merged.Collation = cli.Collation
+	}
+	if setFlags["collation-loose"] {
+		merged.CollationLoose = cli.CollationLoose
+	}
+	if setFlags["ignore-numeric-type"] {
+		merged.IgnoreNumericType = cli.IgnoreNumericType
+	}
+	if setFlags["coerce-left-numeric-strings"] {
+		merged.CoerceLeftNumStrings = cli.CoerceLeftNumStrings
+	}
+	if setFlags["float-tolerance"] {
+		merged.FloatTolerance = cli.FloatTolerance
+	}
+	if setFlags["parse-grouped-numbers"] {
+		merged.ParseGroupedNumbers = cli.ParseGroupedNumbers
+	}
+
+	// Separators fall back to the flag defaults when the config doesn't set them
+	if setFlags["decimal-separator"] || config.DecimalSeparator == "" {
+		merged.DecimalSeparator = cli.DecimalSeparator
+	}
+	if setFlags["group-separator"] || (config.GroupSeparator == "" && config.DecimalSeparator == "") {
+		merged.GroupSeparator = cli.GroupSeparator
+	}
+	if setFlags["normalize-numeric-strings"] {
+		merged.NormalizeNumStrings = cli.NormalizeNumStrings
+	}
+	if setFlags["ignore-boolean-type"] {
+		merged.IgnoreBooleanType = cli.IgnoreBooleanType
+	}
+	if setFlags["numeric-booleans"] {
+		merged.NumericBooleans = cli.NumericBooleans
+	}
+	if setFlags["ignore-null"] {
+		merged.IgnoreNullValues = cli.IgnoreNullValues
+	}
+	// -proto is the only way to set TreatMissingAsDefault, so it marks the preset
+	if setFlags["proto"] && cli.TreatMissingAsDefault {
+		merged = applyProtoPreset(merged)
+	}
+	if setFlags["coerce-numeric-object-to-array"] {
+		merged.CoerceNumericObjects = cli.CoerceNumericObjects
+	}
+	if setFlags["unwrap-singleton-arrays"] {
+		merged.UnwrapSingletons = cli.UnwrapSingletons
+	}
+	if setFlags["unwrap-value-key"] {
+		merged.UnwrapValueKey = cli.UnwrapValueKey
+	}
+	if setFlags["wildcard-value"] {
+		merged.WildcardValue = cli.WildcardValue
+	}
+	if setFlags["deep-type-mismatch"] {
+		merged.DeepTypeMismatch = cli.DeepTypeMismatch
+	}
+	if setFlags["keys-only"] {
+		merged.KeysOnly = cli.KeysOnly
+	}
+	if setFlags["array-type-check"] {
+		merged.ArrayTypeCheck = cli.ArrayTypeCheck
+	}
+	if setFlags["sample-arrays"] {
+		merged.SampleArrays = cli.SampleArrays
+	}
+	if setFlags["seed"] {
+		merged.SampleSeed = cli.SampleSeed
+	}
+	if setFlags["auto-array-key"] {
+		merged.AutoArrayKey = cli.AutoArrayKey
+	}
+	if setFlags["cache-subtrees"] {
+		merged.CacheSubtrees = cli.CacheSubtrees
+	}
+	if setFlags["max-array-diffs"] {
+		merged.MaxArrayDiffs = cli.MaxArrayDiffs
+	}
+	if setFlags["max-depth"] {
+		merged.MaxDepth = cli.MaxDepth
+	}
+	if setFlags["array-length-tolerance"] {
+		merged.ArrayLengthTolerance = cli.ArrayLengthTolerance
+	}
+
+	// The config only overrides the threshold default when it sets one
+	if setFlags["levenshtein-threshold"] || config.LevenshteinThreshold == 0 {
+		merged.LevenshteinThreshold = cli.LevenshteinThreshold
+	}
+
+	for key, pattern := range cli.RegexMatches {
+		merged.RegexMatches[key] = pattern
+	}
+	for key := range cli.LevenshteinKeys {
+		merged.LevenshteinKeys[key] = true
+	}
+	for path, values := range cli.EnumValues {
+		merged.EnumValues[path] = values
+	}
+	for key := range cli.SemverKeys {
+		merged.SemverKeys[key] = true
+	}
+	for key := range cli.UUIDKeys {
+		merged.UUIDKeys[key] = true
+	}
+	for key := range cli.CurrencyKeys {
+		merged.CurrencyKeys[key] = true
+	}
+	for key, unit := range cli.UnitKeys {
+		merged.UnitKeys[key] = unit
+	}
+	for key, rule := range cli.NumericRules {
+		merged.NumericRules[key] = rule
+	}
+	for old, renamed := range cli.RenameKeys {
+		merged.RenameKeys[old] = renamed
+	}
+	if cli.StripKeyPrefixLeft != "" {
+		merged.StripKeyPrefixLeft = cli.StripKeyPrefixLeft
+	}
+	if cli.StripKeyPrefixRight != "" {
+		merged.StripKeyPrefixRight = cli.StripKeyPrefixRight
+	}
+	for typeName, normalizer := range cli.TypeNormalizers {
+		merged.TypeNormalizers[typeName] = normalizer
+	}
+	merged.IgnoreKeyNames = append(merged.IgnoreKeyNames, cli.IgnoreKeyNames...)
+	merged.IgnorePaths = append(merged.IgnorePaths, cli.IgnorePaths...)
+	merged.OnlyPaths = append(merged.OnlyPaths, cli.OnlyPaths...)
+	merged.KeyStyles = append(merged.KeyStyles, cli.KeyStyles...)
+	merged.IgnoreOrderPaths = append(merged.IgnoreOrderPaths, cli.IgnoreOrderPaths...)
+	for alias, canonical := range cli.KeyAliases {
+		merged.KeyAliases[alias] = canonical
+	}
+	for path, severity := range cli.SeverityOverrides {
+		merged.SeverityOverrides[path] = severity
+	}
+	for path, weight := range cli.Weights {
+		merged.Weights[path] = weight
+	}
+	for path, tags := range cli.PathTags {
+		merged.PathTags[path] = append(merged.PathTags[path], tags...)
+	}
+	for objPath, keys := range cli.RequiredKeys {
+		if merged.RequiredKeys[objPath] == nil {
+			merged.RequiredKeys[objPath] = make(map[string]bool)
+		}
+		for key := range keys {
+			merged.RequiredKeys[objPath][key] = true
+		}
+	}
+	for objPath, groups := range cli.OneofGroups {
+		merged.OneofGroups[objPath] = append(merged.OneofGroups[objPath], groups...)
+	}
+	for objPath := range cli.IgnoreExtraAt {
+		merged.IgnoreExtraAt[objPath] = true
+	}
+	if setFlags["ignore-default-extras"] {
+		merged.DefaultExtraTypes = cli.DefaultExtraTypes
+	}
+	merged.IgnoreWhen = append(merged.IgnoreWhen, cli.IgnoreWhen...)
+	merged.DerivedFields = append(merged.DerivedFields, cli.DerivedFields...)
+
+	// External comparators run commands, so they can only be given on the command line
+	merged.ExecComparators = cli.ExecComparators
+	merged.ExecTimeout = cli.ExecTimeout
+
+	return merged
+}
+
+// copyStringMap returns a copy of m, never nil
+func copyStringMap(m map[string]string) map[string]string {
+	copied := make(map[string]string, len(m))
+	for key, val := range m {
+		copied[key] = val
+	}
+	return copied
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("examples/jsondiff.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load examples/jsondiff.yaml: %v", err)
+	}
+
+	file17, err := ReadAndValidateJSON("examples/example17.json", true)
+	if err != nil {
+		t.Fatalf("Failed to read examples/example17.json: %v", err)
+	}
+
+	file18, err := ReadAndValidateJSON("examples/example18.json", true)
+	if err != nil {
+		t.Fatalf("Failed to read examples/example18.json: %v", err)
+	}
+
+	// Only the location differs once the config's fuzzy matching is applied
+	diffs := findDifferencesWithOptions(file17.Data, file18.Data, "", config.CompareOptions())
+	if len(diffs) != 1 || diffs[0].Path != "location" {
+		t.Errorf("Expected a single difference at location, got %v", diffs)
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"Unknown key", "ignore-cases: true\n", "not found"},
+		{"Negative threshold", "levenshtein-threshold: -1\n", "must not be negative"},
+		{"Bad regex", "regex-match:\n  id: \"[\"\n", "regex-match for id"},
+		{"Empty rename", "rename:\n  userName: \"\"\n", "non-empty"},
+		{"Conflicting renames", "rename:\n  userName: name\n  login: name\n", "both target"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "jsondiff.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			_, err := LoadConfig(configPath)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestMergeOptions(t *testing.T) {
+	config := CompareOptions{
+		IgnoreCase:           true,
+		KeysOnly:             true,
+		RegexMatches:         map[string]string{"id": "config", "code": "config"},
+		LevenshteinKeys:      map[string]bool{"name": true},
+		LevenshteinThreshold: 5,
+		RenameKeys:           map[string]string{},
+		IgnoreExtraAt:        map[string]bool{},
+	}
+	cli := CompareOptions{
+		IgnoreCase:           false,
+		KeysOnly:             false,
+		RegexMatches:         map[string]string{"id": "cli"},
+		LevenshteinKeys:      map[string]bool{"title": true},
+		LevenshteinThreshold: 3,
+	}
+
+	// Only explicitly set flags override the config
+	merged := mergeOptions(config, cli, map[string]bool{"ignore-case": true})
+	if merged.IgnoreCase {
+		t.Error("Expected explicit -ignore-case=false to override the config")
+	}
+	if !merged.KeysOnly {
+		t.Error("Expected keys-only from the config to be kept when the flag is not set")
+	}
+	if merged.LevenshteinThreshold != 5 {
+		t.Errorf("Expected the config threshold to replace the flag default, got %d", merged.LevenshteinThreshold)
+	}
+	if merged.RegexMatches["id"] != "cli" || merged.RegexMatches["code"] != "config" {
+		t.Errorf("Expected regex matches to be merged with flags winning, got %v", merged.RegexMatches)
+	}
+	if !merged.LevenshteinKeys["name"] || !merged.LevenshteinKeys["title"] {
+		t.Errorf("Expected levenshtein keys from both sources, got %v", merged.LevenshteinKeys)
+	}
+}
+
+func TestMergeFlagsProto(t *testing.T) {
+	config := &Config{Proto: true}
+
+	merged := config.mergeFlags(CompareOptions{}, map[string]bool{})
+	if !merged.TreatMissingAsDefault || !merged.IgnoreNumericType {
+		t.Errorf("Expected proto: true from the config to apply the preset, got %+v", merged)
+	}
+
+	merged = config.mergeFlags(CompareOptions{}, map[string]bool{"proto": true})
+	if merged.TreatMissingAsDefault || merged.IgnoreNumericType {
+		t.Errorf("Expected explicit -proto=false to override proto: true from the config, got %+v", merged)
+	}
+
+	// Options the config sets on its own are kept
+	config.IgnoreNumericType = true
+	merged = config.mergeFlags(CompareOptions{}, map[string]bool{"proto": true})
+	if merged.TreatMissingAsDefault || !merged.IgnoreNumericType {
+		t.Errorf("Expected ignore-numeric-type from the config to be kept, got %+v", merged)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// parseCurrency reads a number written as a currency amount, such as
+// "$1,234.56", "1.234,56 €", "USD 1,234.56" or "(12.00)" for a negative amount.
+// Currency symbols and a three-letter currency code are dropped and grouping
+// separators removed; numbers are returned as they are. The boolean result
+// is false if the value is not a finite amount.
+func parseCurrency(val interface{}, decimalSep, groupSep string) (float64, bool) {
+	if num, ok := numberValue(val); ok {
+		return num, true
+	}
+	str, ok := val.(string)
+	if !ok {
+		return 0, false
+	}
+
+	// Drop currency symbols wherever they are, e.g. "-$5" or "$-5"
+	str = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Sc, r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(str))
+	str = strings.TrimSpace(trimCurrencyCode(strings.TrimSpace(str)))
+
+	// Accounting format writes negative amounts in parentheses
+	negative := false
+	if strings.HasPrefix(str, "(") && strings.HasSuffix(str, ")") {
+		negative, str = true, strings.TrimSpace(str[1:len(str)-1])
+	}
+
+	if decimalSep == "" {
+		decimalSep, groupSep = ".", ","
+	}
+	ungrouped, ok := ungroupNumber(str, decimalSep, groupSep).(string)
+	if !ok || ungrouped == "" || strings.Trim(ungrouped, "+-.0123456789") != "" {
+		return 0, false
+	}
+	num, err := strconv.ParseFloat(ungrouped, 64)
+	if err != nil || math.IsInf(num, 0) || math.IsNaN(num) {
+		return 0, false
+	}
+	if negative {
+		num = -num
+	}
+	return num, true
+}
+
+// trimCurrencyCode removes a three-letter uppercase currency code, such as
+// USD, from the start or end of an amount
+func trimCurrencyCode(str string) string {
+	isCode := func(s string) bool {
+		return len(s) == 3 && strings.Trim(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+	}
+	if len(str) > 3 && isCode(str[:3]) {
+		return str[3:]
+	}
+	if len(str) > 3 && isCode(str[len(str)-3:]) {
+		return str[:len(str)-3]
+	}
+	return str
+}
+
+// compareCurrency compares two values as currency amounts within tolerance.
+// The second result is false if either value is not an amount.
+func compareCurrency(val1, val2 interface{}, tolerance float64, decimalSep, groupSep string) (bool, bool) {
+	num1, ok1 := parseCurrency(val1, decimalSep, groupSep)
+	num2, ok2 := parseCurrency(val2, decimalSep, groupSep)
+	if !ok1 || !ok2 {
+		return false, false
+	}
+	return withinTolerance(num1, num2, tolerance), true
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import "testing"
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		val  interface{}
+		want float64
+		ok   bool
+	}{
+		{"$1,234.56", 1234.56, true},
+		{"1234.56", 1234.56, true},
+		{1234.56, 1234.56, true},
+		{"-$5.00", -5, true},
+		{"$-5.00", -5, true},
+		{"(12.50)", -12.5, true},
+		{"USD 1,000", 1000, true},
+		{"1,000 EUR", 1000, true},
+		{"€ 3", 3, true},
+		{"£1,23.4", 0, false},
+		{"$Inf", 0, false},
+		{"free", 0, false},
+		{"", 0, false},
+		{true, 0, false},
+	}
+	for _, test := range tests {
+		got, ok := parseCurrency(test.val, ".", ",")
+		if ok != test.ok || (ok && got != test.want) {
+			t.Errorf("parseCurrency(%#v) = %v, %v, want %v, %v", test.val, got, ok, test.want, test.ok)
+		}
+	}
+
+	if got, ok := parseCurrency("1.234,56 €", ",", "."); !ok || got != 1234.56 {
+		t.Errorf("Expected European separators to parse, got %v, %v", got, ok)
+	}
+}
+
+func TestCurrencyKeys(t *testing.T) {
+	obj1 := map[string]interface{}{"amount": "$1,234.56", "fee": "$1.00", "note": "$5", "other": "$5"}
+	obj2 := map[string]interface{}{"amount": 1234.56, "fee": 1.004, "note": "n/a", "other": 5.0}
+	options := CompareOptions{CurrencyKeys: map[string]bool{"amount": true, "fee": true, "note": true}}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(diffs) != 3 || diffs[0].Path != "fee" || diffs[1].Path != "note" || diffs[2].Path != "other" {
+		t.Errorf("Expected fee, note and other to differ, got %v", diffs)
+	}
+
+	options.FloatTolerance = 0.01
+	diffs = findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(diffs) != 2 || diffs[0].Path != "note" {
+		t.Errorf("Expected fee to match within tolerance, got %v", diffs)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultTypeNames are the JSON types whose default value is ignored on extra
+// keys unless other types are given: false, 0, "", [] and {}
+var defaultTypeNames = []string{"boolean", "number", "string", "array", "object"}
+
+// parseDefaultTypes parses the JSON type names whose default value makes a
+// key only in the second object ignorable. null may be given too.
+func parseDefaultTypes(names []string) (map[string]bool, error) {
+	types := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "boolean", "number", "string", "array", "object", "null":
+			types[name] = true
+		default:
+			return nil, fmt.Errorf("unknown default type %q, expected boolean, number, string, array, object or null", name)
+		}
+	}
+	return types, nil
+}
+
+// isTypeDefault reports whether val is the default value of its type (false,
+// 0, "", [], {} or null) and that type is one of types
+func isTypeDefault(val interface{}, types map[string]bool) bool {
+	if !types[jsonTypeName(val)] {
+		return false
+	}
+	switch v := val.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	num, ok := numberValue(val)
+	return ok && num == 0
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import "testing"
+
+func TestIgnoreDefaultExtras(t *testing.T) {
+	types, err := parseDefaultTypes(defaultTypeNames)
+	if err != nil {
+		t.Fatalf("parseDefaultTypes returned error: %v", err)
+	}
+	options := CompareOptions{DefaultExtraTypes: types}
+
+	obj1 := map[string]interface{}{"name": "svc"}
+	tests := []struct {
+		extra    interface{}
+		ignored  bool
+		typeName string
+	}{
+		{false, true, "boolean"},
+		{true, false, "boolean"},
+		{0.0, true, "number"},
+		{0.5, false, "number"},
+		{"", true, "string"},
+		{"x", false, "string"},
+		{[]interface{}{}, true, "array"},
+		{[]interface{}{0.0}, false, "array"},
+		{map[string]interface{}{}, true, "object"},
+		{map[string]interface{}{"a": false}, false, "object"},
+		{nil, false, "null"},
+	}
+	for _, test := range tests {
+		obj2 := map[string]interface{}{"name": "svc", "extra": test.extra}
+		diffs := findDifferencesWithOptions(obj1, obj2, "", options)
+		if test.ignored != (len(diffs) == 0) {
+			t.Errorf("Extra %s %v: expected ignored=%v, got %v", test.typeName, test.extra, test.ignored, diffs)
+		}
+	}
+
+	// Keys only in the first file are still reported
+	if diffs := findDifferencesWithOptions(map[string]interface{}{"enabled": false}, map[string]interface{}{}, "", options); len(diffs) != 1 {
+		t.Errorf("Expected a key only in the first file to be reported, got %v", diffs)
+	}
+
+	// The types can be limited, and null added
+	options.DefaultExtraTypes, _ = parseDefaultTypes([]string{"boolean", "null"})
+	obj2 := map[string]interface{}{"name": "svc", "enabled": false, "parent": nil, "count": 0.0}
+	diffs := findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(diffs) != 1 || diffs[0].Path != "count" {
+		t.Errorf("Expected only count to be reported, got %v", diffs)
+	}
+
+	if _, err := parseDefaultTypes([]string{"integer"}); err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+}
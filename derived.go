@@ -0,0 +1,66 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DerivedField names a field computed from sibling fields, e.g. fullName from
+// first and last. Its differences within an object are suppressed while every
+// source field is present and equal in both documents, since the systems being
+// compared may compute it differently.
+type DerivedField struct {
+	Field   string   // Derived key, or relative path, within the object
+	Sources []string // Sibling keys the field is computed from
+}
+
+// parseDerivedField parses a hint in the form field:source1,source2,...,
+// e.g. "fullName:first,last"
+func parseDerivedField(spec string) (DerivedField, error) {
+	field, sources, ok := cutLast(spec, ":")
+	if !ok || field == "" || sources == "" {
+		return DerivedField{}, fmt.Errorf("invalid derived field %q, expected field:source1,source2", spec)
+	}
+
+	derived := DerivedField{Field: field}
+	for _, source := range strings.Split(sources, ",") {
+		source = strings.TrimSpace(source)
+		if source == "" {
+			return DerivedField{}, fmt.Errorf("invalid derived field %q: empty source field", spec)
+		}
+		derived.Sources = append(derived.Sources, source)
+	}
+	return derived, nil
+}
+
+// derivedPaths returns the paths, relative to the object at path, of derived
+// fields whose sources are present and equal in both objects. The sources are
+// compared with the same options, but what is found is not reported.
+func derivedPaths(map1, map2 map[string]interface{}, path string, fields []DerivedField, options CompareOptions) []string {
+	options.OnDiff = nil
+	options.FuzzyMatches = nil
+	options.Promotions = nil
+	options.SampledArrays = nil
+	options.ArrayKeys = nil
+	options.ArrayMatches = nil
+
+	var derived []string
+	for _, field := range fields {
+		equal := true
+		for _, source := range field.Sources {
+			val1, ok1 := map1[source]
+			val2, ok2 := map2[source]
+			if !ok1 || !ok2 || len(findDifferencesWithParent(val1, val2, joinPath(path, source), ParentObject, options)) > 0 {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			derived = append(derived, joinPath(path, field.Field))
+		}
+	}
+	return derived
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDerivedField(t *testing.T) {
+	derived, err := parseDerivedField("fullName:first, last")
+	if err != nil || !reflect.DeepEqual(derived, DerivedField{Field: "fullName", Sources: []string{"first", "last"}}) {
+		t.Errorf("Unexpected result %+v, %v", derived, err)
+	}
+
+	for _, spec := range []string{"fullName", ":first", "fullName:", "fullName:first,,last"} {
+		if _, err := parseDerivedField(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestDerivedFields(t *testing.T) {
+	options := CompareOptions{DerivedFields: []DerivedField{{Field: "fullName", Sources: []string{"first", "last"}}}}
+
+	obj1 := map[string]interface{}{"users": []interface{}{
+		map[string]interface{}{"first": "Ada", "last": "Lovelace", "fullName": "Ada Lovelace"},
+		map[string]interface{}{"first": "Alan", "last": "Turing", "fullName": "Alan Turing"},
+		map[string]interface{}{"fullName": "Grace Hopper"},
+	}}
+	obj2 := map[string]interface{}{"users": []interface{}{
+		map[string]interface{}{"first": "Ada", "last": "Lovelace", "fullName": "LOVELACE, Ada"},
+		map[string]interface{}{"first": "Alan", "last": "Turin", "fullName": "Turin, Alan"},
+		map[string]interface{}{"fullName": "Hopper, Grace"},
+	}}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", options)
+	paths := make([]string, len(diffs))
+	for i, diff := range diffs {
+		paths[i] = diff.Path
+	}
+	// The derived field is only ignored where both sources are present and equal
+	expected := []string{"users[1].fullName", "users[1].last", "users[2].fullName"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected differences at %v, got %v", expected, paths)
+	}
+
+	// Sources are compared with the same options
+	options.IgnoreCaseValues = true
+	obj1 = map[string]interface{}{"first": "ada", "last": "lovelace", "fullName": "ada lovelace"}
+	obj2 = map[string]interface{}{"first": "Ada", "last": "Lovelace", "fullName": "Lovelace, Ada"}
+	if diffs := findDifferencesWithOptions(obj1, obj2, "", options); len(diffs) != 0 {
+		t.Errorf("Expected sources equal ignoring case to suppress the derived field, got %v", diffs)
+	}
+}
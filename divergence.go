@@ -0,0 +1,74 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Divergence is the first place two canonical JSON texts differ
+type Divergence struct {
+	Offset int // Byte offset in the canonical text
+	Line   int // 1-based line number
+	Column int // 1-based column, counted in characters
+}
+
+// canonicalJSON renders a value as canonical JSON: object keys sorted, two
+// spaces of indentation and no HTML escaping, so equal values give equal text
+func canonicalJSON(data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(encodeNonFinite(data)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// firstDivergence scans two texts for the first byte at which they differ.
+// The boolean result is false if they are identical. If one text is a prefix
+// of the other, they diverge where the shorter one ends.
+func firstDivergence(text1, text2 []byte) (Divergence, bool) {
+	offset := 0
+	for offset < len(text1) && offset < len(text2) && text1[offset] == text2[offset] {
+		offset++
+	}
+	if offset == len(text1) && offset == len(text2) {
+		return Divergence{}, false
+	}
+
+	// Back up to the start of a character split by the divergence
+	midRune := func(text []byte) bool {
+		return offset < len(text) && !utf8.RuneStart(text[offset])
+	}
+	for offset > 0 && (midRune(text1) || midRune(text2)) {
+		offset--
+	}
+
+	lineStart := bytes.LastIndexByte(text1[:offset], '\n') + 1
+	return Divergence{
+		Offset: offset,
+		Line:   bytes.Count(text1[:offset], []byte("\n")) + 1,
+		Column: utf8.RuneCount(text1[lineStart:offset]) + 1,
+	}, true
+}
+
+// formatDivergence describes where two canonical texts first differ and
+// shows the line at that point in each
+func formatDivergence(div Divergence, text1, text2 []byte) string {
+	line := func(text []byte) string {
+		start := bytes.LastIndexByte(text[:min(div.Offset, len(text))], '\n') + 1
+		end := bytes.IndexByte(text[start:], '\n')
+		if end < 0 {
+			return string(text[start:])
+		}
+		return string(text[start : start+end])
+	}
+	return fmt.Sprintf("mismatch at line %d col %d (byte offset %d)\n- %s\n+ %s\n",
+		div.Line, div.Column, div.Offset, line(text1), line(text2))
+}
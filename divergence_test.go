@@ -0,0 +1,60 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFirstDivergence(t *testing.T) {
+	obj1 := map[string]interface{}{"b": []interface{}{1.0, "x<y"}, "a": "café"}
+	obj2 := map[string]interface{}{"a": "café", "b": []interface{}{1.0, "x<z"}}
+
+	text1, err := canonicalJSON(obj1)
+	if err != nil {
+		t.Fatalf("canonicalJSON returned error: %v", err)
+	}
+	text2, _ := canonicalJSON(obj2)
+	if !strings.HasPrefix(string(text1), "{\n  \"a\": \"café\",\n") {
+		t.Errorf("Expected sorted, indented, unescaped output, got %s", text1)
+	}
+
+	div, diverged := firstDivergence(text1, text2)
+	if !diverged || div.Line != 5 || div.Column != 8 {
+		t.Fatalf("Expected a divergence at line 5 col 8, got %+v (%v)", div, diverged)
+	}
+	expected := "mismatch at line 5 col 8 (byte offset 41)\n-     \"x<y\"\n+     \"x<z\"\n"
+	if got := formatDivergence(div, text1, text2); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if _, diverged := firstDivergence(text1, text1); diverged {
+		t.Error("Expected identical texts not to diverge")
+	}
+
+	// Key order doesn't matter, and a divergence inside a character starts at the character
+	if _, diverged := firstDivergence(mustCanonical(t, obj1), mustCanonical(t, map[string]interface{}{"a": "café", "b": obj1["b"]})); diverged {
+		t.Error("Expected key order not to matter")
+	}
+	div, _ = firstDivergence([]byte("\"é\""), []byte("\"è\""))
+	if div.Offset != 1 || div.Column != 2 {
+		t.Errorf("Expected the divergence at the start of the character, got %+v", div)
+	}
+
+	// A text that is a prefix of the other diverges where it ends
+	div, _ = firstDivergence([]byte("[1]"), []byte("[1]\n"))
+	if div.Offset != 3 {
+		t.Errorf("Expected a divergence at offset 3, got %+v", div)
+	}
+}
+
+func mustCanonical(t *testing.T, data interface{}) []byte {
+	t.Helper()
+	text, err := canonicalJSON(data)
+	if err != nil {
+		t.Fatalf("canonicalJSON returned error: %v", err)
+	}
+	return text
+}
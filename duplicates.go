@@ -0,0 +1,153 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DuplicateKeyCheck names an array whose elements should have unique values for a key
+type DuplicateKeyCheck struct {
+	Path string // Path of the array, "" for the root
+	Key  string // Identity key within each element
+}
+
+// DuplicateKey is a key value shared by several elements of one array
+type DuplicateKey struct {
+	Path    string
+	Key     string
+	Value   interface{}
+	Indices []int
+}
+
+// parseDuplicateKeyCheck parses a path:key specification, using . for the root
+func parseDuplicateKeyCheck(spec string) (DuplicateKeyCheck, error) {
+	path, key, found := cutLast(spec, ":")
+	if !found || path == "" || key == "" {
+		return DuplicateKeyCheck{}, fmt.Errorf("invalid duplicate key check %q, expected path:key", spec)
+	}
+	if path == "." {
+		path = ""
+	}
+	if _, err := parsePath(path); err != nil {
+		return DuplicateKeyCheck{}, err
+	}
+	return DuplicateKeyCheck{Path: path, Key: key}, nil
+}
+
+// findDuplicateKeys reports the values of check.Key shared by more than one
+// element of the array at check.Path, in order of first appearance. Elements
+// without the key are skipped, and a missing path has no duplicates.
+func findDuplicateKeys(data interface{}, check DuplicateKeyCheck) ([]DuplicateKey, error) {
+	val, found, err := lookupPath(data, check.Path)
+	if err != nil || !found {
+		return nil, err
+	}
+	arr, ok := val.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not an array", displayPath(check.Path))
+	}
+
+	// Values are indexed by their JSON encoding so objects and numbers compare by content
+	var duplicates []DuplicateKey
+	seen := make(map[string]int)
+	for i, elem := range arr {
+		obj, ok := elem.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, ok := obj[check.Key]
+		if !ok {
+			continue
+		}
+		encoded, err := json.Marshal(id)
+		if err != nil {
+			continue
+		}
+
+		first, ok := seen[string(encoded)]
+		if !ok {
+			seen[string(encoded)] = i
+			continue
+		}
+		found := false
+		for j := range duplicates {
+			if duplicates[j].Indices[0] == first {
+				duplicates[j].Indices = append(duplicates[j].Indices, i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			duplicates = append(duplicates, DuplicateKey{
+				Path:    check.Path,
+				Key:     check.Key,
+				Value:   id,
+				Indices: []int{first, i},
+			})
+		}
+	}
+
+	return duplicates, nil
+}
+
+// checkDuplicateKeys runs every check against a file, checking each document
+// separately in multi-document mode. Reported paths get the document prefix
+// and then pathPrefix prepended, with array indices shown from indexBase.
+func checkDuplicateKeys(file *JSONFile, checks []DuplicateKeyCheck, pathPrefix string, indexBase int) ([]DuplicateKey, error) {
+	documents := []interface{}{file.Data}
+	docPrefix := func(int) string { return "" }
+	if file.Documents != nil {
+		documents = file.Documents
+		docPrefix = func(i int) string { return fmt.Sprintf("doc[%d]", i) }
+	}
+
+	var duplicates []DuplicateKey
+	for i, doc := range documents {
+		for _, check := range checks {
+			found, err := findDuplicateKeys(doc, check)
+			if err != nil {
+				return nil, err
+			}
+			for _, dup := range found {
+				dup.Path = prefixPath(pathPrefix, rebaseIndices(prefixPath(docPrefix(i), dup.Path), indexBase))
+				for j := range dup.Indices {
+					dup.Indices[j] += indexBase
+				}
+				duplicates = append(duplicates, dup)
+			}
+		}
+	}
+	return duplicates, nil
+}
+
+// displayPath shows the root path as "."
+func displayPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+// formatDuplicateKeys formats the duplicates found in one file as a report
+// section, or returns "" if there are none
+func formatDuplicateKeys(label string, duplicates []DuplicateKey) string {
+	if len(duplicates) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Duplicate keys in %s:\n", label)
+	for _, dup := range duplicates {
+		indices := make([]string, len(dup.Indices))
+		for i, index := range dup.Indices {
+			indices[i] = fmt.Sprintf("[%d]", index)
+		}
+		fmt.Fprintf(&sb, "  %s: %s=%v at %s\n", displayPath(dup.Path), dup.Key, dup.Value, strings.Join(indices, ", "))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
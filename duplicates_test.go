@@ -0,0 +1,64 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindDuplicateKeys(t *testing.T) {
+	data := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"id": 1.0},
+			map[string]interface{}{"id": 2.0},
+			map[string]interface{}{"id": 1.0},
+			map[string]interface{}{"name": "no id"},
+			map[string]interface{}{"id": "2"},
+			map[string]interface{}{"id": 1.0},
+		},
+		"name": "John",
+	}
+
+	check, err := parseDuplicateKeyCheck("items:id")
+	if err != nil {
+		t.Fatalf("Failed to parse check: %v", err)
+	}
+	duplicates, err := findDuplicateKeys(data, check)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// "2" and 2 are different identity values
+	expected := []DuplicateKey{{Path: "items", Key: "id", Value: 1.0, Indices: []int{0, 2, 5}}}
+	if !reflect.DeepEqual(duplicates, expected) {
+		t.Errorf("Expected %v, got %v", expected, duplicates)
+	}
+
+	report := formatDuplicateKeys("first file", duplicates)
+	if report != "Duplicate keys in first file:\n  items: id=1 at [0], [2], [5]\n\n" {
+		t.Errorf("Unexpected report %q", report)
+	}
+
+	if _, err := findDuplicateKeys(data, DuplicateKeyCheck{Path: "name", Key: "id"}); err == nil {
+		t.Error("Expected an error for a path that isn't an array")
+	}
+	if dups, err := findDuplicateKeys(data, DuplicateKeyCheck{Path: "missing", Key: "id"}); err != nil || dups != nil {
+		t.Errorf("Expected no duplicates for a missing path, got %v (%v)", dups, err)
+	}
+	if _, err := parseDuplicateKeyCheck("items"); err == nil {
+		t.Error("Expected an error for a check without a key")
+	}
+
+	// Each document is checked separately in multi-document mode
+	root, _ := parseDuplicateKeyCheck(".:id")
+	file := &JSONFile{Documents: []interface{}{
+		[]interface{}{map[string]interface{}{"id": "a"}},
+		[]interface{}{map[string]interface{}{"id": "a"}, map[string]interface{}{"id": "a"}},
+	}}
+	duplicates, err = checkDuplicateKeys(file, []DuplicateKeyCheck{root}, "data", 0)
+	if err != nil || len(duplicates) != 1 || duplicates[0].Path != "data.doc[1]" {
+		t.Errorf("Expected one duplicate in data.doc[1], got %v (%v)", duplicates, err)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Ways to handle a placeholder naming an unset environment variable
+const (
+	MissingEnvKeep  = "keep"  // The placeholder is left as written
+	MissingEnvError = "error" // Reading the file fails
+)
+
+// expandEnv replaces ${VAR} and $VAR placeholders in data with the values of
+// environment variables, as os.Expand does. A variable name is letters,
+// digits and underscores, not starting with a digit. A placeholder naming an
+// unset variable is kept as written, or is an error if onMissing is
+// MissingEnvError.
+func expandEnv(data []byte, onMissing string) ([]byte, error) {
+	var expanded []byte
+	for i := 0; i < len(data); i++ {
+		if data[i] != '$' || i+1 >= len(data) {
+			expanded = append(expanded, data[i])
+			continue
+		}
+
+		// Find the name and the end of the placeholder
+		start, end, next := i+1, i+1, i+1
+		if data[i+1] == '{' {
+			start = i + 2
+			end = start
+			for end < len(data) && isEnvNameByte(data[end], end == start) {
+				end++
+			}
+			if end == start || end >= len(data) || data[end] != '}' {
+				expanded = append(expanded, data[i])
+				continue
+			}
+			next = end + 1
+		} else {
+			for end < len(data) && isEnvNameByte(data[end], end == start) {
+				end++
+			}
+			if end == start {
+				expanded = append(expanded, data[i])
+				continue
+			}
+			next = end
+		}
+
+		name := string(data[start:end])
+		value, ok := os.LookupEnv(name)
+		switch {
+		case ok:
+			expanded = append(expanded, value...)
+		case onMissing == MissingEnvError:
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		default:
+			expanded = append(expanded, data[i:next]...)
+		}
+		i = next - 1
+	}
+	return expanded, nil
+}
+
+// isEnvNameByte reports whether b may appear in an environment variable
+// name, at its start if first is set
+func isEnvNameByte(b byte, first bool) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (!first && b >= '0' && b <= '9')
+}
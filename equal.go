@@ -0,0 +1,58 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// jsonEqual reports whether two values are exactly equal, with the same result
+// as reflect.DeepEqual. Decoded JSON (objects, arrays, strings, numbers,
+// booleans and null) is compared with type switches, which is several times
+// faster than reflection on large equal subtrees; other types fall back to
+// reflect.DeepEqual.
+func jsonEqual(val1, val2 interface{}) bool {
+	switch v1 := val1.(type) {
+	case nil:
+		return val2 == nil
+	case string:
+		v2, ok := val2.(string)
+		return ok && v1 == v2
+	case float64:
+		v2, ok := val2.(float64)
+		return ok && v1 == v2
+	case bool:
+		v2, ok := val2.(bool)
+		return ok && v1 == v2
+	case json.Number:
+		v2, ok := val2.(json.Number)
+		return ok && v1 == v2
+	case map[string]interface{}:
+		v2, ok := val2.(map[string]interface{})
+		if !ok || len(v1) != len(v2) || (v1 == nil) != (v2 == nil) {
+			return false
+		}
+		for key, elem1 := range v1 {
+			elem2, ok := v2[key]
+			if !ok || !jsonEqual(elem1, elem2) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		v2, ok := val2.([]interface{})
+		if !ok || len(v1) != len(v2) || (v1 == nil) != (v2 == nil) {
+			return false
+		}
+		for i := range v1 {
+			if !jsonEqual(v1[i], v2[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(val1, val2)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONEqual(t *testing.T) {
+	values := []interface{}{
+		nil,
+		"1",
+		float64(1),
+		float64(0),
+		true,
+		false,
+		json.Number("1"),
+		[]interface{}{},
+		[]interface{}(nil),
+		[]interface{}{float64(1), "a"},
+		[]interface{}{"a", float64(1)},
+		map[string]interface{}{},
+		map[string]interface{}(nil),
+		map[string]interface{}{"a": float64(1)},
+		map[string]interface{}{"a": "1"},
+		map[string]interface{}{"b": float64(1)},
+		map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": nil}}},
+		map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": false}}},
+		int(1),
+	}
+
+	// jsonEqual must agree with reflect.DeepEqual for every pair
+	for _, val1 := range values {
+		for _, val2 := range values {
+			if got, want := jsonEqual(val1, val2), reflect.DeepEqual(val1, val2); got != want {
+				t.Errorf("jsonEqual(%#v, %#v) = %v, want %v", val1, val2, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultExecTimeout bounds how long an external comparator may run
+const defaultExecTimeout = 5 * time.Second
+
+// runExecComparator asks an external program whether two values are equal.
+// The command is split on whitespace into a program and its arguments, and is
+// given the two values as JSON on stdin, one per line. Exit status 0 means
+// equal and 1 means not equal; any other outcome, including a timeout, is
+// treated as not equal with a warning on stderr. The command is killed when
+// ctx is done, without a warning, as the comparison is then partial anyway.
+func runExecComparator(ctx context.Context, command string, val1, val2 interface{}, path string, timeout time.Duration) bool {
+	equal, err := execComparator(ctx, command, val1, val2, timeout)
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Warning: comparator for %s failed, treating values as different: %v\n", path, err)
+	}
+	return equal
+}
+
+// execComparator runs an external comparator, returning an error when it
+// did not give a clear answer before the timeout or before parent was done
+func execComparator(parent context.Context, command string, val1, val2 interface{}, timeout time.Duration) (bool, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return false, fmt.Errorf("empty command")
+	}
+
+	var input bytes.Buffer
+	encoder := json.NewEncoder(&input)
+	if err := encoder.Encode(encodeNonFinite(val1)); err != nil {
+		return false, err
+	}
+	if err := encoder.Encode(encodeNonFinite(val2)); err != nil {
+		return false, err
+	}
+
+	if timeout <= 0 {
+		timeout = defaultExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = &input
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+	if parent.Err() != nil {
+		return false, parent.Err()
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return false, fmt.Errorf("timed out after %v", timeout)
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return false, nil
+	default:
+		return false, err
+	}
+}
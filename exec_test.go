@@ -0,0 +1,82 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeScript writes an executable shell script for use as an external comparator
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	scriptPath := filepath.Join(t.TempDir(), "compare.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	return scriptPath
+}
+
+func TestExecComparator(t *testing.T) {
+	// Equal when both values have the same number of characters
+	sameLength := writeScript(t, `read a; read b; [ ${#a} -eq ${#b} ] && exit 0; exit 1`)
+
+	obj1 := map[string]interface{}{"code": "abc", "other": "abc"}
+	obj2 := map[string]interface{}{"code": "xyz", "other": "xyz"}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		ExecComparators: map[string]string{"code": sameLength},
+	})
+	if len(diffs) != 1 || diffs[0].Path != "other" {
+		t.Errorf("Expected only other to differ, got %v", diffs)
+	}
+
+	obj2["code"] = "wxyz"
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		ExecComparators: map[string]string{"code": sameLength},
+	})
+	if len(diffs) != 2 {
+		t.Errorf("Expected 2 differences, got %d: %v", len(diffs), diffs)
+	}
+}
+
+func TestExecComparatorFailures(t *testing.T) {
+	testCases := []struct {
+		name    string
+		command string
+		timeout time.Duration
+	}{
+		{"Missing program", filepath.Join(t.TempDir(), "missing"), time.Second},
+		{"Unexpected exit status", writeScript(t, "exit 2"), time.Second},
+		{"Timeout", writeScript(t, "exec sleep 5"), 50 * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			equal, err := execComparator(context.Background(), tc.command, "a", "a", tc.timeout)
+			if equal || err == nil {
+				t.Errorf("Expected a failed comparator to be not equal with an error, got %v, %v", equal, err)
+			}
+		})
+	}
+
+	// Exit status 1 is a plain "not equal"
+	equal, err := execComparator(context.Background(), writeScript(t, "exit 1"), "a", "b", time.Second)
+	if equal || err != nil {
+		t.Errorf("Expected exit 1 to mean not equal without an error, got %v, %v", equal, err)
+	}
+
+	// The command is killed once the comparison's own context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	equal, err = execComparator(ctx, writeScript(t, "exec sleep 5"), "a", "a", time.Minute)
+	if equal || !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 2*time.Second {
+		t.Errorf("Expected the comparator to be cancelled with the comparison, got %v, %v after %v", equal, err, time.Since(start))
+	}
+}
@@ -2,4 +2,9 @@ module github.com/chrissewell/jsondiff
 
 go 1.24.5
 
-require github.com/agnivade/levenshtein v1.2.1 // indirect
+require (
+	github.com/Masterminds/semver/v3 v3.4.0
+	github.com/agnivade/levenshtein v1.2.1
+	golang.org/x/text v0.34.0
+	gopkg.in/yaml.v3 v3.0.1
+)
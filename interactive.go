@@ -0,0 +1,52 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// reviewDifferences presents differences one at a time and returns the ones
+// the user accepted. Each answer is read as a line from in:
+//
+//	(empty) or n  show the next difference
+//	a             accept the difference and show the next one
+//	s             skip the remaining differences in the same object or array
+//	q             stop reviewing
+func reviewDifferences(differences []Diff, in io.Reader, out io.Writer) []Diff {
+	reader := bufio.NewReader(in)
+	accepted := []Diff{}
+
+	for i := 0; i < len(differences); i++ {
+		diff := differences[i]
+		fmt.Fprintf(out, "\n[%d/%d] %s", i+1, len(differences), formatDiffText(diff))
+		fmt.Fprint(out, "[Enter] next, (a)ccept, (s)kip subtree, (q)uit: ")
+
+		line, err := reader.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if err != nil && answer == "" {
+			// Input closed, stop as if the user quit
+			fmt.Fprintln(out)
+			break
+		}
+
+		switch answer {
+		case "a":
+			accepted = append(accepted, diff)
+		case "s":
+			// Skip past every following difference under the same parent
+			parent := parentPath(diff.Path)
+			for i+1 < len(differences) && isUnderPath(differences[i+1].Path, parent) {
+				i++
+			}
+		case "q":
+			return accepted
+		}
+	}
+
+	return accepted
+}
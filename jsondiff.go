@@ -4,11 +4,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"sort"
 	"strings"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // DiffType represents the type of difference between JSON objects
@@ -21,6 +25,13 @@ const (
 	KeyOnlyInSecond
 	ArrayLength
 	TypeMismatch
+	ArrayDiffsTruncated
+	DocumentCount
+	KeyCaseMismatch
+	DepthExceeded
+	Moved
+	RequiredMissing
+	OneofChanged
 )
 
 // MarshalJSON implements the json.Marshaler interface for DiffType
@@ -28,6 +39,21 @@ func (dt DiffType) MarshalJSON() ([]byte, error) {
 	return json.Marshal(dt.String())
 }
 
+// UnmarshalJSON implements the json.Unmarshaler interface for DiffType
+func (dt *DiffType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for t := ValueMismatch; t.String() != "unknown"; t++ {
+		if t.String() == name {
+			*dt = t
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown difference type %q", name)
+}
+
 // String returns the string representation of a DiffType
 func (dt DiffType) String() string {
 	switch dt {
@@ -41,6 +67,64 @@ func (dt DiffType) String() string {
 		return "array_length"
 	case TypeMismatch:
 		return "type_mismatch"
+	case ArrayDiffsTruncated:
+		return "array_diffs_truncated"
+	case DocumentCount:
+		return "document_count"
+	case KeyCaseMismatch:
+		return "key_case_mismatch"
+	case DepthExceeded:
+		return "depth_exceeded"
+	case Moved:
+		return "moved"
+	case RequiredMissing:
+		return "required_missing"
+	case OneofChanged:
+		return "oneof_changed"
+	default:
+		return "unknown"
+	}
+}
+
+// ParentType identifies the kind of container a difference was found in
+type ParentType int
+
+// Enum values for ParentType
+const (
+	ParentRoot ParentType = iota
+	ParentObject
+	ParentArray
+)
+
+// MarshalJSON implements the json.Marshaler interface for ParentType
+func (pt ParentType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pt.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for ParentType
+func (pt *ParentType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for t := ParentRoot; t.String() != "unknown"; t++ {
+		if t.String() == name {
+			*pt = t
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown parent type %q", name)
+}
+
+// String returns the string representation of a ParentType
+func (pt ParentType) String() string {
+	switch pt {
+	case ParentRoot:
+		return "root"
+	case ParentObject:
+		return "object"
+	case ParentArray:
+		return "array"
 	default:
 		return "unknown"
 	}
@@ -48,10 +132,16 @@ func (dt DiffType) String() string {
 
 // Diff represents a difference between two JSON objects
 type Diff struct {
-	Path   string      `json:"path"`   // Path to the key where the difference was found
-	Type   DiffType    `json:"type"`   // Type of difference
-	Value1 interface{} `json:"value1"` // Value from the first object
-	Value2 interface{} `json:"value2"` // Value from the second object
+	Path       string      `json:"path"`             // Path to the key where the difference was found
+	Type       DiffType    `json:"type"`             // Type of difference
+	Value1     interface{} `json:"value1"`           // Value from the first object
+	Value2     interface{} `json:"value2"`           // Value from the second object
+	ParentType ParentType  `json:"parentType"`       // Kind of container holding the value at Path
+	Detail     string      `json:"detail,omitempty"` // Optional explanation of how the values were compared
+	Severity   Severity    `json:"severity"`         // How serious the difference is, derived from Type unless overridden by path
+	Tags       []string    `json:"tags,omitempty"`   // User-defined categories of the paths containing the difference
+	Loc1       *Location   `json:"loc1,omitempty"`   // Where the value at Path starts in the first file, or its nearest enclosing value, if locations were recorded
+	Loc2       *Location   `json:"loc2,omitempty"`   // Where the value at Path starts in the second file, or its nearest enclosing value, if locations were recorded
 }
 
 // FindDifferences recursively compares two JSON objects and returns a list of differences
@@ -71,15 +161,48 @@ func FindDifferences(obj1, obj2 interface{}, path string, ignoreCase, ignoreCase
 }
 
 // compareValues compares two values with all the special handling options
-// Returns true if the values are considered equal according to the options
-func compareValues(val1, val2 interface{}, path string, options CompareOptions) bool {
+// Returns true if the values are considered equal according to the options, and a
+// FuzzyMatch recording the margin when they were only equal within a threshold
+func compareValues(val1, val2 interface{}, path string, options CompareOptions) (bool, *FuzzyMatch) {
+	// A wildcard in the first file accepts any value
+	if isWildcard(val1, options) {
+		return true, nil
+	}
+
+	// Normalize leaves by their JSON type, unless a comparator is set for this path
+	if len(options.TypeNormalizers) > 0 && !options.KeysOnly && !hasPathComparator(path, options) {
+		val1 = normalizeByType(val1, options.TypeNormalizers)
+		val2 = normalizeByType(val2, options.TypeNormalizers)
+	}
+
+	// Special handling for strings that differ only in Unicode normalization
+	if options.FoldUnicode && !options.KeysOnly {
+		str1, isStr1 := val1.(string)
+		str2, isStr2 := val2.(string)
+		if isStr1 && isStr2 {
+			str1, str2 = norm.NFC.String(str1), norm.NFC.String(str2)
+			if str1 == str2 || (options.IgnoreCaseValues && strings.EqualFold(str1, str2)) {
+				// Strings are equal once normalized
+				return true, nil
+			}
+		}
+	}
+
+	// Special handling for strings that rank equal under a locale's collation
+	if options.Collation != "" && !options.KeysOnly {
+		if equal, ok := collatedEqual(val1, val2, options); ok && equal {
+			// Strings are equal according to the locale
+			return true, nil
+		}
+	}
+
 	// Special handling for strings when IgnoreCaseValues is true
 	if options.IgnoreCaseValues && !options.KeysOnly {
 		str1, isStr1 := val1.(string)
 		str2, isStr2 := val2.(string)
 		if isStr1 && isStr2 && strings.EqualFold(str1, str2) {
 			// Strings are equal when ignoring case
-			return true
+			return true, nil
 		}
 	}
 
@@ -87,260 +210,964 @@ func compareValues(val1, val2 interface{}, path string, options CompareOptions)
 	if options.IgnoreNullValues && !options.KeysOnly {
 		if val1 == nil || val2 == nil {
 			// If either value is null, consider them equal
-			return true
+			return true, nil
 		}
 	}
 
 	// Special handling for regex matching
 	if !options.KeysOnly && len(options.RegexMatches) > 0 {
 		// Check if this key path has a regex pattern
-		if pattern, ok := options.RegexMatches[path]; ok {
+		if pattern, ok := lookupScoped(options.RegexMatches, path); ok {
 			// Check if both values match the pattern
 			matches, err := matchesRegex(val1, val2, pattern)
 			if err == nil && matches {
 				// Both values match the pattern, consider them equal
-				return true
+				return true, nil
+			}
+		}
+	}
+
+	// Special handling for paths delegated to an external comparator
+	if !options.KeysOnly {
+		if command, ok := lookupScoped(options.ExecComparators, path); ok {
+			ctx := options.Context
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if runExecComparator(ctx, command, val1, val2, path, options.ExecTimeout) {
+				// The comparator considers the values equal
+				return true, nil
+			}
+		}
+	}
+
+	// Special handling for enums given by name or number
+	if !options.KeysOnly {
+		if names, ok := lookupScoped(options.EnumValues, path); ok {
+			if equal, ok := compareEnumValues(val1, val2, names); ok && equal {
+				// Enum name and number refer to the same value
+				return true, nil
 			}
 		}
 	}
 
+	// Special handling for values with units
+	if !options.KeysOnly {
+		if unit, ok := lookupScoped(options.UnitKeys, path); ok {
+			if equal, ok := compareUnitValues(val1, val2, unit); ok && equal {
+				// Values are equal once converted to the base unit
+				return true, nil
+			}
+		}
+	}
+
+	// Special handling for semantic version strings
+	if semver, _ := lookupScoped(options.SemverKeys, path); semver && !options.KeysOnly {
+		if equal, ok := compareSemver(val1, val2); ok && equal {
+			// Versions are equal once parsed
+			return true, nil
+		}
+	}
+
+	// Special handling for UUIDs written in different formats
+	if uuid, _ := lookupScoped(options.UUIDKeys, path); uuid && !options.KeysOnly {
+		if equal, ok := compareUUID(val1, val2); ok && equal {
+			// UUIDs are equal once normalized
+			return true, nil
+		}
+	}
+
+	// Special handling for amounts written with currency symbols
+	if currency, _ := lookupScoped(options.CurrencyKeys, path); currency && !options.KeysOnly {
+		if equal, ok := compareCurrency(val1, val2, options.FloatTolerance, options.DecimalSeparator, options.GroupSeparator); ok && equal {
+			// Amounts are equal once parsed, within any tolerance
+			num1, _ := parseCurrency(val1, options.DecimalSeparator, options.GroupSeparator)
+			num2, _ := parseCurrency(val2, options.DecimalSeparator, options.GroupSeparator)
+			return true, newFuzzyMatch(path, FuzzyFloatTolerance, val1, val2, math.Abs(num1-num2), options.FloatTolerance)
+		}
+	}
+
 	// Special handling for Levenshtein distance
 	if !options.KeysOnly && len(options.LevenshteinKeys) > 0 && options.LevenshteinThreshold > 0 {
 		// Check if this key path should use Levenshtein distance
-		if _, ok := options.LevenshteinKeys[path]; ok {
+		if _, ok := lookupScoped(options.LevenshteinKeys, path); ok {
 			// Check if strings are similar using Levenshtein distance
-			if compareLevenshtein(val1, val2, options.LevenshteinThreshold) {
+			distance, ok := levenshteinDistance(val1, val2)
+			if ok && distance <= options.LevenshteinThreshold {
 				// Strings are similar enough, consider them equal
-				return true
+				return true, newFuzzyMatch(path, FuzzyLevenshtein, val1, val2, float64(distance), float64(options.LevenshteinThreshold))
+			}
+		}
+	}
+
+	// Special handling for paths with their own numeric rule, which replaces the float tolerance
+	if !options.KeysOnly {
+		if rule, ok := lookupScoped(options.NumericRules, path); ok {
+			num1, isNum1 := ruleNumber(val1, options)
+			num2, isNum2 := ruleNumber(val2, options)
+			if isNum1 && isNum2 {
+				equal, distance, threshold := rule.compare(num1, num2)
+				if !equal {
+					return false, nil
+				}
+				// Numbers are equal under the rule
+				return true, newFuzzyMatch(path, FuzzyFloatTolerance, val1, val2, distance, threshold)
 			}
 		}
 	}
 
+	// Special handling for numeric tolerance
+	if options.FloatTolerance > 0 && !options.KeysOnly {
+		num1, isNum1 := numberValue(val1)
+		num2, isNum2 := numberValue(val2)
+		if isNum1 && isNum2 && withinTolerance(num1, num2, options.FloatTolerance) {
+			// Numbers are equal within tolerance
+			return true, newFuzzyMatch(path, FuzzyFloatTolerance, val1, val2, math.Abs(num1-num2), options.FloatTolerance)
+		}
+	}
+
 	// Special handling for boolean types
 	if options.IgnoreBooleanType && !options.KeysOnly {
-		if equal, ok := compareBooleanValues(val1, val2); ok && equal {
+		if equal, ok := compareBooleanValues(val1, val2, options.NumericBooleans); ok && equal {
 			// Values are equal when compared as booleans
-			return true
+			return true, nil
 		}
 	}
 
-	// Special handling for numeric types
-	if options.IgnoreNumericType && !options.KeysOnly {
-		if compareNumericValues(val1, val2) {
+	// Special handling for numeric types, parsing only the first value's numeric strings if requested
+	if (options.IgnoreNumericType || options.CoerceLeftNumStrings) && !options.KeysOnly {
+		leftOnly := !options.IgnoreNumericType
+		num1, num2 := val1, val2
+		if options.ParseGroupedNumbers {
+			// Strip locale grouping so "1,234.56" parses as 1234.56
+			num1 = ungroupNumber(val1, options.DecimalSeparator, options.GroupSeparator)
+			num2 = ungroupNumber(val2, options.DecimalSeparator, options.GroupSeparator)
+		}
+		if compareNumericValues(num1, num2, options.FloatTolerance, leftOnly) {
 			// Values are equal when compared as numbers
-			return true
+			distance, _ := numericDistance(num1, num2)
+			return true, newFuzzyMatch(path, FuzzyFloatTolerance, val1, val2, distance, options.FloatTolerance)
+		}
+	}
+
+	// Special handling for strings written as numbers
+	if options.NormalizeNumStrings && !options.KeysOnly {
+		str1, isStr1 := val1.(string)
+		str2, isStr2 := val2.(string)
+		if isStr1 && isStr2 {
+			num1, ok1 := normalizeNumericString(str1)
+			num2, ok2 := normalizeNumericString(str2)
+			if ok1 && ok2 && num1 == num2 {
+				// Strings are equal once written in canonical form
+				return true, nil
+			}
 		}
 	}
 
 	// Standard comparison
-	return reflect.DeepEqual(val1, val2)
+	return jsonEqual(val1, val2), nil
 }
 
+// valuesEqual compares two values with compareValues and records any fuzzy
+// match in options.FuzzyMatches and numeric promotion in options.Promotions
+// when they are set
+func valuesEqual(val1, val2 interface{}, path string, options CompareOptions) bool {
+	equal, match := compareValues(val1, val2, path, options)
+	if equal && match != nil && options.FuzzyMatches != nil {
+		*options.FuzzyMatches = append(*options.FuzzyMatches, *match)
+	}
+	if equal && options.Promotions != nil {
+		if promotion := numericPromotion(val1, val2, path, options); promotion != nil {
+			*options.Promotions = append(*options.Promotions, *promotion)
+		}
+	}
+	return equal
+}
+
+// defaultMaxDepth is the nesting depth below which values are not compared
+// unless CompareOptions.MaxDepth says otherwise. It keeps hostile, deeply nested
+// input from exhausting the stack during the recursive traversal.
+const defaultMaxDepth = 10000
+
 // findDifferencesWithOptions is the internal implementation that handles all comparison options
 func findDifferencesWithOptions(obj1, obj2 interface{}, path string, options CompareOptions) []Diff {
+	if options.cache == nil {
+		options.cache = newComparisonCache(options)
+	}
+	if options.collator == nil {
+		options.collator = newCollator(options)
+	}
+	differences := findDifferencesWithParent(obj1, obj2, path, ParentRoot, options)
+	differences = notifyDiffs(assignTags(assignSeverities(differences, options.SeverityOverrides), options.PathTags), options)
+	return filterPathExprs(differences, options.IgnorePaths, options.OnlyPaths)
+}
+
+// notifyDiffs passes each difference to options.OnDiff, if set, once a
+// comparison has finished, so hooks see every raw difference before output
+// options filter them. A summary of truncated array elements is not passed;
+// the differences it stands for were passed by notifyFiltered.
+func notifyDiffs(differences []Diff, options CompareOptions) []Diff {
+	if options.OnDiff != nil {
+		for _, diff := range differences {
+			if diff.Type != ArrayDiffsTruncated {
+				options.OnDiff(diff)
+			}
+		}
+	}
+	return differences
+}
+
+// notifyFiltered passes differences the traversal drops, such as keys allowed
+// by IgnoreExtraAt or elements past MaxArrayDiffs, to options.OnDiff, if set,
+// so hooks count them although they are never reported
+func notifyFiltered(differences []Diff, options CompareOptions) {
+	if options.OnDiff != nil {
+		notifyDiffs(assignTags(assignSeverities(differences, options.SeverityOverrides), options.PathTags), options)
+	}
+}
+
+// findDifferencesWithParent compares two values found at path inside a container of
+// the given parent type, which is recorded on differences reported at path itself
+func findDifferencesWithParent(obj1, obj2 interface{}, path string, parent ParentType, options CompareOptions) []Diff {
 	differences := []Diff{}
 
+	// Objects keyed by sequential indices line up with arrays if requested
+	if options.CoerceNumericObjects {
+		obj1, obj2 = coerceNumericObjects(obj1, obj2)
+	}
+
+	// A one-element array lines up with the object it wraps if requested
+	if options.UnwrapSingletons {
+		obj1, obj2 = unwrapSingletonArrays(obj1, obj2)
+	}
+
+	// Reduce wrapper objects, and those among the children, to their wrapped value
+	if options.UnwrapValueKey != "" {
+		obj1 = unwrapChildValues(unwrapValue(obj1, options.UnwrapValueKey), options.UnwrapValueKey)
+		obj2 = unwrapChildValues(unwrapValue(obj2, options.UnwrapValueKey), options.UnwrapValueKey)
+	}
+
+	// A wildcard in the first file accepts any value, of any type
+	if isWildcard(obj1, options) {
+		return differences
+	}
+
 	// If types are different, that's a difference
-	type1 := reflect.TypeOf(obj1)
-	type2 := reflect.TypeOf(obj2)
-	if type1 != type2 {
-		differences = append(differences, Diff{
-			Path:   path,
-			Type:   TypeMismatch,
-			Value1: type1,
-			Value2: type2,
-		})
+	if reflect.TypeOf(obj1) != reflect.TypeOf(obj2) {
+		differences = append(differences, typeMismatch(obj1, obj2, path, parent))
+		if options.DeepTypeMismatch {
+			differences = append(differences, replacedChildren(obj1, obj2, path)...)
+		}
 		return differences
 	}
 
-	// Handle different types
+	// Stop descending once the nesting limit is reached
+	maxDepth := options.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = defaultMaxDepth
+	}
 	switch obj1.(type) {
-	case map[string]interface{}:
-		// Compare maps
-		map1 := obj1.(map[string]interface{})
-		map2 := obj2.(map[string]interface{})
-
-		// Get all keys from both maps
-		allKeys := make(map[string]bool)
-
-		// If case-insensitive, create case-insensitive maps for lookup
-		var lookupMap1, lookupMap2 map[string]interface{}
-		var keyMap1, keyMap2 map[string]string
-
-		if options.IgnoreCase {
-			lookupMap1 = make(map[string]interface{})
-			lookupMap2 = make(map[string]interface{})
-			keyMap1 = make(map[string]string)
-			keyMap2 = make(map[string]string)
-
-			// Create case-insensitive lookup maps
-			for key, val := range map1 {
-				lKey := strings.ToLower(key)
-				lookupMap1[lKey] = val
-				keyMap1[lKey] = key
-				allKeys[lKey] = true
-			}
+	case map[string]interface{}, []interface{}:
+		// Stop comparing once the context is done; the differences found so far are partial
+		if options.Context != nil && options.Context.Err() != nil {
+			return differences
+		}
 
-			for key, val := range map2 {
-				lKey := strings.ToLower(key)
-				lookupMap2[lKey] = val
-				keyMap2[lKey] = key
-				allKeys[lKey] = true
-			}
-		} else {
-			// Standard case-sensitive comparison
-			for k := range map1 {
-				allKeys[k] = true
-			}
-			for k := range map2 {
-				allKeys[k] = true
+		if options.depth >= maxDepth {
+			differences = append(differences, Diff{
+				Path:       path,
+				Type:       DepthExceeded,
+				Value1:     maxDepth,
+				Value2:     maxDepth,
+				ParentType: parent,
+			})
+			return differences
+		}
+		options.depth++
+
+		// Reuse the differences already found for an identical pair of objects or arrays
+		if options.cache != nil {
+			key := comparisonKey{options.cache.hash(obj1), options.cache.hash(obj2), options.depth, parent}
+			if cached, ok := options.cache.lookup(key, obj1, obj2, path); ok {
+				return cached
 			}
+			differences = compareSameType(obj1, obj2, path, parent, options)
+			options.cache.store(key, obj1, obj2, path, differences)
+			return differences
 		}
+	}
+
+	return compareSameType(obj1, obj2, path, parent, options)
+}
 
-		// Sort keys for consistent output
-		keys := make([]string, 0, len(allKeys))
-		for k := range allKeys {
-			keys = append(keys, k)
+// compareSameType compares two values of the same type, recursing into
+// objects and arrays
+func compareSameType(obj1, obj2 interface{}, path string, parent ParentType, options CompareOptions) []Diff {
+	switch val1 := obj1.(type) {
+	case map[string]interface{}:
+		return compareMaps(val1, obj2.(map[string]interface{}), path, options)
+	case []interface{}:
+		return compareArrays(val1, obj2.([]interface{}), path, parent, options)
+	default:
+		return compareLeaves(obj1, obj2, path, parent, options)
+	}
+}
+
+// compareMaps compares two objects key by key, recursing into nested values
+func compareMaps(map1, map2 map[string]interface{}, path string, options CompareOptions) []Diff {
+	differences := []Diff{}
+
+	map1, map2 = transformKeys(map1, map2, path, options)
+
+	// Get all keys from both maps
+	allKeys := make(map[string]bool)
+
+	// If keys are normalized (case-insensitive, Unicode-folded, without whitespace or restyled), create normalized maps for lookup.
+	// Reporting case differences also requires matching keys case-insensitively.
+	normalizeKeys := options.IgnoreCase || options.ReportCaseDiffs || options.FoldUnicode || options.IgnoreWhitespaceKeys || options.NormalizeNumericKeys || len(options.KeyStyles) > 0
+	var lookupMap1, lookupMap2 map[string]interface{}
+	var keyMap1, keyMap2 map[string]string
+
+	if normalizeKeys {
+		// Create normalized lookup maps
+		lookupMap1, keyMap1 = indexObjectKeys(map1, options)
+		lookupMap2, keyMap2 = indexObjectKeys(map2, options)
+		for lKey := range lookupMap1 {
+			allKeys[lKey] = true
+		}
+		for lKey := range lookupMap2 {
+			allKeys[lKey] = true
+		}
+	} else {
+		// Standard case-sensitive comparison
+		for k := range map1 {
+			allKeys[k] = true
 		}
-		sort.Strings(keys)
+		for k := range map2 {
+			allKeys[k] = true
+		}
+	}
 
-		// Check each key
-		for _, key := range keys {
-			var newPath, originalKey1, originalKey2 string
-			var val1, val2 interface{}
-			var ok1, ok2 bool
+	// Work out which sibling paths are ignored because of a condition on this object,
+	// or because the fields they are derived from are equal
+	var guarded []string
+	if len(options.IgnoreWhen) > 0 {
+		guarded = guardedPaths(map1, map2, path, options.IgnoreWhen)
+	}
+	if len(options.DerivedFields) > 0 {
+		guarded = append(guarded, derivedPaths(map1, map2, path, options.DerivedFields, options)...)
+	}
 
-			if options.IgnoreCase {
-				// For case-insensitive, key is already lowercase
-				originalKey1, ok1 = keyMap1[key]
-				originalKey2, ok2 = keyMap2[key]
+	// Find oneof groups whose set field changed between the objects
+	var oneofs map[string]Diff
+	var oneofFields map[string]bool
+	if groups, _ := lookupScoped(options.OneofGroups, path); len(groups) > 0 {
+		oneofs, oneofFields = oneofChanges(map1, map2, path, options)
+	}
 
-				if ok1 {
-					val1 = lookupMap1[key]
-					newPath = originalKey1
-				} else if ok2 {
-					newPath = originalKey2
-				} else {
-					newPath = key // Shouldn't happen, but just in case
-				}
+	// Sort keys for consistent output
+	keys := make([]string, 0, len(allKeys))
+	for k := range allKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
-				if ok2 {
-					val2 = lookupMap2[key]
-				}
-			} else {
-				// Standard case-sensitive comparison
-				newPath = key
-				val1, ok1 = map1[key]
-				val2, ok2 = map2[key]
-			}
+	// Check each key
+	for _, key := range keys {
+		var newPath, originalKey1, originalKey2 string
+		var val1, val2 interface{}
+		var ok1, ok2 bool
 
-			if path != "" {
-				newPath = path + "." + newPath
-			}
+		if normalizeKeys {
+			// For normalized keys, key is already normalized
+			originalKey1, ok1 = keyMap1[key]
+			originalKey2, ok2 = keyMap2[key]
 
-			if !ok1 {
-				differences = append(differences, Diff{
-					Path:   newPath,
-					Type:   KeyOnlyInSecond,
-					Value1: nil,
-					Value2: val2,
-				})
-			} else if !ok2 {
-				differences = append(differences, Diff{
-					Path:   newPath,
-					Type:   KeyOnlyInFirst,
-					Value1: val1,
-					Value2: nil,
-				})
+			if ok1 {
+				val1 = lookupMap1[key]
+				newPath = originalKey1
+			} else if ok2 {
+				newPath = originalKey2
 			} else {
-				// Compare values using all the special handling options
-				if options.KeysOnly {
-					// In keys-only mode, only check structure of complex objects
-					if isComplex(val1) {
-						differences = append(differences, findDifferencesWithOptions(val1, val2, newPath, options)...)
-					}
-				} else {
-					// Check if values are equal according to the options
-					if !compareValues(val1, val2, newPath, options) {
-						if isComplex(val1) {
-							// Recursively compare nested structures
-							differences = append(differences, findDifferencesWithOptions(val1, val2, newPath, options)...)
-						} else {
-							// For primitive types, just compare values
-							differences = append(differences, Diff{
-								Path:   newPath,
-								Type:   ValueMismatch,
-								Value1: val1,
-								Value2: val2,
-							})
-						}
-					}
-				}
+				newPath = key // Shouldn't happen, but just in case
 			}
-		}
 
-	case []interface{}:
-		// Compare arrays
-		arr1 := obj1.([]interface{})
-		arr2 := obj2.([]interface{})
+			if ok2 {
+				val2 = lookupMap2[key]
+			}
+		} else {
+			// Standard case-sensitive comparison
+			newPath = key
+			val1, ok1 = map1[key]
+			val2, ok2 = map2[key]
+		}
 
-		// Check array lengths
-		if len(arr1) != len(arr2) {
-			differences = append(differences, Diff{
-				Path:   path,
-				Type:   ArrayLength,
-				Value1: len(arr1),
-				Value2: len(arr2),
-			})
+		keyName := newPath
+		if path != "" {
+			newPath = path + "." + newPath
 		}
 
-		// Compare array elements
-		minLen := len(arr1)
-		if len(arr2) < minLen {
-			minLen = len(arr2)
+		// A changed oneof is reported once, in place of its first field
+		if oneofFields[keyName] {
+			if diff, ok := oneofs[keyName]; ok {
+				differences = append(differences, diff)
+			}
+			continue
 		}
 
-		for i := 0; i < minLen; i++ {
-			newPath := fmt.Sprintf("%s[%d]", path, i)
-			val1 := arr1[i]
-			val2 := arr2[i]
+		if !ok1 {
+			diff := Diff{
+				Path:       newPath,
+				Type:       KeyOnlyInSecond,
+				Value1:     nil,
+				Value2:     val2,
+				ParentType: ParentObject,
+			}
+			// Extra keys are allowed at objects marked as open, an omitted field
+			// equals its default value, and so does one the first file's
+			// serializer omits as a default
+			if open, _ := lookupScoped(options.IgnoreExtraAt, path); open ||
+				(options.TreatMissingAsDefault && isDefaultValue(val2, newPath, options)) ||
+				isTypeDefault(val2, options.DefaultExtraTypes) {
+				notifyFiltered([]Diff{diff}, options)
+				continue
+			}
+			differences = append(differences, diff)
+		} else if !ok2 {
+			diffType := KeyOnlyInFirst
+			if required, _ := lookupScoped(options.RequiredKeys, path); required[keyName] {
+				diffType = RequiredMissing
+			}
+			diff := Diff{
+				Path:       newPath,
+				Type:       diffType,
+				Value1:     val1,
+				Value2:     nil,
+				ParentType: ParentObject,
+			}
+			if options.TreatMissingAsDefault && isDefaultValue(val1, newPath, options) {
+				notifyFiltered([]Diff{diff}, options)
+				continue
+			}
+			differences = append(differences, diff)
+		} else {
+			// Report keys that only matched by ignoring case
+			if options.ReportCaseDiffs && foldKey(originalKey1, options) != foldKey(originalKey2, options) {
+				differences = append(differences, Diff{
+					Path:       newPath,
+					Type:       KeyCaseMismatch,
+					Value1:     originalKey1,
+					Value2:     originalKey2,
+					ParentType: ParentObject,
+				})
+			}
 
 			// Compare values using all the special handling options
 			if options.KeysOnly {
 				// In keys-only mode, only check structure of complex objects
 				if isComplex(val1) {
-					differences = append(differences, findDifferencesWithOptions(val1, val2, newPath, options)...)
+					differences = append(differences, findDifferencesWithParent(val1, val2, newPath, ParentObject, options)...)
 				}
 			} else {
 				// Check if values are equal according to the options
-				if !compareValues(val1, val2, newPath, options) {
+				if !valuesEqual(val1, val2, newPath, options) {
 					if isComplex(val1) {
 						// Recursively compare nested structures
-						differences = append(differences, findDifferencesWithOptions(val1, val2, newPath, options)...)
+						differences = append(differences, findDifferencesWithParent(val1, val2, newPath, ParentObject, options)...)
 					} else {
 						// For primitive types, just compare values
-						differences = append(differences, Diff{
-							Path:   newPath,
-							Type:   ValueMismatch,
-							Value1: val1,
-							Value2: val2,
-						})
+						differences = append(differences, primitiveDiff(val1, val2, newPath, ParentObject, options)...)
 					}
 				}
 			}
 		}
+	}
+
+	if len(guarded) > 0 {
+		differences = filterGuarded(differences, guarded, options)
+	}
+
+	return differences
+}
+
+// transformKeys drops, strips and renames the keys of two objects at path as
+// options ask, so that the keys compared with each other line up
+func transformKeys(map1, map2 map[string]interface{}, path string, options CompareOptions) (map[string]interface{}, map[string]interface{}) {
+	// Drop ignored key names from both objects wherever they appear
+	if len(options.IgnoreKeyNames) > 0 {
+		map1 = dropKeys(map1, options.IgnoreKeyNames)
+		map2 = dropKeys(map2, options.IgnoreKeyNames)
+	}
+
+	// Strip namespace prefixes from flattened keys so they line up
+	if options.StripKeyPrefixLeft != "" {
+		map1 = stripKeyPrefix(map1, options.StripKeyPrefixLeft)
+	}
+	if options.StripKeyPrefixRight != "" {
+		map2 = stripKeyPrefix(map2, options.StripKeyPrefixRight)
+	}
+
+	// Apply key renames to the first object so renamed keys line up
+	if len(options.RenameKeys) > 0 {
+		map1 = renameKeys(map1, path, options.RenameKeys)
+	}
+
+	// Normalize synonym keys in both objects to their canonical name
+	if len(options.KeyAliases) > 0 {
+		map1 = renameKeys(map1, path, options.KeyAliases)
+		map2 = renameKeys(map2, path, options.KeyAliases)
+	}
+	return map1, map2
+}
+
+// compareArrays compares two arrays element by element by index, recursing
+// into nested values. parent is the kind of container the arrays are in.
+func compareArrays(arr1, arr2 []interface{}, path string, parent ParentType, options CompareOptions) []Diff {
+	// Arrays of objects may be matched by an inferred identity key instead of by position
+	if options.AutoArrayKey {
+		if key, matched, ok := inferArrayKey(arr1, arr2); ok {
+			if options.ArrayKeys != nil {
+				*options.ArrayKeys = append(*options.ArrayKeys, ArrayKeyChoice{Path: path, Key: key, Matched: matched})
+			}
+			return compareKeyedArrays(arr1, arr2, key, path, options)
+		}
+	}
+
+	// Arrays at some paths are compared regardless of element order
+	if ignoresOrder(path, options) {
+		return compareUnorderedArrays(arr1, arr2, path, options)
+	}
+
+	differences := []Diff{}
+
+	// Check array lengths, allowing the configured slack
+	lengthDiff := len(arr1) - len(arr2)
+	if lengthDiff < 0 {
+		lengthDiff = -lengthDiff
+	}
+	if lengthDiff > options.ArrayLengthTolerance {
+		differences = append(differences, Diff{
+			Path:       path,
+			Type:       ArrayLength,
+			Value1:     len(arr1),
+			Value2:     len(arr2),
+			ParentType: parent,
+		})
+	}
+
+	// Compare array elements
+	minLen := len(arr1)
+	if len(arr2) < minLen {
+		minLen = len(arr2)
+	}
+
+	// Long arrays may only have a sample of their elements compared
+	indices := sampleIndices(minLen, options.SampleArrays, options.SampleSeed, path)
+	if len(indices) < minLen && options.SampledArrays != nil {
+		*options.SampledArrays = append(*options.SampledArrays, ArraySample{
+			Path:     path,
+			Compared: len(indices),
+			Total:    minLen,
+		})
+	}
+
+	differingElements := 0
+	for n, i := range indices {
+		// Once the cap is reached, summarize the remaining elements instead of descending
+		if options.MaxArrayDiffs > 0 && differingElements >= options.MaxArrayDiffs {
+			remaining := countDifferingElements(arr1, arr2, path, indices[n:], options)
+			if remaining > 0 {
+				differences = append(differences, Diff{
+					Path:       path,
+					Type:       ArrayDiffsTruncated,
+					Value1:     remaining,
+					Value2:     nil,
+					ParentType: parent,
+				})
+			}
+			break
+		}
+
+		elementDiffs := compareElements(arr1[i], arr2[i], fmt.Sprintf("%s[%d]", path, i), options)
+		if len(elementDiffs) > 0 {
+			differences = append(differences, elementDiffs...)
+			differingElements++
+		}
+	}
 
+	return differences
+}
+
+// compareElements compares two array elements paired at path
+func compareElements(val1, val2 interface{}, path string, options CompareOptions) []Diff {
+	// Compare values using all the special handling options
+	if options.ArrayTypeCheck {
+		// Only the JSON types of the elements have to match
+		if jsonTypeName(val1) != jsonTypeName(val2) {
+			return []Diff{typeMismatch(val1, val2, path, ParentArray)}
+		}
+	} else if options.KeysOnly {
+		// In keys-only mode, only check structure of complex objects
+		if isComplex(val1) {
+			return findDifferencesWithParent(val1, val2, path, ParentArray, options)
+		}
+	} else if !valuesEqual(val1, val2, path, options) {
+		if isComplex(val1) {
+			// Recursively compare nested structures
+			return findDifferencesWithParent(val1, val2, path, ParentArray, options)
+		}
+		// For primitive types, just compare values
+		return primitiveDiff(val1, val2, path, ParentArray, options)
+	}
+	return nil
+}
+
+// primitiveDiff reports two unequal values, at least one of them primitive.
+// A null or a scalar compared with an object or array is a type mismatch;
+// scalars are a value mismatch, even if their types differ.
+func primitiveDiff(val1, val2 interface{}, path string, parent ParentType, options CompareOptions) []Diff {
+	if (val1 == nil) != (val2 == nil) || isComplex(val1) || isComplex(val2) {
+		return findDifferencesWithParent(val1, val2, path, parent, options)
+	}
+	return []Diff{{
+		Path:       path,
+		Type:       ValueMismatch,
+		Value1:     val1,
+		Value2:     val2,
+		ParentType: parent,
+		Detail:     mismatchDetail(val1, val2, path, options),
+	}}
+}
+
+// replacedChildren lists the keys or elements of an object or array replaced
+// by a value of another type, as only in the first file, or of one replacing
+// a value of another type, as only in the second file. Only the children of
+// an object or array compared with a scalar or null are listed.
+func replacedChildren(val1, val2 interface{}, path string) []Diff {
+	if isComplex(val1) && isComplex(val2) {
+		return nil
+	}
+
+	var differences []Diff
+	for _, side := range []struct {
+		val      interface{}
+		diffType DiffType
+	}{{val1, KeyOnlyInFirst}, {val2, KeyOnlyInSecond}} {
+		switch v := side.val.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				differences = append(differences, childDiff(side.diffType, joinPath(path, key), v[key], ParentObject))
+			}
+		case []interface{}:
+			for i, elem := range v {
+				differences = append(differences, childDiff(side.diffType, fmt.Sprintf("%s[%d]", path, i), elem, ParentArray))
+			}
+		}
+	}
+	return differences
+}
+
+// childDiff reports a value present on only one side
+func childDiff(diffType DiffType, path string, val interface{}, parent ParentType) Diff {
+	diff := Diff{Path: path, Type: diffType, ParentType: parent}
+	if diffType == KeyOnlyInFirst {
+		diff.Value1 = val
+	} else {
+		diff.Value2 = val
+	}
+	return diff
+}
+
+// typeMismatch reports two values of different types, described by their
+// JSON type names
+func typeMismatch(val1, val2 interface{}, path string, parent ParentType) Diff {
+	return Diff{
+		Path:       path,
+		Type:       TypeMismatch,
+		Value1:     jsonTypeName(val1),
+		Value2:     jsonTypeName(val2),
+		ParentType: parent,
+	}
+}
+
+// jsonTypeName returns the JSON type of a decoded value: "object", "array",
+// "string", "number", "boolean" or "null". Values that JSON decoding never
+// produces are named by their Go type.
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case bool:
+		return "boolean"
 	default:
-		// For primitive types, just compare values if not in keys-only mode
-		if !options.KeysOnly && !compareValues(obj1, obj2, path, options) {
-			differences = append(differences, Diff{
-				Path:   path,
-				Type:   ValueMismatch,
-				Value1: obj1,
-				Value2: obj2,
-			})
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// compareLeaves compares two primitive values of the same type
+func compareLeaves(val1, val2 interface{}, path string, parent ParentType, options CompareOptions) []Diff {
+	differences := []Diff{}
+
+	// For primitive types, just compare values if not in keys-only mode
+	if !options.KeysOnly && !valuesEqual(val1, val2, path, options) {
+		differences = append(differences, Diff{
+			Path:       path,
+			Type:       ValueMismatch,
+			Value1:     val1,
+			Value2:     val2,
+			ParentType: parent,
+			Detail:     mismatchDetail(val1, val2, path, options),
+		})
+	}
+
+	return differences
+}
+
+// coerceNumericObjects converts an object keyed by sequential indices into an
+// array when the value it is compared with is an array
+func coerceNumericObjects(obj1, obj2 interface{}) (interface{}, interface{}) {
+	_, isArr1 := obj1.([]interface{})
+	_, isArr2 := obj2.([]interface{})
+
+	if map1, ok := obj1.(map[string]interface{}); ok && isArr2 {
+		if arr, ok := numericObjectToArray(map1); ok {
+			return arr, obj2
+		}
+	}
+	if map2, ok := obj2.(map[string]interface{}); ok && isArr1 {
+		if arr, ok := numericObjectToArray(map2); ok {
+			return obj1, arr
+		}
+	}
+	return obj1, obj2
+}
+
+// unwrapSingletonArrays replaces a one-element array holding an object with
+// that object when the value it is compared with is an object
+func unwrapSingletonArrays(obj1, obj2 interface{}) (interface{}, interface{}) {
+	_, isMap1 := obj1.(map[string]interface{})
+	_, isMap2 := obj2.(map[string]interface{})
+
+	if arr, ok := obj1.([]interface{}); ok && isMap2 && len(arr) == 1 {
+		if elem, ok := arr[0].(map[string]interface{}); ok {
+			return elem, obj2
+		}
+	}
+	if arr, ok := obj2.([]interface{}); ok && isMap1 && len(arr) == 1 {
+		if elem, ok := arr[0].(map[string]interface{}); ok {
+			return obj1, elem
+		}
+	}
+	return obj1, obj2
+}
+
+// unwrapValue replaces an object containing key with the value at key,
+// repeatedly, so {"value": {"value": 1}} becomes 1 for the key "value"
+func unwrapValue(val interface{}, key string) interface{} {
+	for {
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			return val
+		}
+		inner, ok := obj[key]
+		if !ok {
+			return val
+		}
+		val = inner
+	}
+}
+
+// unwrapChildValues returns a copy of an object or array with unwrapValue
+// applied to each of its children, so a wrapper compares like the value it
+// wraps wherever it appears. Other values are returned unchanged.
+func unwrapChildValues(val interface{}, key string) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		unwrapped := make(map[string]interface{}, len(v))
+		for k, child := range v {
+			unwrapped[k] = unwrapValue(child, key)
+		}
+		return unwrapped
+	case []interface{}:
+		unwrapped := make([]interface{}, len(v))
+		for i, child := range v {
+			unwrapped[i] = unwrapValue(child, key)
+		}
+		return unwrapped
+	}
+	return val
+}
+
+// countDifferingElements counts the index-aligned elements at indices that differ,
+// without collecting their individual differences. It is used to summarize the
+// tail of an array once the per-array diff cap has been reached.
+func countDifferingElements(arr1, arr2 []interface{}, path string, indices []int, options CompareOptions) int {
+	// The differences found here are only counted, not reported or recorded,
+	// but a hook still sees them, as it sees every raw difference
+	options.FuzzyMatches = nil
+	options.Promotions = nil
+	options.SampledArrays = nil
+	options.ArrayKeys = nil
+	options.ArrayMatches = nil
+
+	count := 0
+	for _, i := range indices {
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		if options.OnDiff != nil {
+			if diffs := compareElements(arr1[i], arr2[i], elemPath, options); len(diffs) > 0 {
+				notifyFiltered(diffs, options)
+				count++
+			}
+		} else if options.ArrayTypeCheck {
+			if jsonTypeName(arr1[i]) != jsonTypeName(arr2[i]) {
+				count++
+			}
+		} else if options.KeysOnly {
+			if isComplex(arr1[i]) && len(findDifferencesWithOptions(arr1[i], arr2[i], elemPath, options)) > 0 {
+				count++
+			}
+		} else if !valuesEqual(arr1[i], arr2[i], elemPath, options) {
+			count++
+		}
+	}
+	return count
+}
+
+// prefixPath prepends prefix to a reported path, using the same joining rules as
+// the traversal: the root path becomes the prefix itself, array indices attach
+// directly and keys are joined with a dot.
+func prefixPath(prefix, path string) string {
+	switch {
+	case prefix == "":
+		return path
+	case path == "":
+		return prefix
+	case strings.HasPrefix(path, "["):
+		return prefix + path
+	default:
+		return prefix + "." + path
+	}
+}
+
+// applyPathPrefix prepends prefix to the path of every difference
+func applyPathPrefix(differences []Diff, prefix string) []Diff {
+	for i := range differences {
+		differences[i].Path = prefixPath(prefix, differences[i].Path)
+	}
+	return differences
+}
+
+// compareDocuments compares two lists of documents pairwise by index.
+// Paths are prefixed with doc[n], and a DocumentCount difference is reported
+// when the files contain a different number of documents.
+func compareDocuments(docs1, docs2 []interface{}, options CompareOptions) []Diff {
+	differences := []Diff{}
+
+	if len(docs1) != len(docs2) {
+		differences = append(differences, Diff{
+			Path:       "",
+			Type:       DocumentCount,
+			Value1:     len(docs1),
+			Value2:     len(docs2),
+			ParentType: ParentRoot,
+		})
+		notifyDiffs(assignTags(assignSeverities(differences, options.SeverityOverrides), options.PathTags), options)
+	}
+
+	for i := 0; i < len(docs1) && i < len(docs2); i++ {
+		prefix := fmt.Sprintf("doc[%d]", i)
+		docOptions := options
+		if options.OnDiff != nil {
+			docOptions.OnDiff = func(diff Diff) {
+				diff.Path = prefixPath(prefix, diff.Path)
+				options.OnDiff(diff)
+			}
+		}
+		var matched, promoted, sampled, keyed int
+		if options.FuzzyMatches != nil {
+			matched = len(*options.FuzzyMatches)
+		}
+		if options.Promotions != nil {
+			promoted = len(*options.Promotions)
+		}
+		if options.SampledArrays != nil {
+			sampled = len(*options.SampledArrays)
+		}
+		if options.ArrayKeys != nil {
+			keyed = len(*options.ArrayKeys)
+		}
+
+		docDiffs := findDifferencesWithOptions(docs1[i], docs2[i], "", docOptions)
+		differences = append(differences, applyPathPrefix(docDiffs, prefix)...)
+
+		if options.FuzzyMatches != nil {
+			prefixFuzzyMatches((*options.FuzzyMatches)[matched:], prefix)
+		}
+		if options.Promotions != nil {
+			prefixPromotions((*options.Promotions)[promoted:], prefix)
+		}
+		if options.SampledArrays != nil {
+			prefixArraySamples((*options.SampledArrays)[sampled:], prefix)
+		}
+		if options.ArrayKeys != nil {
+			prefixArrayKeyChoices((*options.ArrayKeys)[keyed:], prefix)
 		}
 	}
 
 	return differences
-}
\ No newline at end of file
+}
+
+// mismatchDetail explains a value mismatch when the values were compared by a
+// special comparator, e.g. "semver 1.2.0 < 1.3.0", or have different types
+// that could be converted to each other, e.g. "string vs number". It returns
+// "" otherwise.
+func mismatchDetail(val1, val2 interface{}, path string, options CompareOptions) string {
+	if semver, _ := lookupScoped(options.SemverKeys, path); semver {
+		if detail, ok := describeSemver(val1, val2); ok {
+			return detail
+		}
+	}
+	if unit, ok := lookupScoped(options.UnitKeys, path); ok {
+		if detail, ok := describeUnitValues(val1, val2, unit); ok {
+			return detail
+		}
+	}
+	return coercionDetail(val1, val2)
+}
+
+// coercionDetail names the types of two scalars of different types when one
+// could be read as the other: a numeric string and a number, or a boolean and
+// a "true"/"false" string or the number 1 or 0. It returns "" otherwise.
+func coercionDetail(val1, val2 interface{}) string {
+	scalar := map[string]bool{"string": true, "number": true, "boolean": true}
+	kind1, kind2 := jsonTypeName(val1), jsonTypeName(val2)
+	if !scalar[kind1] || !scalar[kind2] || kind1 == kind2 {
+		return ""
+	}
+
+	var ok1, ok2 bool
+	if kind1 == "boolean" || kind2 == "boolean" {
+		_, ok1 = booleanValue(val1, true)
+		_, ok2 = booleanValue(val2, true)
+	} else {
+		_, ok1 = convertToFloat64(val1)
+		_, ok2 = convertToFloat64(val2)
+	}
+	if !ok1 || !ok2 {
+		return ""
+	}
+	return kind1 + " vs " + kind2
+}
@@ -4,7 +4,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -64,6 +66,29 @@ func TestCaseSensitivity(t *testing.T) {
 		}
 	}
 
+	// Test reporting case differences while still comparing values
+	caseDiffs := findDifferencesWithOptions(file1.Data, file6.Data, "", CompareOptions{ReportCaseDiffs: true, KeysOnly: true})
+	expectedCaseDiffs := []string{
+		"address: key case mismatch - address vs ADDRESS",
+		"age: key case mismatch - age vs Age",
+		"name: key case mismatch - name vs Name",
+	}
+	if len(caseDiffs) != len(expectedCaseDiffs) {
+		t.Errorf("Expected %d case differences, got %d: %v", len(expectedCaseDiffs), len(caseDiffs), caseDiffs)
+	}
+	for _, expected := range expectedCaseDiffs {
+		found := false
+		for _, diff := range caseDiffs {
+			if formatDiff(diff) == expected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected to find diff '%s', but didn't", expected)
+		}
+	}
+
 	// Test case-insensitive key-only comparison
 	keyDiffs := FindDifferences(file1.Data, file6.Data, "", true, false, false, false, false, true, nil, nil, 0)
 	if len(keyDiffs) > 0 {
@@ -377,7 +402,473 @@ func formatDiff(diff Diff) string {
 		return fmt.Sprintf("%s: array length mismatch - %v vs %v", diff.Path, diff.Value1, diff.Value2)
 	case TypeMismatch:
 		return fmt.Sprintf("%s: type mismatch - %v vs %v", diff.Path, diff.Value1, diff.Value2)
+	case ArrayDiffsTruncated:
+		return fmt.Sprintf("%s: %v more differing elements", diff.Path, diff.Value1)
+	case DocumentCount:
+		return fmt.Sprintf("document count mismatch - %v vs %v", diff.Value1, diff.Value2)
+	case KeyCaseMismatch:
+		return fmt.Sprintf("%s: key case mismatch - %v vs %v", diff.Path, diff.Value1, diff.Value2)
+	case DepthExceeded:
+		return fmt.Sprintf("%s: nesting deeper than %v levels", diff.Path, diff.Value1)
+	case Moved:
+		return fmt.Sprintf("%s: moved from %v", diff.Path, diff.Value1)
+	case RequiredMissing:
+		return fmt.Sprintf("%s: required key missing from second file", diff.Path)
+	case OneofChanged:
+		return fmt.Sprintf("%s: oneof branch changed - %v vs %v", diff.Path, diff.Value1, diff.Value2)
 	default:
 		return fmt.Sprintf("%s: unknown difference type", diff.Path)
 	}
-}
\ No newline at end of file
+}
+
+func TestMaxArrayDiffs(t *testing.T) {
+	arr1 := make([]interface{}, 0, 10)
+	arr2 := make([]interface{}, 0, 10)
+	for i := 0; i < 10; i++ {
+		arr1 = append(arr1, float64(i))
+		arr2 = append(arr2, float64(i+100))
+	}
+	obj1 := map[string]interface{}{"values": arr1}
+	obj2 := map[string]interface{}{"values": arr2}
+
+	// Without a cap every element is reported
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{})
+	if len(diffs) != 10 {
+		t.Errorf("Expected 10 differences without a cap, got %d", len(diffs))
+	}
+
+	// With a cap the tail is summarized in a single diff
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{MaxArrayDiffs: 3})
+	if len(diffs) != 4 {
+		t.Fatalf("Expected 3 differences plus a summary, got %d: %v", len(diffs), diffs)
+	}
+	summary := diffs[3]
+	if summary.Type != ArrayDiffsTruncated || summary.Path != "values" || summary.Value1 != 7 {
+		t.Errorf("Expected summary of 7 more differing elements in values, got %s", formatDiff(summary))
+	}
+
+	// No summary is emitted when the cap is not exceeded
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{MaxArrayDiffs: 10})
+	for _, diff := range diffs {
+		if diff.Type == ArrayDiffsTruncated {
+			t.Errorf("Unexpected summary diff when the cap was not exceeded: %s", formatDiff(diff))
+		}
+	}
+
+	// The summary counts elements, not the several differences nested in each
+	nested1 := make([]interface{}, 0, 4)
+	nested2 := make([]interface{}, 0, 4)
+	for i := 0; i < 4; i++ {
+		nested1 = append(nested1, map[string]interface{}{"a": float64(i), "b": float64(i)})
+		nested2 = append(nested2, map[string]interface{}{"a": float64(i + 1), "b": float64(i + 1)})
+	}
+	diffs = findDifferencesWithOptions(nested1, nested2, "items", CompareOptions{MaxArrayDiffs: 1})
+	summary = diffs[len(diffs)-1]
+	if summary.Type != ArrayDiffsTruncated || summary.Value1 != 3 {
+		t.Fatalf("Expected summary of 3 more differing elements, got %s", formatDiff(summary))
+	}
+	if text := formatDiffText(summary); text != "... and 3 more differing elements in items\n" {
+		t.Errorf("Unexpected summary text %q", text)
+	}
+
+	// The cap counts elements too, however many differences each one holds
+	diffs = findDifferencesWithOptions(nested1, nested2, "items", CompareOptions{MaxArrayDiffs: 3})
+	if len(diffs) != 7 || diffs[6].Type != ArrayDiffsTruncated || diffs[6].Value1 != 1 {
+		t.Errorf("Expected the differences of 3 elements plus a summary of 1, got %v", diffs)
+	}
+
+	// Elements that are only counted don't record fuzzy matches or promotions
+	var matches []FuzzyMatch
+	var promotions []Promotion
+	options := CompareOptions{MaxArrayDiffs: 1, FloatTolerance: 0.5, IgnoreNumericType: true, FuzzyMatches: &matches, Promotions: &promotions}
+	arr1 = []interface{}{1.0, 2.0, 3.0, 4.0}
+	arr2 = []interface{}{9.0, 8.0, 3.1, "4"}
+	diffs = findDifferencesWithOptions(arr1, arr2, "values", options)
+	if len(diffs) != 2 || diffs[1].Value1 != 1 {
+		t.Errorf("Expected 1 difference plus a summary of 1, got %v", diffs)
+	}
+	if len(matches) != 0 || len(promotions) != 0 {
+		t.Errorf("Expected nothing recorded for the summarized elements, got %v and %v", matches, promotions)
+	}
+}
+
+func TestIgnoreExtraAt(t *testing.T) {
+	file1, err := ReadAndValidateJSON("examples/example1.json", true)
+	if err != nil {
+		t.Fatalf("Failed to read examples/example1.json: %v", err)
+	}
+
+	file5, err := ReadAndValidateJSON("examples/example5.json", true)
+	if err != nil {
+		t.Fatalf("Failed to read examples/example5.json: %v", err)
+	}
+
+	// Extra keys under address are ignored, but the extra root key and removed keys are not
+	diffs := findDifferencesWithOptions(file1.Data, file5.Data, "", CompareOptions{
+		KeysOnly:      true,
+		IgnoreExtraAt: map[string]bool{"address": true},
+	})
+	expected := []string{
+		"address.zip: key exists only in first file",
+		"email: key exists only in second file",
+		"hobbies: array length mismatch",
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d differences, got %d: %v", len(expected), len(diffs), diffs)
+	}
+	for i, diff := range diffs {
+		if !strings.HasPrefix(formatDiff(diff), expected[i]) {
+			t.Errorf("Expected %q, got %q", expected[i], formatDiff(diff))
+		}
+	}
+
+	// The root path is the empty string
+	diffs = findDifferencesWithOptions(file1.Data, file5.Data, "", CompareOptions{
+		KeysOnly:      true,
+		IgnoreExtraAt: map[string]bool{"": true},
+	})
+	for _, diff := range diffs {
+		if diff.Path == "email" {
+			t.Errorf("Expected extra root key to be ignored, got %s", formatDiff(diff))
+		}
+	}
+}
+
+func TestApplyPathPrefix(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		path     string
+		expected string
+	}{
+		{"data.items[3]", "name", "data.items[3].name"},
+		{"data.items[3]", "address.city", "data.items[3].address.city"},
+		{"data.items[3]", "[0]", "data.items[3][0]"},
+		{"data.items[3]", "", "data.items[3]"},
+		{"", "name", "name"},
+	}
+
+	for _, tt := range tests {
+		diffs := applyPathPrefix([]Diff{{Path: tt.path}}, tt.prefix)
+		if diffs[0].Path != tt.expected {
+			t.Errorf("applyPathPrefix(%q, %q) = %q, want %q", tt.prefix, tt.path, diffs[0].Path, tt.expected)
+		}
+	}
+}
+
+func TestFoldUnicode(t *testing.T) {
+	composed := "caf\u00e9"
+	decomposed := "cafe\u0301"
+
+	obj1 := map[string]interface{}{"name": composed, "upper": "CAF\u00c9", composed: 1.0}
+	obj2 := map[string]interface{}{"name": decomposed, "upper": decomposed, decomposed: 1.0}
+
+	// Without folding, the differently encoded strings and keys all differ
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{})
+	if len(diffs) != 4 {
+		t.Errorf("Expected 4 differences without folding, got %d: %v", len(diffs), diffs)
+	}
+
+	// Folding matches the composed and decomposed forms of values and keys
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{FoldUnicode: true})
+	if len(diffs) != 1 || diffs[0].Path != "upper" {
+		t.Errorf("Expected only the differently cased value to differ, got %v", diffs)
+	}
+
+	// Folding composes with case-insensitive value comparison
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{FoldUnicode: true, IgnoreCaseValues: true})
+	if len(diffs) != 0 {
+		t.Errorf("Expected no differences with folding and ignore-case-values, got %v", diffs)
+	}
+
+	// Keys differing only in normalization are not reported as case mismatches
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{FoldUnicode: true, IgnoreCaseValues: true, ReportCaseDiffs: true})
+	if len(diffs) != 0 {
+		t.Errorf("Expected no key case mismatches for normalization-only differences, got %v", diffs)
+	}
+}
+
+func TestParentType(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"name":  "John",
+		"tags":  []interface{}{"a", map[string]interface{}{"k": 1.0}, []interface{}{1.0}},
+		"extra": true,
+	}
+	obj2 := map[string]interface{}{
+		"name": "Jane",
+		"tags": []interface{}{"b", map[string]interface{}{"k": 2.0}, "x", "y"},
+	}
+
+	expected := map[string]ParentType{
+		"extra":     ParentObject,
+		"name":      ParentObject,
+		"tags":      ParentObject,
+		"tags[0]":   ParentArray,
+		"tags[1].k": ParentObject,
+		"tags[2]":   ParentArray,
+	}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{})
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d differences, got %d: %v", len(expected), len(diffs), diffs)
+	}
+	for _, diff := range diffs {
+		if diff.ParentType != expected[diff.Path] {
+			t.Errorf("Expected parent type %s at %s, got %s", expected[diff.Path], diff.Path, diff.ParentType)
+		}
+	}
+
+	// Differences at the root of the comparison have a root parent
+	diffs = findDifferencesWithOptions("a", 1.0, "", CompareOptions{})
+	if len(diffs) != 1 || diffs[0].ParentType != ParentRoot {
+		t.Errorf("Expected a root type mismatch, got %v", diffs)
+	}
+	if diffs[0].Value1 != "string" || diffs[0].Value2 != "number" {
+		t.Errorf("Expected the types to be named string and number, got %v and %v", diffs[0].Value1, diffs[0].Value2)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	// Nesting far beyond the default limit is reported instead of recursed into.
+	// Each level holds 1 in one file and "1" in the other, so every level
+	// differs without producing a difference under -ignore-numeric-type.
+	nest := func(depth int, leaf interface{}) interface{} {
+		var value interface{}
+		for i := 0; i < depth; i++ {
+			value = []interface{}{leaf, value}
+		}
+		return value
+	}
+
+	diffs := findDifferencesWithOptions(nest(50000, 1.0), nest(50000, "1"), "", CompareOptions{IgnoreNumericType: true})
+	if len(diffs) != 1 || diffs[0].Type != DepthExceeded || diffs[0].Value1 != defaultMaxDepth {
+		t.Fatalf("Expected a single depth_exceeded difference, got %d differences", len(diffs))
+	}
+	if expected := strings.Repeat("[1]", defaultMaxDepth); diffs[0].Path != expected {
+		t.Errorf("Expected the difference at depth %d, got a path of length %d", defaultMaxDepth, len(diffs[0].Path))
+	}
+	if diffs[0].Severity != SeverityError {
+		t.Errorf("Expected depth_exceeded to be an error, got %s", diffs[0].Severity)
+	}
+
+	// Values within the limit are compared as usual
+	obj1 := map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": 1.0}}, "d": 1.0}
+	obj2 := map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": 2.0}}, "d": 2.0}
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{MaxDepth: 2})
+	if len(diffs) != 2 || formatDiff(diffs[0]) != "a.b: nesting deeper than 2 levels" || diffs[1].Path != "d" {
+		t.Errorf("Unexpected differences with MaxDepth 2: %v", diffs)
+	}
+	if diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{MaxDepth: 3}); len(diffs) != 2 || diffs[0].Path != "a.b.c" {
+		t.Errorf("Unexpected differences with MaxDepth 3: %v", diffs)
+	}
+}
+
+func TestArrayLengthTolerance(t *testing.T) {
+	obj1 := map[string]interface{}{"items": []interface{}{1.0, 2.0, 3.0}}
+	obj2 := map[string]interface{}{"items": []interface{}{1.0, 5.0, 3.0, 4.0, 5.0}}
+
+	tests := []struct {
+		tolerance int
+		expected  []string
+	}{
+		{0, []string{"items: array length mismatch - 3 vs 5", "items[1]: value mismatch - 2 vs 5"}},
+		{1, []string{"items: array length mismatch - 3 vs 5", "items[1]: value mismatch - 2 vs 5"}},
+		{2, []string{"items[1]: value mismatch - 2 vs 5"}},
+		{10, []string{"items[1]: value mismatch - 2 vs 5"}},
+	}
+
+	for _, test := range tests {
+		diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{ArrayLengthTolerance: test.tolerance})
+		var got []string
+		for _, diff := range diffs {
+			got = append(got, formatDiff(diff))
+		}
+		if strings.Join(got, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("With tolerance %d expected %v, got %v", test.tolerance, test.expected, got)
+		}
+	}
+}
+
+func TestOnDiff(t *testing.T) {
+	counts := make(map[DiffType]int)
+	var paths []string
+	options := CompareOptions{
+		MaxArrayDiffs: 1,
+		OnDiff: func(diff Diff) {
+			counts[diff.Type]++
+			paths = append(paths, diff.Path)
+			if diff.Severity != defaultSeverity(diff.Type) {
+				t.Errorf("Expected severities to be assigned before the hook, got %s for %s", diff.Severity, diff.Path)
+			}
+		},
+	}
+
+	obj1 := map[string]interface{}{"name": "John", "tags": []interface{}{"a", "b", "c"}, "old": true}
+	obj2 := map[string]interface{}{"name": "Jane", "tags": []interface{}{"x", "y", "z"}}
+	diffs := findDifferencesWithOptions(obj1, obj2, "", options)
+
+	// The hook sees the raw differences, including those of elements only counted for the summary
+	if len(paths) != 5 || counts[ValueMismatch] != 4 || counts[ArrayDiffsTruncated] != 0 || counts[KeyOnlyInFirst] != 1 {
+		t.Errorf("Unexpected hook calls %v for differences %v", counts, diffs)
+	}
+	if len(diffs) != 4 || diffs[3].Type != ArrayDiffsTruncated {
+		t.Errorf("Expected the summary to still be reported, got %v", diffs)
+	}
+
+	// Paths in multi-document mode carry the document prefix
+	paths = nil
+	counts = make(map[DiffType]int)
+	compareDocuments([]interface{}{obj1}, []interface{}{obj2, obj2}, options)
+	if counts[DocumentCount] != 1 || paths[0] != "" || !strings.Contains(strings.Join(paths, " "), "doc[0].name") {
+		t.Errorf("Unexpected hook paths in multi-document mode: %v", paths)
+	}
+
+	// The hook also sees differences that -ignore-path and -only-path filter out
+	paths = nil
+	options.IgnorePaths = []string{"name"}
+	options.OnlyPaths = []string{"!old"}
+	diffs = findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(paths) != 5 || len(diffs) != 2 || diffs[0].Path != "tags[0]" || diffs[1].Path != "tags" {
+		t.Errorf("Expected 5 hook calls and only the tags differences reported, got %v and %v", paths, diffs)
+	}
+
+	// And those the traversal itself drops
+	paths = nil
+	options = CompareOptions{
+		IgnoreWhen:            []ConditionalIgnore{{Field: "status", Value: "cancelled", Path: "discount"}},
+		DerivedFields:         []DerivedField{{Field: "fullName", Sources: []string{"first"}}},
+		IgnoreExtraAt:         map[string]bool{"meta": true},
+		TreatMissingAsDefault: true,
+		OnDiff:                func(diff Diff) { paths = append(paths, diff.Path) },
+	}
+	obj1 = map[string]interface{}{"status": "cancelled", "discount": 5.0, "first": "John", "fullName": "John", "meta": map[string]interface{}{}, "count": 0.0}
+	obj2 = map[string]interface{}{"status": "cancelled", "discount": 10.0, "first": "John", "fullName": "John Smith", "meta": map[string]interface{}{"extra": true}}
+	diffs = findDifferencesWithOptions(obj1, obj2, "", options)
+	sort.Strings(paths)
+	if len(diffs) != 0 || strings.Join(paths, " ") != "count discount fullName meta.extra" {
+		t.Errorf("Expected 4 hook calls and no differences reported, got %v and %v", paths, diffs)
+	}
+}
+
+func TestNullComparison(t *testing.T) {
+	testCases := []struct {
+		name     string
+		val1     interface{}
+		val2     interface{}
+		expected string // Type mismatch values, or "" if equal
+	}{
+		{"Null vs null", nil, nil, ""},
+		{"Null vs string", nil, "x", "null vs string"},
+		{"Number vs null", 1.0, nil, "number vs null"},
+		{"Null vs object", nil, map[string]interface{}{"a": 1.0}, "null vs object"},
+		{"Object vs null", map[string]interface{}{}, nil, "object vs null"},
+		{"Null vs array", nil, []interface{}{}, "null vs array"},
+		{"Array vs null", []interface{}{1.0}, nil, "array vs null"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Nulls compare the same inside objects, inside arrays and at the root
+			for _, c := range []struct {
+				obj1, obj2 interface{}
+				path       string
+			}{
+				{map[string]interface{}{"value": tc.val1}, map[string]interface{}{"value": tc.val2}, "value"},
+				{[]interface{}{tc.val1}, []interface{}{tc.val2}, "[0]"},
+				{tc.val1, tc.val2, ""},
+			} {
+				var got []string
+				for _, diff := range findDifferencesWithOptions(c.obj1, c.obj2, "", CompareOptions{}) {
+					got = append(got, formatDiff(diff))
+				}
+				var expected []string
+				if tc.expected != "" {
+					expected = []string{c.path + ": type mismatch - " + tc.expected}
+				}
+				if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+					t.Errorf("Expected %v, got %v", expected, got)
+				}
+			}
+		})
+	}
+
+	// Nulls are ignored entirely with IgnoreNullValues
+	diffs := findDifferencesWithOptions(map[string]interface{}{"a": nil}, map[string]interface{}{"a": "x"}, "", CompareOptions{IgnoreNullValues: true})
+	if len(diffs) != 0 {
+		t.Errorf("Expected no differences with IgnoreNullValues, got %v", diffs)
+	}
+}
+
+func TestDeepTypeMismatch(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"address": map[string]interface{}{"city": "Paris", "zip": "75001"},
+		"tags":    "a,b",
+		"both":    map[string]interface{}{"a": 1.0},
+	}
+	obj2 := map[string]interface{}{
+		"address": "10 Rue de Rivoli, Paris",
+		"tags":    []interface{}{"a", "b"},
+		"both":    []interface{}{1.0},
+	}
+
+	// Without the option only the type mismatches are reported
+	if diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{}); len(diffs) != 3 {
+		t.Errorf("Expected 3 type mismatches, got %v", diffs)
+	}
+
+	var got []string
+	for _, diff := range findDifferencesWithOptions(obj1, obj2, "", CompareOptions{DeepTypeMismatch: true}) {
+		got = append(got, formatDiff(diff))
+	}
+	expected := []string{
+		"address: type mismatch - object vs string",
+		"address.city: key exists only in first file",
+		"address.zip: key exists only in first file",
+		"both: type mismatch - object vs array",
+		"tags: type mismatch - string vs array",
+		"tags[0]: key exists only in second file",
+		"tags[1]: key exists only in second file",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestArrayTypeCheck(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"items": []interface{}{1.0, "a", map[string]interface{}{"x": 1.0}, nil, true},
+		"name":  "old",
+	}
+	obj2 := map[string]interface{}{
+		"items": []interface{}{2.0, 3.0, map[string]interface{}{"y": 2.0}, "b"},
+		"name":  "new",
+	}
+
+	var got []string
+	for _, diff := range findDifferencesWithOptions(obj1, obj2, "", CompareOptions{ArrayTypeCheck: true}) {
+		got = append(got, formatDiff(diff))
+	}
+	expected := []string{
+		"items: array length mismatch - 5 vs 4",
+		"items[1]: type mismatch - string vs number",
+		"items[3]: type mismatch - null vs string",
+		"name: value mismatch - old vs new",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestContextStopsComparison(t *testing.T) {
+	obj1 := map[string]interface{}{"a": 1.0, "nested": map[string]interface{}{"b": 1.0}}
+	obj2 := map[string]interface{}{"a": 2.0, "nested": map[string]interface{}{"b": 2.0}}
+
+	if diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{Context: context.Background()}); len(diffs) != 2 {
+		t.Errorf("Expected 2 differences with a live context, got %v", diffs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{Context: ctx}); len(diffs) != 0 {
+		t.Errorf("Expected no differences once the context is done, got %v", diffs)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// jsonDiffPatchDelta builds the delta from obj1 to obj2 in the format of the
+// jsondiffpatch JavaScript library: [new] for an added value, [old, 0, 0] for
+// a deleted one, [old, new] for a replaced one, and for objects and arrays
+// present in both, an object of their changed children. Array deltas are
+// marked with "_t": "a" and key deleted elements by "_" and their old index.
+// Array elements are paired by index rather than by the library's LCS
+// matching and object keys exactly, once ignored keys are dropped and keys
+// renamed as for the comparison. Scalar values equal under options and
+// changes at paths filtered out by IgnorePaths or OnlyPaths produce no delta.
+// The boolean result is false if there is no delta.
+func jsonDiffPatchDelta(obj1, obj2 interface{}, path string, options CompareOptions) (interface{}, bool) {
+	switch val1 := obj1.(type) {
+	case map[string]interface{}:
+		if val2, ok := obj2.(map[string]interface{}); ok {
+			delta := objectDelta(val1, val2, path, options)
+			return delta, len(delta) > 0
+		}
+	case []interface{}:
+		if val2, ok := obj2.([]interface{}); ok {
+			delta := arrayDelta(val1, val2, path, options)
+			return delta, len(delta) > 1
+		}
+	}
+
+	if valuesEqual(obj1, obj2, path, options) || !pathReported(path, options.IgnorePaths, options.OnlyPaths) {
+		return nil, false
+	}
+	return []interface{}{encodeNonFinite(obj1), encodeNonFinite(obj2)}, true
+}
+
+// jsonDiffPatch builds the delta between two documents already compared with
+// options, whose fuzzy matches and promotions have been recorded once
+func jsonDiffPatch(obj1, obj2 interface{}, options CompareOptions) (interface{}, bool) {
+	options.FuzzyMatches = nil
+	options.Promotions = nil
+	options.SampledArrays = nil
+	options.ArrayKeys = nil
+	options.ArrayMatches = nil
+	return jsonDiffPatchDelta(obj1, obj2, "", options)
+}
+
+// objectDelta builds the jsondiffpatch delta between two objects
+func objectDelta(map1, map2 map[string]interface{}, path string, options CompareOptions) map[string]interface{} {
+	map1, map2 = transformKeys(map1, map2, path, options)
+	delta := make(map[string]interface{})
+	for key, val1 := range map1 {
+		val2, ok := map2[key]
+		if !ok {
+			if pathReported(joinPath(path, key), options.IgnorePaths, options.OnlyPaths) {
+				delta[key] = []interface{}{encodeNonFinite(val1), 0, 0}
+			}
+			continue
+		}
+		if child, changed := jsonDiffPatchDelta(val1, val2, joinPath(path, key), options); changed {
+			delta[key] = child
+		}
+	}
+	for key, val2 := range map2 {
+		if _, ok := map1[key]; !ok && pathReported(joinPath(path, key), options.IgnorePaths, options.OnlyPaths) {
+			delta[key] = []interface{}{encodeNonFinite(val2)}
+		}
+	}
+	return delta
+}
+
+// arrayDelta builds the jsondiffpatch delta between two arrays, pairing
+// elements by index. An element replaced by one of another type is deleted
+// and re-added.
+func arrayDelta(arr1, arr2 []interface{}, path string, options CompareOptions) map[string]interface{} {
+	delta := map[string]interface{}{"_t": "a"}
+	for i := 0; i < len(arr1) || i < len(arr2); i++ {
+		index := strconv.Itoa(i)
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		if (i >= len(arr1) || i >= len(arr2)) && !pathReported(elemPath, options.IgnorePaths, options.OnlyPaths) {
+			continue
+		}
+		switch {
+		case i >= len(arr2):
+			delta["_"+index] = []interface{}{encodeNonFinite(arr1[i]), 0, 0}
+		case i >= len(arr1):
+			delta[index] = []interface{}{encodeNonFinite(arr2[i])}
+		default:
+			if child, changed := jsonDiffPatchDelta(arr1[i], arr2[i], elemPath, options); changed {
+				setElementDelta(delta, index, child)
+			}
+		}
+	}
+	return delta
+}
+
+// setElementDelta adds the delta of an array element present in both arrays
+// to the array's delta. An element replaced by a value of another type is
+// deleted and re-added.
+func setElementDelta(delta map[string]interface{}, index string, child interface{}) {
+	if replaced, ok := child.([]interface{}); ok {
+		delta["_"+index] = []interface{}{replaced[0], 0, 0}
+		delta[index] = []interface{}{replaced[1]}
+	} else {
+		delta[index] = child
+	}
+}
+
+// jsonDiffPatchDocuments builds the delta between two lists of documents as
+// an array delta of the documents' own deltas. Paths in options are relative
+// to each document, as when compareDocuments compares them.
+func jsonDiffPatchDocuments(docs1, docs2 []interface{}, options CompareOptions) (interface{}, bool) {
+	delta := map[string]interface{}{"_t": "a"}
+	for i := 0; i < len(docs1) || i < len(docs2); i++ {
+		index := strconv.Itoa(i)
+		switch {
+		case i >= len(docs2):
+			delta["_"+index] = []interface{}{encodeNonFinite(docs1[i]), 0, 0}
+		case i >= len(docs1):
+			delta[index] = []interface{}{encodeNonFinite(docs2[i])}
+		default:
+			if child, changed := jsonDiffPatch(docs1[i], docs2[i], options); changed {
+				setElementDelta(delta, index, child)
+			}
+		}
+	}
+	return delta, len(delta) > 1
+}
+
+// marshalJSONDiffPatch renders a delta as indented JSON, writing {} when
+// there are no changes. Object keys are sorted by encoding/json.
+func marshalJSONDiffPatch(delta interface{}, changed bool) ([]byte, error) {
+	if !changed {
+		delta = map[string]interface{}{}
+	}
+	return json.MarshalIndent(delta, "", "  ")
+}
+
+// writeJSONDiffPatch writes a delta built by jsonDiffPatchDelta to filePath,
+// or to stdout if filePath is "-"
+func writeJSONDiffPatch(delta interface{}, changed bool, filePath string) error {
+	output, err := marshalJSONDiffPatch(delta, changed)
+	if err != nil {
+		return err
+	}
+	if filePath == "-" {
+		_, err = fmt.Fprintln(os.Stdout, string(output))
+		return err
+	}
+	return os.WriteFile(filePath, output, 0644)
+}
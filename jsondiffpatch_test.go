@@ -0,0 +1,100 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONDiffPatchDelta(t *testing.T) {
+	var obj1, obj2 interface{}
+	json.Unmarshal([]byte(`{
+		"name": "John",
+		"age": 30,
+		"same": {"a": 1},
+		"removed": true,
+		"tags": ["a", "b", "c"],
+		"items": [{"id": 1, "qty": 1}, {"id": 2}],
+		"kind": {"x": 1}
+	}`), &obj1)
+	json.Unmarshal([]byte(`{
+		"name": "Jane",
+		"age": 30,
+		"same": {"a": 1},
+		"added": null,
+		"tags": ["a", "x"],
+		"items": [{"id": 1, "qty": 2}, {"id": 2}, {"id": 3}],
+		"kind": [1]
+	}`), &obj2)
+
+	delta, changed := jsonDiffPatchDelta(obj1, obj2, "", CompareOptions{})
+	if !changed {
+		t.Fatal("Expected a delta")
+	}
+	got, err := json.Marshal(delta)
+	if err != nil {
+		t.Fatalf("Failed to marshal delta: %v", err)
+	}
+
+	expected := `{"added":[null],"items":{"0":{"qty":[1,2]},"2":[{"id":3}],"_t":"a"},"kind":[{"x":1},[1]],` +
+		`"name":["John","Jane"],"removed":[true,0,0],"tags":{"1":["x"],"_1":["b",0,0],"_2":["c",0,0],"_t":"a"}}`
+	if string(got) != expected {
+		t.Errorf("Expected delta\n%s\ngot\n%s", expected, got)
+	}
+
+	// Values equal under the options produce no delta
+	if _, changed := jsonDiffPatchDelta(map[string]interface{}{"n": "John"}, map[string]interface{}{"n": "JOHN"}, "", CompareOptions{IgnoreCaseValues: true}); changed {
+		t.Error("Expected no delta for values equal ignoring case")
+	}
+	output, err := marshalJSONDiffPatch(jsonDiffPatchDelta([]interface{}{1.0}, []interface{}{1.0}, "", CompareOptions{}))
+	if err != nil || string(output) != "{}" {
+		t.Errorf("Expected {} for identical documents, got %s, %v", output, err)
+	}
+}
+
+func TestJSONDiffPatchOptions(t *testing.T) {
+	var obj1, obj2 interface{}
+	json.Unmarshal([]byte(`{"id": 1, "updatedAt": "mon", "fullname": "John", "secret": "a", "price": 1.0, "users": [{"token": "x", "name": "A"}]}`), &obj1)
+	json.Unmarshal([]byte(`{"id": "1", "updatedAt": "tue", "name": "John", "secret": "b", "price": 1.01, "users": [{"token": "y", "name": "B"}]}`), &obj2)
+
+	// Keys are dropped and renamed, and paths filtered, as for the comparison
+	var matches []FuzzyMatch
+	var promotions []Promotion
+	options := CompareOptions{
+		IgnoreKeyNames:    []string{"updatedAt"},
+		RenameKeys:        map[string]string{"fullname": "name"},
+		IgnorePaths:       []string{"secret", "users[*].token"},
+		IgnoreNumericType: true,
+		FloatTolerance:    0.1,
+		FuzzyMatches:      &matches,
+		Promotions:        &promotions,
+	}
+	delta, changed := jsonDiffPatch(obj1, obj2, options)
+	got, err := json.Marshal(delta)
+	if err != nil {
+		t.Fatalf("Failed to marshal delta: %v", err)
+	}
+	if expected := `{"users":{"0":{"name":["A","B"]},"_t":"a"}}`; !changed || string(got) != expected {
+		t.Errorf("Expected delta\n%s\ngot\n%s", expected, got)
+	}
+
+	// The values compared again for the delta are not recorded twice
+	if len(matches) != 0 || len(promotions) != 0 {
+		t.Errorf("Expected nothing recorded while building the delta, got %v and %v", matches, promotions)
+	}
+
+	// Only-path expressions keep the changes under them
+	delta, _ = jsonDiffPatch(obj1, obj2, CompareOptions{OnlyPaths: []string{"secret"}})
+	if got, _ := json.Marshal(delta); string(got) != `{"secret":["a","b"]}` {
+		t.Errorf("Expected only the secret change, got %s", got)
+	}
+
+	// Documents are diffed one by one, with paths relative to each
+	delta, _ = jsonDiffPatchDocuments([]interface{}{obj1}, []interface{}{obj2, map[string]interface{}{}}, options)
+	got, _ = json.Marshal(delta)
+	if expected := `{"0":{"users":{"0":{"name":["A","B"]},"_t":"a"}},"1":[{}],"_t":"a"}`; string(got) != expected {
+		t.Errorf("Expected multi-document delta\n%s\ngot\n%s", expected, got)
+	}
+}
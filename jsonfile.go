@@ -4,36 +4,287 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 )
 
+// Errors returned when reading files, wrapped with details; use errors.Is to
+// tell them apart
+var (
+	ErrFileRead      = errors.New("failed to read file")
+	ErrInvalidJSON   = errors.New("invalid JSON")
+	ErrEmptyDocument = errors.New("empty document")
+)
+
 // JSONFile represents a parsed JSON file
 type JSONFile struct {
-	Data interface{}
+	Data      interface{}
+	Documents []interface{}       // All documents in the file, only set in multi-document mode
+	Locations map[string]Location // Where each value starts in the file, by path, only set with ReadOptions.RecordLocations
 }
 
 // ReadAndValidateJSON reads a JSON file, validates it, and returns the parsed object
 func ReadAndValidateJSON(filePath string, concise bool) (*JSONFile, error) {
+	return readAndValidateJSONWithOptions(filePath, ReadOptions{Concise: concise})
+}
+
+// readAndValidateJSONWithOptions is the internal implementation that handles all read options
+func readAndValidateJSONWithOptions(filePath string, options ReadOptions) (*JSONFile, error) {
 	// Read file
 	data, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %v", err)
+		return nil, fmt.Errorf("%w: %w", ErrFileRead, err)
 	}
 
-	// Parse JSON
+	return parseJSONData(data, filePath, options)
+}
+
+// checkNonEmpty returns an ErrEmptyDocument error if the file's document, or
+// any of its documents in multi-document mode, is null, {} or []
+func checkNonEmpty(file *JSONFile) error {
+	documents := []interface{}{file.Data}
+	if file.Documents != nil {
+		documents = file.Documents
+	}
+
+	for i, doc := range documents {
+		var empty string
+		switch v := doc.(type) {
+		case nil:
+			empty = "null"
+		case map[string]interface{}:
+			if len(v) == 0 {
+				empty = "{}"
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				empty = "[]"
+			}
+		}
+		if empty == "" {
+			continue
+		}
+		if file.Documents != nil {
+			return fmt.Errorf("%w: document %d is %s", ErrEmptyDocument, i, empty)
+		}
+		return fmt.Errorf("%w: document is %s", ErrEmptyDocument, empty)
+	}
+	return nil
+}
+
+// parseJSONData parses the contents of a JSON (or XML) file; filePath is used
+// for messages and to resolve file-relative $ref pointers
+func parseJSONData(data []byte, filePath string, options ReadOptions) (*JSONFile, error) {
+	var err error
+
+	// Placeholders are resolved in the raw text, as a template renderer would
+	if options.ExpandEnv {
+		data, err = expandEnv(data, options.OnMissingEnv)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// XML is converted into the same structure as parsed JSON
+	if options.XML {
+		if options.MultiDoc {
+			return nil, fmt.Errorf("multi-document mode is not supported for XML")
+		}
+
+		xmlObj, err := decodeXML(data, options.XMLArrays)
+		if err != nil {
+			return nil, err
+		}
+
+		if !options.Concise {
+			fmt.Printf("Validated XML from %s\n", filePath)
+		}
+
+		return &JSONFile{
+			Data: xmlObj,
+		}, nil
+	}
+
+	// Non-finite number literals are quoted so encoding/json accepts them
+	if options.AllowNonFinite {
+		data = quoteNonFinite(data)
+	}
+
+	// Concatenated documents are decoded one at a time
+	if options.MultiDoc {
+		documents, err := decodeDocuments(data, options.NormalizeNumbers)
+		if err != nil {
+			return nil, err
+		}
+
+		if options.AllowNonFinite {
+			for i := range documents {
+				documents[i] = restoreNonFinite(documents[i])
+			}
+		}
+
+		if options.ResolveRefs || options.ResolveFileRefs {
+			for i := range documents {
+				documents[i], err = resolveRefs(documents[i], filePath, options.ResolveFileRefs)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if !options.Concise {
+			fmt.Printf("Validated %d JSON documents from %s\n", len(documents), filePath)
+		}
+
+		return &JSONFile{
+			Documents: documents,
+		}, nil
+	}
+
+	// Parse JSON, keeping exact number text if requested
 	var jsonObj interface{}
-	err = json.Unmarshal(data, &jsonObj)
+	if options.NormalizeNumbers {
+		jsonObj, err = decodeNumbers(data)
+	} else {
+		err = json.Unmarshal(data, &jsonObj)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("invalid JSON: %v", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidJSON, err)
+	}
+	if options.AllowNonFinite {
+		jsonObj = restoreNonFinite(jsonObj)
+	}
+
+	// Inline $ref pointers if requested
+	if options.ResolveRefs || options.ResolveFileRefs {
+		jsonObj, err = resolveRefs(jsonObj, filePath, options.ResolveFileRefs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Record where each value starts in the text just parsed
+	var locations map[string]Location
+	if options.RecordLocations {
+		locations, err = recordLocations(data)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if !concise {
+	if !options.Concise {
 		fmt.Printf("Validated JSON from %s\n", filePath)
 	}
 	
 	return &JSONFile{
-		Data: jsonObj,
+		Data:      jsonObj,
+		Locations: locations,
 	}, nil
-}
\ No newline at end of file
+}
+
+// decodeDocuments decodes every JSON document in data, which may contain
+// several documents back to back (optionally separated by whitespace).
+// With useNumber, numbers are kept as json.Number in canonical form.
+func decodeDocuments(data []byte, useNumber bool) ([]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if useNumber {
+		decoder.UseNumber()
+	}
+
+	var documents []interface{}
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w in document %d: %w", ErrInvalidJSON, len(documents), err)
+		}
+		if useNumber {
+			doc = canonicalizeNumbers(doc)
+		}
+		documents = append(documents, doc)
+	}
+
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("%w: no documents found", ErrInvalidJSON)
+	}
+
+	return documents, nil
+}
+
+// splitCombined splits a file holding two JSON documents into the bytes of
+// each. The documents may be separated by whitespace alone or by a line
+// holding only "---"; the boundary is found by decoding the first document,
+// so a "---" inside a string is never mistaken for the separator.
+func splitCombined(data []byte) ([]byte, []byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var first json.RawMessage
+	if err := decoder.Decode(&first); err != nil {
+		if err == io.EOF {
+			return nil, nil, fmt.Errorf("%w: no documents found", ErrInvalidJSON)
+		}
+		return nil, nil, fmt.Errorf("%w in first document: %w", ErrInvalidJSON, err)
+	}
+
+	rest := bytes.TrimLeft(data[decoder.InputOffset():], " \t\r\n")
+	if bytes.HasPrefix(rest, []byte("---")) {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line = rest[:i]
+		}
+		if len(bytes.TrimSpace(line)) != 3 {
+			return nil, nil, fmt.Errorf("%w: separator line must be exactly ---", ErrInvalidJSON)
+		}
+		rest = rest[len(line):]
+	}
+
+	decoder = json.NewDecoder(bytes.NewReader(rest))
+	var second json.RawMessage
+	if err := decoder.Decode(&second); err != nil {
+		if err == io.EOF {
+			return nil, nil, fmt.Errorf("%w: second document not found", ErrInvalidJSON)
+		}
+		return nil, nil, fmt.Errorf("%w in second document: %w", ErrInvalidJSON, err)
+	}
+	var extra json.RawMessage
+	if err := decoder.Decode(&extra); err != io.EOF {
+		return nil, nil, fmt.Errorf("%w: expected two documents, found more", ErrInvalidJSON)
+	}
+
+	return first, second, nil
+}
+
+// readSplitJSON reads both documents of a combined file, see splitCombined,
+// parsing each with its own options
+func readSplitJSON(filePath string, options1, options2 ReadOptions) (*JSONFile, *JSONFile, error) {
+	if options1.XML || options1.MultiDoc {
+		return nil, nil, fmt.Errorf("a combined file cannot be read as XML or in multi-document mode")
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrFileRead, err)
+	}
+	if options1.AllowNonFinite {
+		data = quoteNonFinite(data)
+	}
+
+	data1, data2, err := splitCombined(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	file1, err := parseJSONData(data1, filePath+" (first document)", options1)
+	if err != nil {
+		return nil, nil, err
+	}
+	file2, err := parseJSONData(data2, filePath+" (second document)", options2)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file1, file2, nil
+}
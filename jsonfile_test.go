@@ -0,0 +1,203 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMultiDocument(t *testing.T) {
+	dir := t.TempDir()
+	path1 := filepath.Join(dir, "first.json")
+	path2 := filepath.Join(dir, "second.json")
+
+	if err := os.WriteFile(path1, []byte(`{"id": 1, "name": "a"}{"id": 2, "name": "b"}`), 0644); err != nil {
+		t.Fatalf("Failed to write first file: %v", err)
+	}
+	if err := os.WriteFile(path2, []byte("{\"id\": 1, \"name\": \"a\"}\n{\"id\": 2, \"name\": \"c\"}\n[1, 2]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write second file: %v", err)
+	}
+
+	file1, err := readAndValidateJSONWithOptions(path1, ReadOptions{Concise: true, MultiDoc: true})
+	if err != nil {
+		t.Fatalf("Failed to read first file: %v", err)
+	}
+	file2, err := readAndValidateJSONWithOptions(path2, ReadOptions{Concise: true, MultiDoc: true})
+	if err != nil {
+		t.Fatalf("Failed to read second file: %v", err)
+	}
+
+	if len(file1.Documents) != 2 || len(file2.Documents) != 3 {
+		t.Fatalf("Expected 2 and 3 documents, got %d and %d", len(file1.Documents), len(file2.Documents))
+	}
+
+	diffs := compareDocuments(file1.Documents, file2.Documents, CompareOptions{})
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 differences, got %d: %v", len(diffs), diffs)
+	}
+	if diffs[0].Type != DocumentCount || diffs[0].Value1 != 2 || diffs[0].Value2 != 3 {
+		t.Errorf("Expected document count mismatch, got %s", formatDiff(diffs[0]))
+	}
+	if diffs[1].Path != "doc[1].name" || diffs[1].Type != ValueMismatch {
+		t.Errorf("Expected value mismatch at doc[1].name, got %s", formatDiff(diffs[1]))
+	}
+
+	// Without multi-document mode trailing documents are invalid JSON
+	if _, err := ReadAndValidateJSON(path1, true); err == nil {
+		t.Error("Expected an error reading concatenated documents without multi-document mode")
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"a": `), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	_, err := ReadAndValidateJSON(filepath.Join(dir, "missing.json"), true)
+	if !errors.Is(err, ErrFileRead) || !errors.Is(err, fs.ErrNotExist) || exitCode(err) != exitFileRead {
+		t.Errorf("Expected a file read error wrapping fs.ErrNotExist, got %v", err)
+	}
+
+	_, err = ReadAndValidateJSON(invalid, true)
+	if !errors.Is(err, ErrInvalidJSON) || errors.Is(err, ErrFileRead) || exitCode(err) != exitInvalidJSON {
+		t.Errorf("Expected an invalid JSON error, got %v", err)
+	}
+
+	_, err = readAndValidateJSONWithOptions(invalid, ReadOptions{Concise: true, MultiDoc: true})
+	if !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("Expected an invalid JSON error in multi-document mode, got %v", err)
+	}
+
+	_, err = compileRegex("[a-")
+	if !errors.Is(err, ErrBadPattern) || exitCode(err) != exitBadPattern {
+		t.Errorf("Expected a bad pattern error, got %v", err)
+	}
+	if _, err := matchesRegex("a", "b", "(x"); !errors.Is(err, ErrBadPattern) {
+		t.Errorf("Expected matchesRegex to return a bad pattern error, got %v", err)
+	}
+
+	config := &Config{RegexMatches: map[string]string{"name": "[a-"}}
+	if err := config.Validate(); !errors.Is(err, ErrBadPattern) {
+		t.Errorf("Expected config validation to wrap the bad pattern error, got %v", err)
+	}
+
+	if code := exitCode(errors.New("other")); code != 1 {
+		t.Errorf("Expected other errors to exit with 1, got %d", code)
+	}
+}
+
+func TestCheckNonEmpty(t *testing.T) {
+	testCases := []struct {
+		name  string
+		file  *JSONFile
+		empty bool
+	}{
+		{"Null", &JSONFile{Data: nil}, true},
+		{"Empty object", &JSONFile{Data: map[string]interface{}{}}, true},
+		{"Empty array", &JSONFile{Data: []interface{}{}}, true},
+		{"Object", &JSONFile{Data: map[string]interface{}{"a": nil}}, false},
+		{"Array", &JSONFile{Data: []interface{}{nil}}, false},
+		{"Scalar", &JSONFile{Data: false}, false},
+		{"Empty document among several", &JSONFile{Data: []interface{}{1.0}, Documents: []interface{}{1.0, []interface{}{}}}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkNonEmpty(tc.file)
+			if (err != nil) != tc.empty {
+				t.Errorf("Expected empty=%v, got %v", tc.empty, err)
+			}
+			if err != nil && (!errors.Is(err, ErrEmptyDocument) || exitCode(err) != exitEmptyDoc) {
+				t.Errorf("Expected an empty document error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSplitCombined(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		first, second string
+	}{
+		{"separator line", "{\"a\": 1}\n---\n{\"a\": 2}\n", `{"a": 1}`, `{"a": 2}`},
+		{"whitespace only", "[1, 2] [3]", `[1, 2]`, `[3]`},
+		{"separator inside a string", "{\"s\": \"---\"}\n---\n\"---\"", `{"s": "---"}`, `"---"`},
+		{"separator with trailing spaces", "1\n---  \r\n2", `1`, `2`},
+	}
+	for _, tc := range tests {
+		first, second, err := splitCombined([]byte(tc.data))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if string(first) != tc.first || string(second) != tc.second {
+			t.Errorf("%s: got %q and %q", tc.name, first, second)
+		}
+	}
+
+	for _, data := range []string{"", "{\"a\": 1}", "{\"a\": 1}\n---\n", "1\n----\n2", "1\n---\n2\n3", "{\"a\": }\n---\n{}"} {
+		if _, _, err := splitCombined([]byte(data)); !errors.Is(err, ErrInvalidJSON) {
+			t.Errorf("splitCombined(%q) error = %v, want ErrInvalidJSON", data, err)
+		}
+	}
+}
+
+func TestReadSplitJSON(t *testing.T) {
+	combined := filepath.Join(t.TempDir(), "combined.json")
+	if err := os.WriteFile(combined, []byte("{\"name\": \"a\"}\n---\n{\"name\": \"b\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file1, file2, err := readSplitJSON(combined, ReadOptions{Concise: true}, ReadOptions{Concise: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	differences := findDifferencesWithOptions(file1.Data, file2.Data, "", CompareOptions{})
+	if len(differences) != 1 || differences[0].Path != "name" {
+		t.Errorf("Expected name to differ, got %v", differences)
+	}
+
+	if _, _, err := readSplitJSON(combined, ReadOptions{Concise: true, MultiDoc: true}, ReadOptions{Concise: true, MultiDoc: true}); err == nil {
+		t.Error("Expected an error in multi-document mode")
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("JSONDIFF_HOST", "db.example.com")
+	t.Setenv("JSONDIFF_PORT", "5432")
+
+	data := []byte(`{"host": "${JSONDIFF_HOST}", "port": $JSONDIFF_PORT, "$ref": "#/x", "cost": "$5", "path": "${JSONDIFF_UNSET}"}`)
+	expanded, err := expandEnv(data, MissingEnvKeep)
+	if err != nil {
+		t.Fatalf("expandEnv returned error: %v", err)
+	}
+	expected := `{"host": "db.example.com", "port": 5432, "$ref": "#/x", "cost": "$5", "path": "${JSONDIFF_UNSET}"}`
+	if string(expanded) != expected {
+		t.Errorf("Expected %s, got %s", expected, expanded)
+	}
+
+	if _, err := expandEnv(data, MissingEnvError); err == nil || !strings.Contains(err.Error(), "ref") {
+		t.Errorf("Expected an error naming the first unset variable, got %v", err)
+	}
+
+	// Only the file read with ExpandEnv is expanded
+	file, err := parseJSONData(data, "template.json", ReadOptions{Concise: true, ExpandEnv: true})
+	if err != nil {
+		t.Fatalf("Failed to parse expanded data: %v", err)
+	}
+	if obj := file.Data.(map[string]interface{}); obj["host"] != "db.example.com" || obj["port"] != 5432.0 {
+		t.Errorf("Expected the placeholders to be resolved, got %v", obj)
+	}
+	if _, err := parseJSONData(data, "template.json", ReadOptions{Concise: true}); err == nil {
+		t.Error("Expected the unexpanded template to be invalid JSON")
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// joinPath appends a key to a parent path using dot notation
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// renameKeys returns a copy of obj with its keys renamed according to renames.
+// A rename is looked up first by the key's full path and then by its bare name,
+// so "user.userName" only applies at that path while "userName" applies everywhere.
+// If a renamed key collides with an existing key, the renamed value wins.
+func renameKeys(obj map[string]interface{}, path string, renames map[string]string) map[string]interface{} {
+	renamed := make(map[string]interface{}, len(obj))
+	targets := make(map[string]bool)
+
+	for key, val := range obj {
+		newKey, ok := renames[joinPath(path, key)]
+		if !ok {
+			newKey, ok = renames[key]
+		}
+		if !ok {
+			// Only keep an unrenamed key if no renamed key already claimed its name
+			if !targets[key] {
+				renamed[key] = val
+			}
+			continue
+		}
+		renamed[newKey] = val
+		targets[newKey] = true
+	}
+
+	return renamed
+}
+
+// checkRenameTargets reports renames that give two keys of the same object the same
+// new name, since which value survived would then depend on map iteration order.
+// A bare key name applies in every object, so it conflicts with any other rename to
+// the same name; two paths only conflict if they share a parent.
+func checkRenameTargets(renames map[string]string) error {
+	olds := make([]string, 0, len(renames))
+	for old := range renames {
+		olds = append(olds, old)
+	}
+	sort.Strings(olds)
+
+	for i, a := range olds {
+		for _, b := range olds[i+1:] {
+			if renames[a] != renames[b] {
+				continue
+			}
+			parentA, parentB := renameParent(a), renameParent(b)
+			if !strings.Contains(a, ".") || !strings.Contains(b, ".") || parentA == parentB {
+				return fmt.Errorf("renames %q and %q both target %q", a, b, renames[a])
+			}
+		}
+	}
+	return nil
+}
+
+// renameParent returns the parent path of a rename's old key or path
+func renameParent(old string) string {
+	if i := strings.LastIndex(old, "."); i >= 0 {
+		return old[:i]
+	}
+	return ""
+}
+
+// parseStripKeyPrefix parses a side:prefix specification, where side is left
+// (the first file) or right (the second file)
+func parseStripKeyPrefix(spec string) (string, string, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || (parts[0] != "left" && parts[0] != "right") || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid key prefix %q, expected left:prefix or right:prefix", spec)
+	}
+	return parts[0], parts[1], nil
+}
+
+// stripKeyPrefix returns obj with prefix removed from the start of every key
+// that has it, e.g. "app.user.name" becomes "user.name" for the prefix "app.".
+// Keys that are exactly the prefix are kept. As with renames, a stripped key
+// colliding with an existing key wins.
+func stripKeyPrefix(obj map[string]interface{}, prefix string) map[string]interface{} {
+	renames := make(map[string]string)
+	for key := range obj {
+		if len(key) > len(prefix) && strings.HasPrefix(key, prefix) {
+			renames[key] = key[len(prefix):]
+		}
+	}
+	if len(renames) == 0 {
+		return obj
+	}
+	return renameKeys(obj, "", renames)
+}
+
+// parseAliasGroup parses a group of synonym key names in the form
+// canonical=alias=alias..., e.g. "zip=zipcode=postal_code". It returns a map
+// from every name in the group, including the canonical name, to the canonical name.
+func parseAliasGroup(group string) (map[string]string, error) {
+	names := strings.Split(group, "=")
+	if len(names) < 2 {
+		return nil, fmt.Errorf("invalid alias %q, expected canonical=alias[=alias...]", group)
+	}
+
+	aliases := make(map[string]string, len(names))
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("invalid alias %q, key names must not be empty", group)
+		}
+		aliases[name] = names[0]
+	}
+	return aliases, nil
+}
+
+// dropKeys returns a copy of obj without the keys whose name is in names.
+// obj itself is returned when none of its keys are dropped.
+func dropKeys(obj map[string]interface{}, names []string) map[string]interface{} {
+	var kept map[string]interface{}
+	for _, name := range names {
+		if _, ok := obj[name]; !ok {
+			continue
+		}
+		if kept == nil {
+			kept = make(map[string]interface{}, len(obj))
+			for key, val := range obj {
+				kept[key] = val
+			}
+		}
+		delete(kept, name)
+	}
+
+	if kept == nil {
+		return obj
+	}
+	return kept
+}
+
+// numericObjectToArray converts an object whose keys are exactly the
+// sequential indices "0", "1", ... into an array in index order.
+// The boolean result is false if any key is not such an index.
+func numericObjectToArray(obj map[string]interface{}) ([]interface{}, bool) {
+	arr := make([]interface{}, len(obj))
+	for i := range arr {
+		val, ok := obj[strconv.Itoa(i)]
+		if !ok {
+			return nil, false
+		}
+		arr[i] = val
+	}
+	return arr, true
+}
+
+// foldKey returns the form of a key that differences in representation are
+// ignored for, without ignoring case
+func foldKey(key string, options CompareOptions) string {
+	if options.FoldUnicode {
+		key = norm.NFC.String(key)
+	}
+	if options.IgnoreWhitespaceKeys {
+		key = strings.Join(strings.Fields(key), "")
+	}
+	if options.NormalizeNumericKeys {
+		key = normalizeNumericKey(key)
+	}
+	return key
+}
+
+// leadingZeros matches the zeros padding the integer part of a number
+var leadingZeros = regexp.MustCompile(`^(-?)0+([0-9])`)
+
+// normalizeNumericKey returns a key written as a number in canonical form
+// (see canonicalNumber), also stripping zeros padding its integer part, so
+// "01", "1.0" and "1e0" all become "1". Other keys are returned unchanged.
+func normalizeNumericKey(key string) string {
+	if canonical, ok := normalizeNumericString(leadingZeros.ReplaceAllString(key, "$1$2")); ok {
+		return canonical
+	}
+	return key
+}
+
+// matchKey returns the normalized name two keys are matched by
+func matchKey(key string, options CompareOptions) string {
+	// Styles run before case folding, which would hide camelCase word boundaries
+	key = applyKeyStyles(foldKey(key, options), options.KeyStyles)
+	if options.IgnoreCase || options.ReportCaseDiffs {
+		key = strings.ToLower(key)
+	}
+	return key
+}
+
+// indexObjectKeys indexes an object's values by the normalized name their
+// key is matched by, and maps each normalized name back to the original
+// key. When several keys normalize to the same name, the key already in
+// that form, or else the first in sorted order, takes it; the others keep
+// their own name so they are still compared rather than silently dropped.
+func indexObjectKeys(obj map[string]interface{}, options CompareOptions) (map[string]interface{}, map[string]string) {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	normalized := make(map[string]string, len(keys))
+	owner := make(map[string]string, len(keys))
+	for _, key := range keys {
+		name := matchKey(key, options)
+		normalized[key] = name
+		if current, taken := owner[name]; !taken || (key == name && current != name) {
+			owner[name] = key
+		}
+	}
+
+	lookup := make(map[string]interface{}, len(keys))
+	originals := make(map[string]string, len(keys))
+	for _, key := range keys {
+		name := normalized[key]
+		if owner[name] != key {
+			name = key
+		}
+		lookup[name] = obj[key]
+		originals[name] = key
+	}
+	return lookup, originals
+}
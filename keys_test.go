@@ -0,0 +1,453 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"testing"
+)
+
+func TestRenameKeys(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"userName": "jdoe",
+		"profile": map[string]interface{}{
+			"userName": "jdoe",
+			"zip":      "10001",
+		},
+	}
+	obj2 := map[string]interface{}{
+		"user_name": "jdoe",
+		"profile": map[string]interface{}{
+			"user_name":   "jdoe",
+			"postal_code": "10002",
+		},
+	}
+
+	// Without renames every renamed key shows up as a remove/add pair
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{})
+	if len(diffs) != 6 {
+		t.Errorf("Expected 6 differences without renames, got %d: %v", len(diffs), diffs)
+	}
+
+	// A bare key name applies at every level, a path applies only at that path
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		RenameKeys: map[string]string{
+			"userName":    "user_name",
+			"profile.zip": "postal_code",
+		},
+	})
+	if len(diffs) != 1 {
+		t.Fatalf("Expected 1 difference with renames, got %d: %v", len(diffs), diffs)
+	}
+	if diffs[0].Path != "profile.postal_code" || diffs[0].Type != ValueMismatch {
+		t.Errorf("Expected value mismatch reported at the renamed path, got %s", formatDiff(diffs[0]))
+	}
+
+	// A path-scoped rename does not apply elsewhere
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		RenameKeys: map[string]string{"profile.userName": "user_name"},
+	})
+	if len(diffs) != 4 {
+		t.Errorf("Expected 4 differences with a path-scoped rename, got %d: %v", len(diffs), diffs)
+	}
+}
+
+func TestCheckRenameTargets(t *testing.T) {
+	tests := []struct {
+		name     string
+		renames  map[string]string
+		conflict bool
+	}{
+		{"Distinct targets", map[string]string{"userName": "user_name", "zip": "postal_code"}, false},
+		{"Two bare names", map[string]string{"userName": "name", "login": "name"}, true},
+		{"Bare name and path", map[string]string{"userName": "name", "profile.login": "name"}, true},
+		{"Paths with the same parent", map[string]string{"profile.userName": "name", "profile.login": "name"}, true},
+		{"Paths with different parents", map[string]string{"profile.userName": "name", "account.login": "name"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRenameTargets(tt.renames)
+			if (err != nil) != tt.conflict {
+				t.Errorf("checkRenameTargets(%v) = %v, expected conflict %v", tt.renames, err, tt.conflict)
+			}
+		})
+	}
+}
+
+func TestIgnoreKeyNames(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"name":      "John",
+		"updatedAt": "2023-01-01",
+		"orders": []interface{}{
+			map[string]interface{}{"id": 1.0, "updatedAt": "2023-01-02"},
+		},
+	}
+	obj2 := map[string]interface{}{
+		"name": "John",
+		"orders": []interface{}{
+			map[string]interface{}{"id": 1.0, "updatedAt": "2023-02-02", "createdAt": "2023-02-01"},
+		},
+	}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		IgnoreKeyNames: []string{"updatedAt", "createdAt"},
+	})
+	if len(diffs) != 0 {
+		t.Errorf("Expected no differences with ignored key names, got %d: %v", len(diffs), diffs)
+	}
+
+	// Only exact names are ignored
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		IgnoreKeyNames: []string{"updated"},
+	})
+	if len(diffs) != 3 {
+		t.Errorf("Expected 3 differences, got %d: %v", len(diffs), diffs)
+	}
+
+	// The original objects are left untouched
+	if _, ok := obj1["updatedAt"]; !ok {
+		t.Errorf("Expected dropKeys not to modify its input")
+	}
+}
+
+func TestKeyAliases(t *testing.T) {
+	aliases, err := parseAliasGroup("zip=zipcode=postal_code")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(aliases) != 3 || aliases["postal_code"] != "zip" || aliases["zip"] != "zip" {
+		t.Errorf("Unexpected aliases: %v", aliases)
+	}
+	for _, group := range []string{"zip", "zip==postal_code", "=zip"} {
+		if _, err := parseAliasGroup(group); err == nil {
+			t.Errorf("Expected an error for %q", group)
+		}
+	}
+
+	obj1 := map[string]interface{}{
+		"zipcode": "10001",
+		"address": map[string]interface{}{"postal_code": "10001"},
+	}
+	obj2 := map[string]interface{}{
+		"postal_code": "10002",
+		"address":     map[string]interface{}{"zip": "10001"},
+	}
+
+	// Differences are reported under the canonical name
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{KeyAliases: aliases})
+	if len(diffs) != 1 || diffs[0].Path != "zip" || diffs[0].Type != ValueMismatch {
+		t.Errorf("Expected a single value mismatch at zip, got %v", diffs)
+	}
+}
+
+func TestCoerceNumericObjects(t *testing.T) {
+	arr, ok := numericObjectToArray(map[string]interface{}{"1": "b", "0": "a"})
+	if !ok || len(arr) != 2 || arr[0] != "a" || arr[1] != "b" {
+		t.Errorf("Expected [a b], got %v, %v", arr, ok)
+	}
+	for _, obj := range []map[string]interface{}{
+		{"0": "a", "2": "c"},
+		{"0": "a", "01": "b"},
+		{"0": "a", "name": "b"},
+	} {
+		if _, ok := numericObjectToArray(obj); ok {
+			t.Errorf("Expected %v not to convert to an array", obj)
+		}
+	}
+
+	obj1 := map[string]interface{}{"items": map[string]interface{}{"0": "a", "1": "b"}}
+	obj2 := map[string]interface{}{"items": []interface{}{"a", "c"}}
+
+	// A type mismatch without the option
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{})
+	if len(diffs) != 1 || diffs[0].Type != TypeMismatch {
+		t.Errorf("Expected a type mismatch, got %v", diffs)
+	}
+
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{CoerceNumericObjects: true})
+	if len(diffs) != 1 || diffs[0].Path != "items[1]" || diffs[0].Type != ValueMismatch {
+		t.Errorf("Expected a value mismatch at items[1], got %v", diffs)
+	}
+	diffs = findDifferencesWithOptions(obj2, obj1, "", CompareOptions{CoerceNumericObjects: true})
+	if len(diffs) != 1 || diffs[0].Path != "items[1]" {
+		t.Errorf("Expected the coercion to work in either direction, got %v", diffs)
+	}
+}
+
+func TestStripKeyPrefix(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"app.user.name": "Alice",
+		"app.user.age":  30.0,
+		"app.":          "kept",
+		"nested":        map[string]interface{}{"app.port": 80.0},
+	}
+	obj2 := map[string]interface{}{
+		"user.name": "Alice",
+		"user.age":  31.0,
+		"app.":      "kept",
+		"nested":    map[string]interface{}{"port": 80.0},
+	}
+
+	for _, spec := range []string{"left", "up:app.", "left:"} {
+		if _, _, err := parseStripKeyPrefix(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+	side, prefix, err := parseStripKeyPrefix("left:app.")
+	if err != nil || side != "left" || prefix != "app." {
+		t.Fatalf("Expected left and app., got %q, %q, %v", side, prefix, err)
+	}
+
+	// Stripped keys are reported under the stripped name, at every level
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{StripKeyPrefixLeft: prefix})
+	if len(diffs) != 1 || diffs[0].Path != "user.age" || diffs[0].Type != ValueMismatch {
+		t.Errorf("Expected a single value mismatch at user.age, got %v", diffs)
+	}
+	diffs = findDifferencesWithOptions(obj2, obj1, "", CompareOptions{StripKeyPrefixRight: prefix})
+	if len(diffs) != 1 || diffs[0].Path != "user.age" {
+		t.Errorf("Expected the right prefix to apply to the second object, got %v", diffs)
+	}
+}
+
+func TestUnwrapSingletonArrays(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"owner": []interface{}{map[string]interface{}{"name": "Alice", "age": 30.0}},
+		"tags":  []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"}},
+	}
+	obj2 := map[string]interface{}{
+		"owner": map[string]interface{}{"name": "Alice", "age": 31.0},
+		"tags":  map[string]interface{}{"name": "a"},
+	}
+
+	// Type mismatches without the option
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{})
+	if len(diffs) != 2 || diffs[0].Type != TypeMismatch || diffs[1].Type != TypeMismatch {
+		t.Errorf("Expected two type mismatches, got %v", diffs)
+	}
+
+	// The singleton is unwrapped in either direction; the two-element array is not
+	for _, pair := range [][2]interface{}{{obj1, obj2}, {obj2, obj1}} {
+		diffs = findDifferencesWithOptions(pair[0], pair[1], "", CompareOptions{UnwrapSingletons: true})
+		if len(diffs) != 2 || diffs[0].Path != "owner.age" || diffs[0].Type != ValueMismatch ||
+			diffs[1].Path != "tags" || diffs[1].Type != TypeMismatch {
+			t.Errorf("Expected a value mismatch at owner.age and a type mismatch at tags, got %v", diffs)
+		}
+	}
+}
+
+func TestUnwrapValueKey(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"name": map[string]interface{}{"value": "Alice", "updatedAt": "2023-01-01"},
+		"age":  map[string]interface{}{"value": 30.0, "updatedAt": "2023-01-01"},
+		"address": map[string]interface{}{
+			"value": map[string]interface{}{
+				"city": map[string]interface{}{"value": "Paris", "updatedAt": "2023-01-01"},
+			},
+		},
+		"tags":  []interface{}{map[string]interface{}{"value": "a"}, "b"},
+		"plain": map[string]interface{}{"x": 1.0},
+	}
+	obj2 := map[string]interface{}{
+		"name": map[string]interface{}{"value": "Alice", "updatedAt": "2023-02-01"},
+		"age":  31.0,
+		"address": map[string]interface{}{
+			"value": map[string]interface{}{
+				"city": map[string]interface{}{"value": "Lyon", "updatedAt": "2023-02-01"},
+			},
+		},
+		"tags":  []interface{}{"a", map[string]interface{}{"value": "b", "meta": 1.0}},
+		"plain": map[string]interface{}{"x": 2.0},
+	}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{UnwrapValueKey: "value"})
+	expected := []string{"address.city", "age", "plain.x"}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected differences at %v, got %v", expected, diffs)
+	}
+	for i, diff := range diffs {
+		if diff.Path != expected[i] || diff.Type != ValueMismatch {
+			t.Errorf("Expected a value mismatch at %s, got %v", expected[i], diff)
+		}
+	}
+	if diffs[1].Value1 != 30.0 || diffs[1].Value2 != 31.0 {
+		t.Errorf("Expected the unwrapped values to be reported, got %v", diffs[1])
+	}
+}
+
+func TestIgnoreWhitespaceKeys(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"name ":      "John",
+		"first name": "John",
+		"age":        30.0,
+	}
+	obj2 := map[string]interface{}{
+		"name":      "Jane",
+		"firstname": "John",
+		" age\t":    30.0,
+	}
+
+	// Without the option every key is only in one file
+	if diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{}); len(diffs) != 6 {
+		t.Errorf("Expected 6 differences without the option, got %v", diffs)
+	}
+
+	// Matched keys are reported under the name from the first file
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{IgnoreWhitespaceKeys: true})
+	if len(diffs) != 1 || diffs[0].Path != "name " || diffs[0].Type != ValueMismatch {
+		t.Errorf("Expected a single value mismatch at \"name \", got %v", diffs)
+	}
+
+	// Combines with case-insensitive keys
+	obj2 = map[string]interface{}{"NAME": "John", "First Name": "John", "age": 30.0}
+	if diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{IgnoreWhitespaceKeys: true, IgnoreCase: true}); len(diffs) != 0 {
+		t.Errorf("Expected no differences, got %v", diffs)
+	}
+}
+
+func TestNormalizedKeyCollision(t *testing.T) {
+	// Keys that normalize to the same name are each compared, whatever the map order
+	tests := []struct {
+		name    string
+		obj     map[string]interface{}
+		options CompareOptions
+	}{
+		{"ignore-case", map[string]interface{}{"Name": "a", "name": "b"}, CompareOptions{IgnoreCase: true}},
+		{"report-case-diffs", map[string]interface{}{"Name": "a", "name": "b"}, CompareOptions{ReportCaseDiffs: true}},
+		{"fold-unicode", map[string]interface{}{"caf\u00e9": "a", "cafe\u0301": "b"}, CompareOptions{FoldUnicode: true}},
+		{"ignore-whitespace-keys", map[string]interface{}{"first name": "a", "firstname": "b"}, CompareOptions{IgnoreWhitespaceKeys: true}},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if diffs := findDifferencesWithOptions(tt.obj, tt.obj, "", tt.options); len(diffs) != 0 {
+				t.Fatalf("%s: expected identical objects to match, got %v", tt.name, diffs)
+			}
+		}
+	}
+
+	// The key already in normalized form takes the name; the other is matched by its own
+	lookup, originals := indexObjectKeys(map[string]interface{}{"Name": "a", "name": "b"}, CompareOptions{IgnoreCase: true})
+	if lookup["name"] != "b" || lookup["Name"] != "a" || originals["name"] != "name" || originals["Name"] != "Name" {
+		t.Errorf("Unexpected collision handling: %v %v", lookup, originals)
+	}
+}
+
+func TestNormalizeNumericKeys(t *testing.T) {
+	tests := []struct{ key, want string }{
+		{"01", "1"},
+		{"1.0", "1"},
+		{"1e2", "100"},
+		{"-007", "-7"},
+		{"00", "0"},
+		{"0.50", "0.5"},
+		{"id", "id"},
+		{"1a", "1a"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeNumericKey(tt.key); got != tt.want {
+			t.Errorf("normalizeNumericKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	obj1 := map[string]interface{}{"01": "a", "02": "b", "x": 1.0}
+	obj2 := map[string]interface{}{"1": "a", "2": "c", "x": 1.0}
+
+	// Without the option every numeric key is only in one file
+	if diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{}); len(diffs) != 4 {
+		t.Errorf("Expected 4 differences without the option, got %v", diffs)
+	}
+
+	// Matched keys are reported under the name from the first file
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{NormalizeNumericKeys: true})
+	if len(diffs) != 1 || diffs[0].Path != "02" || diffs[0].Type != ValueMismatch {
+		t.Errorf("Expected a single value mismatch at \"02\", got %v", diffs)
+	}
+
+	// Numeric keys differing in representation are not a case mismatch
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{NormalizeNumericKeys: true, ReportCaseDiffs: true})
+	if len(diffs) != 1 || diffs[0].Path != "02" {
+		t.Errorf("Expected a single difference at \"02\", got %v", diffs)
+	}
+}
+
+func TestKeyStyles(t *testing.T) {
+	snake := []struct{ key, want string }{
+		{"firstName", "first_name"},
+		{"FirstName", "first_name"},
+		{"first-name", "first_name"},
+		{"first name", "first_name"},
+		{"first_name", "first_name"},
+		{"HTTPServer", "http_server"},
+		{"userID", "user_id"},
+		{"address2Line", "address2_line"},
+	}
+	for _, tc := range snake {
+		if got := camelToSnake(tc.key); got != tc.want {
+			t.Errorf("camelToSnake(%q) = %q, want %q", tc.key, got, tc.want)
+		}
+	}
+
+	singular := []struct{ key, want string }{
+		{"items", "item"},
+		{"categories", "category"},
+		{"boxes", "box"},
+		{"addresses", "address"},
+		{"matches", "match"},
+		{"status", "status"},
+		{"address", "address"},
+		{"analysis", "analysis"},
+		{"order_items", "order_item"},
+		{"s", "s"},
+	}
+	for _, tc := range singular {
+		if got := singularize(tc.key); got != tc.want {
+			t.Errorf("singularize(%q) = %q, want %q", tc.key, got, tc.want)
+		}
+	}
+
+	if _, err := parseKeyStyles([]string{"camel-to-snake,singularize"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := parseKeyStyles([]string{"kebab"}); err == nil {
+		t.Error("Expected an error for an unknown style")
+	}
+}
+
+func TestNormalizeKeyStyle(t *testing.T) {
+	obj1 := map[string]interface{}{"firstName": "Ann", "orderItems": []interface{}{1.0}}
+	obj2 := map[string]interface{}{"first_name": "Ann", "order_item": []interface{}{2.0}}
+	options := CompareOptions{KeyStyles: []string{"camel-to-snake", "singularize"}}
+
+	// Keys match across styles and differences are reported under the first file's key
+	differences := findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(differences) != 1 || differences[0].Path != "orderItems[0]" {
+		t.Errorf("Expected only orderItems[0] to differ, got %v", differences)
+	}
+
+	// Style transformations compose with ignoring case
+	options.IgnoreCase = true
+	if differences := findDifferencesWithOptions(map[string]interface{}{"userId": 1.0}, map[string]interface{}{"user_id": 1.0}, "", options); len(differences) != 0 {
+		t.Errorf("Expected userId to match user_id with -ignore-case, got %v", differences)
+	}
+}
+
+func TestNormalizeKeyStyleCollision(t *testing.T) {
+	// item and items both singularize to item: item keeps the name, items is matched as itself
+	obj1 := map[string]interface{}{"item": 1.0, "items": []interface{}{1.0}}
+	obj2 := map[string]interface{}{"item": 1.0, "items": []interface{}{2.0}}
+	options := CompareOptions{KeyStyles: []string{"singularize"}}
+
+	for i := 0; i < 10; i++ {
+		differences := findDifferencesWithOptions(obj1, obj2, "", options)
+		if len(differences) != 1 || differences[0].Path != "items[0]" {
+			t.Fatalf("Expected only items[0] to differ, got %v", differences)
+		}
+	}
+
+	// Without a key in canonical form, the first key alphabetically wins
+	lookup, originals := indexObjectKeys(map[string]interface{}{"Items": 1.0, "items": 2.0}, CompareOptions{KeyStyles: []string{"singularize"}, IgnoreCase: true})
+	if lookup["item"] != 1.0 || originals["item"] != "Items" || lookup["items"] != 2.0 {
+		t.Errorf("Unexpected collision handling: %v %v", lookup, originals)
+	}
+}
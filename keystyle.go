@@ -0,0 +1,91 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// keyStyles maps the name of each key style transformation to its function
+var keyStyles = map[string]func(string) string{
+	"camel-to-snake": camelToSnake,
+	"singularize":    singularize,
+}
+
+// parseKeyStyles checks a list of key style names, which may also be given
+// comma-separated, and returns them in order
+func parseKeyStyles(specs []string) ([]string, error) {
+	var styles []string
+	for _, spec := range specs {
+		for _, name := range strings.Split(spec, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := keyStyles[name]; !ok {
+				return nil, fmt.Errorf("unknown key style %q, expected camel-to-snake or singularize", name)
+			}
+			styles = append(styles, name)
+		}
+	}
+	return styles, nil
+}
+
+// applyKeyStyles transforms a key by each style in order
+func applyKeyStyles(key string, styles []string) string {
+	for _, style := range styles {
+		key = keyStyles[style](key)
+	}
+	return key
+}
+
+// camelToSnake converts camelCase, PascalCase, kebab-case and spaced keys
+// to lowercase snake_case, e.g. "firstName" and "first-name" to
+// "first_name" and "HTTPServer" to "http_server"
+func camelToSnake(key string) string {
+	runes := []rune(key)
+	var sb strings.Builder
+	for i, r := range runes {
+		if r == '-' || r == ' ' {
+			r = '_'
+		}
+		if unicode.IsUpper(r) && i > 0 && runes[i-1] != '_' && runes[i-1] != '-' && runes[i-1] != ' ' {
+			// A word starts at an upper case letter after a lower case one or a digit,
+			// or at the last capital of an acronym followed by a lower case letter
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				sb.WriteRune('_')
+			}
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
+
+// singularize turns the last word of a key from a regular English plural
+// into its singular, e.g. "items" to "item", "categories" to "category" and
+// "order_addresses" to "order_address". Words ending in "ss", "us" or "is"
+// are left alone, as are irregular plurals.
+func singularize(key string) string {
+	lower := strings.ToLower(key)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(key) > 4:
+		return key[:len(key)-3] + matchCase(key[len(key)-3:], "y")
+	case strings.HasSuffix(lower, "sses") || strings.HasSuffix(lower, "xes") || strings.HasSuffix(lower, "zes") ||
+		strings.HasSuffix(lower, "ches") || strings.HasSuffix(lower, "shes"):
+		return key[:len(key)-2]
+	case strings.HasSuffix(lower, "s") && !strings.HasSuffix(lower, "ss") &&
+		!strings.HasSuffix(lower, "us") && !strings.HasSuffix(lower, "is") && len(key) > 1:
+		return key[:len(key)-1]
+	}
+	return key
+}
+
+// matchCase returns replacement in upper case if suffix is all upper case
+func matchCase(suffix, replacement string) string {
+	if strings.ToUpper(suffix) == suffix {
+		return strings.ToUpper(replacement)
+	}
+	return replacement
+}
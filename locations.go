@@ -0,0 +1,134 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Location is where a value starts in a source file
+type Location struct {
+	Offset int `json:"offset"` // Byte offset of the value's first byte
+	Line   int `json:"line"`   // 1-based line number
+	Column int `json:"column"` // 1-based column, counted in characters
+}
+
+// recordLocations walks the tokens of a JSON document and returns where each
+// value starts, keyed by its path in the same form differences are reported
+// in. Of duplicate keys the last wins, as it does when decoding.
+func recordLocations(data []byte) (map[string]Location, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	// Offsets only grow, so lines are counted in one pass alongside the tokens
+	line, lineStart, scanned := 1, 0, 0
+	locate := func(offset int) Location {
+		for ; scanned < offset; scanned++ {
+			if data[scanned] == '\n' {
+				line, lineStart = line+1, scanned+1
+			}
+		}
+		return Location{Offset: offset, Line: line, Column: utf8.RuneCount(data[lineStart:offset]) + 1}
+	}
+
+	// container is an object or array whose values are being read
+	type container struct {
+		path    string
+		array   bool
+		index   int    // Index of the next array element
+		key     string // Key of the next object value
+		wantKey bool   // Whether the next token is an object key
+	}
+	var stack []*container
+
+	locations := make(map[string]Location)
+	for {
+		start := valueStart(data, int(decoder.InputOffset()))
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidJSON, err)
+		}
+
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		path := ""
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			switch {
+			case top.wantKey:
+				top.key, top.wantKey = token.(string), false
+				continue
+			case top.array:
+				path = fmt.Sprintf("%s[%d]", top.path, top.index)
+				top.index++
+			default:
+				path = joinPath(top.path, top.key)
+				top.wantKey = true
+			}
+		}
+		locations[path] = locate(start)
+
+		if delim, ok := token.(json.Delim); ok {
+			stack = append(stack, &container{path: path, array: delim == '[', wantKey: delim == '{'})
+		}
+	}
+	return locations, nil
+}
+
+// valueStart returns the offset of the next token at or after offset,
+// skipping whitespace and the separators between values
+func valueStart(data []byte, offset int) int {
+	for offset < len(data) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// findLocation returns the location of the value at path, or of the nearest
+// enclosing value if the file has none there, e.g. for a key only in the
+// other file
+func findLocation(locations map[string]Location, path string) (Location, bool) {
+	for {
+		if loc, ok := locations[path]; ok {
+			return loc, true
+		}
+		if path == "" {
+			return Location{}, false
+		}
+		path = parentPath(path)
+	}
+}
+
+// addLocations sets the source locations of each difference in both files.
+// The first file's location of a moved value is where it was moved from.
+func addLocations(differences []Diff, locations1, locations2 map[string]Location) []Diff {
+	for i, diff := range differences {
+		path1 := diff.Path
+		if diff.Type == Moved {
+			path1, _ = diff.Value1.(string)
+		}
+		if loc, ok := findLocation(locations1, path1); ok {
+			differences[i].Loc1 = &loc
+		}
+		if loc, ok := findLocation(locations2, diff.Path); ok {
+			differences[i].Loc2 = &loc
+		}
+	}
+	return differences
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecordLocations(t *testing.T) {
+	data := []byte("{\n  \"name\": \"café\", \"tags\": [1, {\"k\": null}],\n  \"user\": {\"id\": 7}\n}\n")
+	locations, err := recordLocations(data)
+	if err != nil {
+		t.Fatalf("recordLocations returned error: %v", err)
+	}
+
+	expected := map[string]Location{
+		"":          {Offset: 0, Line: 1, Column: 1},
+		"name":      {Offset: 12, Line: 2, Column: 11},
+		"tags":      {Offset: 29, Line: 2, Column: 27},
+		"tags[0]":   {Offset: 30, Line: 2, Column: 28},
+		"tags[1]":   {Offset: 33, Line: 2, Column: 31},
+		"tags[1].k": {Offset: 39, Line: 2, Column: 37},
+		"user":      {Offset: 57, Line: 3, Column: 11},
+		"user.id":   {Offset: 64, Line: 3, Column: 18},
+	}
+	if len(locations) != len(expected) {
+		t.Errorf("Expected %d locations, got %v", len(expected), locations)
+	}
+	for path, loc := range expected {
+		if locations[path] != loc {
+			t.Errorf("Expected %s at %+v, got %+v", displayPath(path), loc, locations[path])
+		}
+		if got := string(data[loc.Offset]); path != "" && !strings.ContainsAny(got, "\"[{0123456789n") {
+			t.Errorf("Expected %s to start at a value, got %q", displayPath(path), got)
+		}
+	}
+
+	if _, err := recordLocations([]byte(`{"a": }`)); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestAddLocations(t *testing.T) {
+	locations1, _ := recordLocations([]byte(`{"a": 1, "b": {"c": 2}}`))
+	locations2, _ := recordLocations([]byte(`{"a": 2, "b": {"d": 2}}`))
+	differences := []Diff{
+		{Path: "a", Type: ValueMismatch},
+		{Path: "b.c", Type: KeyOnlyInFirst},
+		{Path: "b.d", Type: Moved, Value1: "b.c"},
+	}
+
+	differences = addLocations(differences, locations1, locations2)
+	if loc := differences[0].Loc1; loc == nil || loc.Offset != 6 {
+		t.Errorf("Expected a in the first file at offset 6, got %+v", loc)
+	}
+	if loc := differences[0].Loc2; loc == nil || loc.Offset != 6 {
+		t.Errorf("Expected a in the second file at offset 6, got %+v", loc)
+	}
+	// A key missing from the second file is located at its enclosing object
+	if loc := differences[1].Loc2; loc == nil || loc.Offset != 14 {
+		t.Errorf("Expected b.c in the second file at b, offset 14, got %+v", loc)
+	}
+	// A moved value is located at its old path in the first file
+	if loc := differences[2].Loc1; loc == nil || loc.Offset != 20 {
+		t.Errorf("Expected the move in the first file at b.c, offset 20, got %+v", loc)
+	}
+	if loc := differences[2].Loc2; loc == nil || loc.Offset != 20 {
+		t.Errorf("Expected the move in the second file at b.d, offset 20, got %+v", loc)
+	}
+
+	annotation := formatGitHubAnnotation(differences[0], "new.json")
+	if !strings.HasPrefix(annotation, "::warning file=new.json,line=1,col=7::") {
+		t.Errorf("Expected the annotation to carry the line and column, got %q", annotation)
+	}
+}
@@ -4,7 +4,8 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -23,49 +24,431 @@ func (s *stringSliceFlag) Set(value string) error {
 	return nil
 }
 
+// twoFileOutputFlags are the flags that filter, rewrite or export the
+// differences between two files, which archive and three-way comparisons
+// report in their own format
+var twoFileOutputFlags = map[string]bool{
+	"output-json": true, "json-version": true, "output-jsonl": true, "output-json-append": true,
+	"run-label": true, "output-jsondiffpatch": true, "output-merged": true, "output-sse": true,
+	"porcelain": true, "output-github": true, "baseline": true, "interactive": true,
+	"path-prefix": true, "index-base": true, "threshold-report": true, "show-promotions": true,
+	"show-array-matches": true, "char-diff": true, "detect-moves": true, "additions-only": true,
+	"removals-only": true, "structure-delta": true, "only-tag": true, "group-arrays-by-diff-type": true,
+	"head": true, "limit-output-bytes": true, "first-divergence": true, "similarity": true,
+	"max-runtime": true,
+}
+
+// Exit codes. Comparisons exit with exitIdentical or exitDifferent; errors
+// that can be told apart exit with their own code and any other error with 1.
+const (
+	exitIdentical   = 0
+	exitDifferent   = 1
+	exitFileRead    = 2
+	exitInvalidJSON = 3
+	exitBadPattern  = 4
+	exitEmptyDoc    = 5
+	exitTimeout     = 6
+)
+
+// exitCode returns the exit code for an error
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, ErrFileRead):
+		return exitFileRead
+	case errors.Is(err, ErrInvalidJSON):
+		return exitInvalidJSON
+	case errors.Is(err, ErrBadPattern):
+		return exitBadPattern
+	case errors.Is(err, ErrEmptyDocument):
+		return exitEmptyDoc
+	default:
+		return 1
+	}
+}
+
 func main() {
 	// Define flags
+	configPtr := flag.String("config", "", "Load comparison options from a YAML config file (flags override config values)")
 	concisePtr := flag.Bool("concise", false, "Show concise output")
 	quietPtr := flag.Bool("quiet", false, "Only show if files differ, no details")
-	outputJSONPtr := flag.String("output-json", "", "Write differences to a JSON file")
+	quietEqualPtr := flag.Bool("quiet-equal", false, "Don't print validation messages or that the files are identical, but still show differences")
+	outputJSONPtr := flag.String("output-json", "", "Write differences to a JSON file (use - for stdout)")
 	keysOnlyPtr := flag.Bool("keys-only", false, "Only compare keys, ignore values")
+	arrayTypeCheckPtr := flag.Bool("array-type-check", false, "Only compare the JSON types of array elements, ignore their values")
 	ignoreCasePtr := flag.Bool("ignore-case", false, "Ignore case when comparing keys")
+	ignoreWhitespaceKeysPtr := flag.Bool("ignore-whitespace-keys", false, "Ignore whitespace in keys when matching them (e.g., \"first name \" == \"firstname\")")
+	normalizeNumericKeysPtr := flag.Bool("normalize-numeric-keys", false, "Match keys written as numbers in canonical form (e.g., \"01\" == \"1\" == \"1.0\")")
+	reportCaseDiffsPtr := flag.Bool("report-case-diffs", false, "Match keys case-insensitively but report keys whose casing differs")
+	var keyStyleList stringSliceFlag
+	flag.Var(&keyStyleList, "normalize-key-style", "Normalize keys before matching them, with camel-to-snake (firstName == first_name) and/or singularize (items == item), comma-separated or repeated, applied in order")
 	ignoreCaseValuesPtr := flag.Bool("ignore-case-values", false, "Ignore case when comparing string values")
+	foldUnicodePtr := flag.Bool("fold-unicode", false, "Apply Unicode NFC normalization to string values and keys before comparing (e.g., composed == decomposed \"café\")")
+	collationPtr := flag.String("collation", "", "Treat string values that rank equal under this locale's collation as equal (e.g., de-DE)")
+	collationLoosePtr := flag.Bool("collation-loose", false, "With -collation, also ignore diacritics, case and width (e.g., \"ä\" == \"a\")")
 	ignoreNumericTypePtr := flag.Bool("ignore-numeric-type", false, "Ignore numeric types (e.g., 1 == \"1\" == \"1.0\" == 1.0)")
+	coerceLeftNumStringsPtr := flag.Bool("coerce-left-numeric-strings", false, "Compare numeric strings in the first file with numbers in the second by value (e.g., \"42\" == 42), but not numeric strings in the second file")
+	floatTolerancePtr := flag.Float64("float-tolerance", 0, "Maximum absolute difference for numbers to be considered equal (applies to numeric strings with -ignore-numeric-type)")
+	var numericRuleList stringSliceFlag
+	flag.Var(&numericRuleList, "numeric-rule", "Compare numbers at a specific key by their own rule instead of -float-tolerance (format: key:terms, terms are exact, roundN, absX and relX, comma-separated, e.g. lat:round4 or price:round2,abs0.01), can be specified multiple times")
+	parseGroupedNumbersPtr := flag.Bool("parse-grouped-numbers", false, "With -ignore-numeric-type, parse numeric strings with thousands separators (e.g., \"1,234.56\" == 1234.56)")
+	decimalSeparatorPtr := flag.String("decimal-separator", ".", "Decimal separator for -parse-grouped-numbers")
+	groupSeparatorPtr := flag.String("group-separator", ",", "Group separator for -parse-grouped-numbers (e.g., . with -decimal-separator , for 1.234,56)")
+	normalizeNumericStringsPtr := flag.Bool("normalize-numeric-strings", false, "Compare strings written as numbers in canonical form (e.g., \"1.50\" == \"1.5\" == \"15e-1\"), without making them equal to numbers")
 	ignoreBooleanTypePtr := flag.Bool("ignore-boolean-type", false, "Ignore boolean types (e.g., true == \"true\")")
+	numericBooleansPtr := flag.Bool("numeric-booleans", false, "With -ignore-boolean-type, also treat the numbers 1 and 0 as true and false")
 	ignoreNullValuesPtr := flag.Bool("ignore-null", false, "Ignore null values (e.g., \"Harry Potter\" == null)")
+	protoPtr := flag.Bool("proto", false, "Compare proto3 canonical JSON (string-encoded int64 == number, omitted field == default value)")
+	var protoEnumList stringSliceFlag
+	flag.Var(&protoEnumList, "proto-enum", "Treat enum names and numbers at a specific key as equal (format: key:NAME=number,...), can be specified multiple times")
+	coerceNumericObjectsPtr := flag.Bool("coerce-numeric-object-to-array", false, "Compare an object keyed by sequential indices (e.g. {\"0\": \"a\", \"1\": \"b\"}) as an array when the other file has an array there")
+	unwrapSingletonsPtr := flag.Bool("unwrap-singleton-arrays", false, "Compare a one-element array holding an object as that object when the other file has an object there")
+	wildcardValuePtr := flag.String("wildcard-value", "", "Accept any value in the second file where the first file has this string (e.g., <any>), as long as the key is present")
+	unwrapValueKeyPtr := flag.String("unwrap-value-key", "", "Compare any object containing this key as the value at the key, at every level (e.g., value for {\"value\": 1, \"updatedAt\": ...})")
+	deepTypeMismatchPtr := flag.Bool("deep-type-mismatch", false, "When an object or array meets a scalar, also list its keys or elements as only in one file after the type mismatch")
+	multiDocPtr := flag.Bool("multi-doc", false, "Read multiple concatenated JSON documents from each file and compare them pairwise")
+	xmlPtr := flag.Bool("xml", false, "Parse both files as XML (attributes as @name keys, text as #text) instead of JSON")
+	var xmlArrayList stringSliceFlag
+	flag.Var(&xmlArrayList, "xml-array", "With -xml, always map an element name to an array, even when it occurs once, can be specified multiple times")
+	normalizeNumbersPtr := flag.Bool("normalize-numbers", false, "Compare numbers by their exact value in a canonical text form (1e3 == 1000, 1.10 == 1.1) without float64 rounding")
+	expandEnvLeftPtr := flag.Bool("expand-env-left", false, "Replace ${VAR} and $VAR placeholders in the first file with environment variables before parsing")
+	expandEnvRightPtr := flag.Bool("expand-env-right", false, "Replace ${VAR} and $VAR placeholders in the second file with environment variables before parsing")
+	onMissingEnvPtr := flag.String("on-missing-env", MissingEnvKeep, "How placeholders naming unset variables are handled: keep (leave them as written) or error")
+	allowNonFinitePtr := flag.Bool("allow-nonfinite", false, "Accept the non-standard NaN, Infinity and -Infinity number literals (NaN never equals NaN; infinities are equal by sign)")
+	basePtr := flag.String("base", "", "Compare both files against this common ancestor and report changes made on either side, flagging conflicts")
+	archivePtr := flag.Bool("archive", false, "Compare two zip or tar archives entry by entry, pairing JSON entries by name (automatic for .zip, .tar, .tar.gz and .tgz files)")
+	onlyChangedFilesPtr := flag.Bool("only-changed-files", false, "With -archive, list only the names of entries that differ (M), were added (A), were removed (D) or could not be parsed (E)")
+	requireNonEmptyPtr := flag.Bool("require-nonempty", false, "Exit with status 5 if either file is null, an empty object or an empty array")
+	resolveRefsPtr := flag.Bool("resolve-refs", false, "Resolve local $ref pointers (#/definitions/item) before comparing")
+	resolveFileRefsPtr := flag.Bool("resolve-file-refs", false, "Resolve $ref pointers into other files (common.json#/item) as well as local ones, reading those files")
 	var regexMatchList stringSliceFlag
 	flag.Var(&regexMatchList, "regex-match", "Use regex matching on specific key (format: key:pattern), can be specified multiple times")
 	var levenshteinKeyList stringSliceFlag
 	flag.Var(&levenshteinKeyList, "levenshtein-key", "Apply Levenshtein distance matching on specific key, can be specified multiple times")
 	levenshteinThresholdPtr := flag.Int("levenshtein-threshold", 3, "Maximum Levenshtein distance to consider strings as equal (default: 3)")
+	var uuidKeyList stringSliceFlag
+	var currencyKeyList stringSliceFlag
+	flag.Var(&uuidKeyList, "uuid-key", "Compare values at a specific key as UUIDs, ignoring case, hyphens and braces (e.g., 550E8400E29B41D4A716446655440000 == 550e8400-e29b-41d4-a716-446655440000), can be specified multiple times")
+	flag.Var(&currencyKeyList, "currency-key", "Compare values at a specific key as currency amounts, ignoring currency symbols and grouping (e.g., \"$1,234.56\" == 1234.56), can be specified multiple times")
+	var semverKeyList stringSliceFlag
+	flag.Var(&semverKeyList, "semver-key", "Compare values at a specific key as semantic versions (e.g., 1.2 == 1.2.0), can be specified multiple times")
+	var unitKeyList stringSliceFlag
+	flag.Var(&unitKeyList, "unit-key", "Parse values at a specific key with units before comparing (format: key:unit, unit is bytes or si, e.g. size:bytes), can be specified multiple times")
+	var execComparatorList stringSliceFlag
+	flag.Var(&execComparatorList, "exec-comparator", "Let an external command decide whether values at a specific key are equal (format: key:command), can be specified multiple times")
+	maxRuntimePtr := flag.Duration("max-runtime", 0, "Stop comparing after this long and report the differences found so far as partial, exiting with status 6 (e.g., 5s; 0 for no limit)")
+	execTimeoutPtr := flag.Duration("exec-timeout", defaultExecTimeout, "Maximum time an -exec-comparator command may run before the values are treated as different")
+	redactValuesPtr := flag.Bool("redact-values", false, "Mask all values in the output, keeping only paths, difference types and value lengths")
+	redactPathPtr := flag.String("redact-path", "", "Comma-separated list of key names or paths whose values are masked in the output")
+	unwrapPtr := flag.String("unwrap", "", "Compare the value at this path in whichever file contains it (e.g., items to unwrap a paginated envelope)")
+	unwrapLeftPtr := flag.String("unwrap-left", "", "Compare the value at this path in the first file instead of the whole document")
+	unwrapRightPtr := flag.String("unwrap-right", "", "Compare the value at this path in the second file instead of the whole document")
+	baselinePtr := flag.String("baseline", "", "Ignore differences already accepted in this baseline file (JSON, as written by -output-json)")
+	interactivePtr := flag.Bool("interactive", false, "Step through differences one at a time, accepting them into the -baseline file")
+	watchFilePtr := flag.String("watch-file", "", "Compare only the paths listed in this file (one per line), reporting each as equal or differ")
+	pathPrefixPtr := flag.String("path-prefix", "", "Prefix prepended to every reported path (e.g., data.items[3])")
+	indexBasePtr := flag.Int("index-base", 0, "Base of the array indices in reported paths (0 or 1)")
+	thresholdReportPtr := flag.Int("threshold-report", 0, "Show the n fuzzy matches (-levenshtein-key, -float-tolerance) that came closest to their threshold")
+	printOptionsPtr := flag.Bool("print-options", false, "Print the effective comparison options (after merging the config file and flags) as JSON to stderr")
+	charDiffPtr := flag.Bool("char-diff", false, "Show an inline character-level diff for mismatched strings of 20 or more characters")
+	detectMovesPtr := flag.Bool("detect-moves", false, "Report a key only in the first file and a key only in the second file holding an equal value as a single move")
+	additionsOnlyPtr := flag.Bool("additions-only", false, "Only report keys and array elements present in the second file but not the first")
+	removalsOnlyPtr := flag.Bool("removals-only", false, "Only report keys and array elements present in the first file but not the second")
+	structureDeltaPtr := flag.Bool("structure-delta", false, "Only report keys added or removed anywhere in the tree, printed as + path / - path")
+	jsonVersionPtr := flag.Bool("json-version", false, "With -output-json, wrap the differences in an object with jsondiffVersion and formatVersion fields")
+	splitFilePtr := flag.String("split-file", "", "Read both documents from this one file, separated by whitespace or a --- line, instead of two file arguments")
+	versionPtr := flag.Bool("version", false, "Print the jsondiff version and exit")
+	outputJSONLPtr := flag.String("output-jsonl", "", "Write differences to a file as JSON Lines, one compact JSON object per difference (use - for stdout)")
+	outputJSONAppendPtr := flag.String("output-json-append", "", "Append this run's differences, labelled, to a JSON array in a file shared across runs")
+	runLabelPtr := flag.String("run-label", "", "Label for this run in the -output-json-append file (default: \"<file1> vs <file2>\")")
+	outputJSONDiffPatchPtr := flag.String("output-jsondiffpatch", "", "Write the changes as a jsondiffpatch delta to a JSON file (use - for stdout)")
+	outputMergedPtr := flag.String("output-merged", "", "Write the first file with the differences found applied to a JSON file (use - for stdout)")
+	outputSSEPtr := flag.Bool("output-sse", false, "Write differences to stdout as Server-Sent Events (one JSON-encoded diff per data: line) instead of the human-readable output")
+	porcelainPtr := flag.Bool("porcelain", false, "Print differences in a stable, tab-separated format for scripts (<code> <path>\\t<value1>\\t<value2>, code is M, A, D, T, L, R or X) instead of the human-readable output")
+	outputGitHubPtr := flag.Bool("output-github", false, "Print differences as GitHub Actions annotations (structural changes as errors, value changes as warnings)")
+	withLocationsPtr := flag.Bool("with-locations", false, "Record where each differing value starts in each file (byte offset, line and column) as loc1/loc2 in JSON output and as the line of -output-github annotations")
+	var severityList stringSliceFlag
+	flag.Var(&severityList, "severity", "Override the severity of differences at or under a path (format: path:severity, severity is info, warning, error or critical), can be specified multiple times")
+	var tagList stringSliceFlag
+	flag.Var(&tagList, "tag", "Tag differences at or under a path with a category for triage (format: path:tag, e.g. billing:financial), can be specified multiple times")
+	var ignorePathList stringSliceFlag
+	flag.Var(&ignorePathList, "ignore-path", "Don't report differences at or under paths matching this expression (e.g., sensitive.*, users[*].{name,email}, !public), can be specified multiple times")
+	var ignoreOrderList stringSliceFlag
+	flag.Var(&ignoreOrderList, "ignore-order-for", "Compare the arrays at paths matching this expression regardless of element order (e.g., tags, users[*].roles), can be specified multiple times")
+	showPromotionsPtr := flag.Bool("show-promotions", false, "List values that were only equal once converted to a common numeric type (e.g., 1 and \"1\" with -ignore-numeric-type)")
+	showArrayMatchesPtr := flag.Bool("show-array-matches", false, "List how the elements of arrays compared regardless of order were paired")
+	var onlyPathList stringSliceFlag
+	flag.Var(&onlyPathList, "only-path", "Only report differences at or under paths matching this expression, can be specified multiple times")
+	firstDivergencePtr := flag.Bool("first-divergence", false, "Print the line and column where the canonical JSON of the files first differs instead of the differences")
+	similarityPtr := flag.Bool("similarity", false, "Print a similarity score from 0.0 to 1.0, the weighted fraction of leaf values that match, instead of the differences")
+	var weightList stringSliceFlag
+	flag.Var(&weightList, "weight", "Weight of the leaf values under a path in the -similarity score (format: path:weight, e.g. id:5), can be specified multiple times")
+	var onlyTagList stringSliceFlag
+	flag.Var(&onlyTagList, "only-tag", "Only report differences with this tag, can be specified multiple times")
+	failOnSeverityPtr := flag.String("fail-on-severity", "", "Exit with status 1 only if a difference has at least this severity (info, warning, error or critical)")
+	groupArraysPtr := flag.Bool("group-arrays-by-diff-type", false, "Summarize the differences inside each array as one line of elements added, removed and changed, counted by type, instead of listing them")
+	headPtr := flag.Int("head", 0, "Show only the first n differences in detail, then a count of the rest by type (0 shows all)")
+	limitOutputBytesPtr := flag.Int("limit-output-bytes", 0, "Stop printing differences after n bytes of console output (0 for no limit); -output-json and the exit code are unaffected")
+	sampleArraysPtr := flag.Int("sample-arrays", 0, "Compare only n randomly chosen index-aligned elements of longer arrays (0 compares all)")
+	seedPtr := flag.Int64("seed", 0, "Seed for choosing the elements compared by -sample-arrays")
+	cacheSubtreesPtr := flag.Bool("cache-subtrees", false, "Compare repeated identical pairs of objects or arrays once and reuse the differences (ignored with path-scoped or fuzzy options)")
+	autoArrayKeyPtr := flag.Bool("auto-array-key", false, "Match elements of arrays of objects by an inferred identity field (unique in both arrays) instead of by position")
+	maxArrayDiffsPtr := flag.Int("max-array-diffs", 0, "Maximum differing elements to report per array before counting the rest (0 for no limit)")
+	arrayLengthTolerancePtr := flag.Int("array-length-tolerance", 0, "Only report array length differences greater than n elements (elements are still compared up to the shorter length)")
+	maxDepthPtr := flag.Int("max-depth", defaultMaxDepth, "Maximum nesting depth of objects and arrays to compare; deeper values are reported as not compared")
+	var ignoreExtraAtList stringSliceFlag
+	flag.Var(&ignoreExtraAtList, "ignore-extra-at", "Ignore keys only in the second file at a specific object path (use . for the root), can be specified multiple times")
+	ignoreDefaultExtrasPtr := flag.Bool("ignore-default-extras", false, "Ignore keys only in the second file whose value is a default (false, 0, \"\", [] or {}), as written by serializers that don't omit defaults")
+	defaultTypesPtr := flag.String("default-types", strings.Join(defaultTypeNames, ","), "With -ignore-default-extras, the comma-separated JSON types whose default value is ignored (boolean, number, string, array, object or null)")
+	var requiredList stringSliceFlag
+	var oneofList stringSliceFlag
+	flag.Var(&requiredList, "required", "Report keys of the object at a path that are missing from the second file as required_missing, a critical difference (format: path:key1,key2, use . for the root), can be specified multiple times")
+	flag.Var(&oneofList, "oneof", "Treat fields of the object at a path as alternatives of a oneof, reporting a change of the set field as one oneof_changed difference (format: path:field1,field2, use . for the root), can be specified multiple times")
+	var ignoreWhenList stringSliceFlag
+	flag.Var(&ignoreWhenList, "ignore-when", "Ignore a field while a sibling has a value in both files (format: field=value:path, e.g. status=cancelled:discount), can be specified multiple times")
+	var derivedList stringSliceFlag
+	flag.Var(&derivedList, "derived", "Ignore a field computed from sibling fields while those are equal in both files (format: field:source1,source2, e.g. fullName:first,last), can be specified multiple times")
+	var normalizeTypeList stringSliceFlag
+	flag.Var(&normalizeTypeList, "normalize-type", "Normalize every value of a JSON type before comparing (format: type:normalizer, e.g. string:lower, string:trim or number:round2), can be specified multiple times")
+	var ignoreKeyList stringSliceFlag
+	flag.Var(&ignoreKeyList, "ignore-key", "Ignore keys with this name at any depth (e.g., updatedAt), can be specified multiple times")
+	var aliasList stringSliceFlag
+	flag.Var(&aliasList, "alias", "Treat synonym key names in either file as one canonical key (format: canonical=alias[=alias...], e.g. zip=zipcode=postal_code), can be specified multiple times")
+	var dupKeyList stringSliceFlag
+	flag.Var(&dupKeyList, "detect-dup-keys", "Report elements sharing an identity value within an array of each file (format: path:key, use . for the root), can be specified multiple times")
+	var stripKeyPrefixList stringSliceFlag
+	flag.Var(&stripKeyPrefixList, "strip-key-prefix", "Remove a prefix from keys that start with it in one file, reporting them under the stripped name (format: left:prefix or right:prefix, e.g. left:app.), can be specified once per side")
+	var renameList stringSliceFlag
+	flag.Var(&renameList, "rename", "Treat a key in the first file as renamed (format: old:new, old may be a key name or path), can be specified multiple times")
 
 	// Parse flags
 	flag.Parse()
 
-	// Check if we have exactly two arguments after flags
+	// Print the version instead of comparing, as -version or a version subcommand
 	args := flag.Args()
-	if len(args) != 2 {
+	if *versionPtr || (len(args) == 1 && args[0] == "version") {
+		fmt.Printf("jsondiff %s (JSON format %d)\n", version, formatVersion)
+		os.Exit(0)
+	}
+
+	// Check if we have exactly two arguments after flags, or none when both documents are in a combined file
+	wantArgs := 2
+	if *splitFilePtr != "" {
+		wantArgs = 0
+	}
+	if len(args) != wantArgs {
 		fmt.Println("Usage: jsondiff [options] <file1.json> <file2.json>")
+		fmt.Println("       jsondiff [options] -split-file <combined.json>")
 		fmt.Println("Options:")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	file1Path := args[0]
-	file2Path := args[1]
+	file1Path, file2Path := *splitFilePtr, *splitFilePtr
+	if *splitFilePtr == "" {
+		file1Path = args[0]
+		file2Path = args[1]
+	}
+
+	// When streaming JSON to stdout, keep stdout free of human-readable output
+	jsonToStdout, machineOutput := stdoutOutputs(*outputJSONPtr, []string{*outputJSONLPtr, *outputJSONDiffPatchPtr, *outputMergedPtr}, *outputSSEPtr || *porcelainPtr)
+	// Messages fall in three groups: validation messages are silenced by
+	// -concise, messages that the files are equal also by -quiet-equal, and
+	// differences and reports only by -quiet, which silences everything
+	quiet := *quietPtr || machineOutput
+	quietEqual := *quietEqualPtr || quiet
+	concise := *concisePtr || quietEqual
+
+	if len(xmlArrayList) > 0 && !*xmlPtr {
+		fmt.Println("-xml-array can only be used with -xml")
+		os.Exit(1)
+	}
+	xmlArrays := make(map[string]bool)
+	for _, name := range xmlArrayList {
+		xmlArrays[name] = true
+	}
+
+	readOptions := ReadOptions{
+		Concise:          concise,
+		ResolveRefs:      *resolveRefsPtr,
+		ResolveFileRefs:  *resolveFileRefsPtr,
+		MultiDoc:         *multiDocPtr,
+		XML:              *xmlPtr,
+		XMLArrays:        xmlArrays,
+		AllowNonFinite:   *allowNonFinitePtr,
+		NormalizeNumbers: *normalizeNumbersPtr,
+		RecordLocations:  *withLocationsPtr,
+	}
 
-	// Read and validate first JSON file
-	jsonFile1, err := ReadAndValidateJSON(file1Path, *concisePtr)
-	if err != nil {
-		fmt.Printf("Error with first file: %v\n", err)
+	// Environment variables are only expanded in the files asked for
+	if *onMissingEnvPtr != MissingEnvKeep && *onMissingEnvPtr != MissingEnvError {
+		fmt.Printf("-on-missing-env must be %s or %s\n", MissingEnvKeep, MissingEnvError)
 		os.Exit(1)
 	}
+	readOptions1, readOptions2 := readOptions, readOptions
+	readOptions1.ExpandEnv, readOptions2.ExpandEnv = *expandEnvLeftPtr, *expandEnvRightPtr
+	readOptions1.OnMissingEnv, readOptions2.OnMissingEnv = *onMissingEnvPtr, *onMissingEnvPtr
 
-	// Read and validate second JSON file
-	jsonFile2, err := ReadAndValidateJSON(file2Path, *concisePtr)
-	if err != nil {
-		fmt.Printf("Error with second file: %v\n", err)
+	// Archives are read whole here and their entries parsed once options are known
+	archiveMode := *archivePtr || (isArchivePath(file1Path) && isArchivePath(file2Path))
+	if archiveMode && *splitFilePtr != "" {
+		fmt.Println("-split-file cannot be combined with archive comparison")
+		os.Exit(1)
+	}
+	if archiveMode && *resolveFileRefsPtr {
+		fmt.Println("-resolve-file-refs cannot be combined with archive comparison")
+		os.Exit(1)
+	}
+	if archiveMode && (*expandEnvLeftPtr || *expandEnvRightPtr) {
+		fmt.Println("-expand-env-left and -expand-env-right cannot be combined with archive comparison")
+		os.Exit(1)
+	}
+	if archiveMode && (*unwrapPtr != "" || *unwrapLeftPtr != "" || *unwrapRightPtr != "" || *watchFilePtr != "" || len(dupKeyList) > 0) {
+		fmt.Println("Unwrap options, -watch-file and -detect-dup-keys cannot be combined with archive comparison")
+		os.Exit(1)
+	}
+
+	// Locations are only known for values read straight from a file's own text
+	if *withLocationsPtr && (archiveMode || *xmlPtr || *multiDocPtr || *splitFilePtr != "" || *allowNonFinitePtr || *expandEnvLeftPtr || *expandEnvRightPtr ||
+		*unwrapPtr != "" || *unwrapLeftPtr != "" || *unwrapRightPtr != "") {
+		fmt.Println("-with-locations cannot be combined with archives, -xml, -multi-doc, -split-file, -allow-nonfinite, -expand-env-left/-right or unwrap options")
+		os.Exit(1)
+	}
+
+	if *basePtr != "" && (archiveMode || *multiDocPtr || *watchFilePtr != "" || *unwrapPtr != "" || *unwrapLeftPtr != "" || *unwrapRightPtr != "") {
+		fmt.Println("-base cannot be combined with archives, -multi-doc, -watch-file or unwrap options")
+		os.Exit(1)
+	}
+
+	// Archives and three-way comparisons print their own report; the options
+	// that filter, rewrite or export the differences of two files don't apply.
+	// Only conflicts fail a three-way comparison, whatever their severity.
+	if archiveMode || *basePtr != "" {
+		var unsupported []string
+		flag.Visit(func(f *flag.Flag) {
+			if twoFileOutputFlags[f.Name] || (*basePtr != "" && f.Name == "fail-on-severity") {
+				unsupported = append(unsupported, "-"+f.Name)
+			}
+		})
+		if len(unsupported) > 0 {
+			fmt.Printf("%s cannot be combined with archive or three-way comparison\n", strings.Join(unsupported, ", "))
+			os.Exit(1)
+		}
+	}
+
+	var jsonFile1, jsonFile2 *JSONFile
+	var archive1, archive2 map[string][]byte
+	var err error
+	if archiveMode {
+		archive1, err = readArchive(file1Path, *xmlPtr)
+		if err != nil {
+			fmt.Printf("Error with first archive: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		archive2, err = readArchive(file2Path, *xmlPtr)
+		if err != nil {
+			fmt.Printf("Error with second archive: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+		if !concise {
+			fmt.Printf("Read %d entries from %s and %d entries from %s\n", len(archive1), file1Path, len(archive2), file2Path)
+		}
+	} else if *splitFilePtr != "" {
+		// Read and validate both documents of the combined file
+		jsonFile1, jsonFile2, err = readSplitJSON(*splitFilePtr, readOptions1, readOptions2)
+		if err != nil {
+			fmt.Printf("Error with combined file: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+	} else {
+		// Read and validate first JSON file
+		jsonFile1, err = readAndValidateJSONWithOptions(file1Path, readOptions1)
+		if err != nil {
+			fmt.Printf("Error with first file: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+
+		// Read and validate second JSON file
+		jsonFile2, err = readAndValidateJSONWithOptions(file2Path, readOptions2)
+		if err != nil {
+			fmt.Printf("Error with second file: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+	}
+
+	// Guard against truncated downloads that parse as an empty document
+	if !archiveMode && *requireNonEmptyPtr {
+		for i, file := range []*JSONFile{jsonFile1, jsonFile2} {
+			if err := checkNonEmpty(file); err != nil {
+				fmt.Printf("Error with %s file: %v\n", []string{"first", "second"}[i], err)
+				os.Exit(exitCode(err))
+			}
+		}
+	}
+
+	// Read and validate the common ancestor for a three-way comparison
+	var baseFile *JSONFile
+	if *basePtr != "" {
+		baseFile, err = readAndValidateJSONWithOptions(*basePtr, readOptions)
+		if err != nil {
+			fmt.Printf("Error with base file: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+	}
+
+	// Unwrap envelopes so the compared values line up
+	if (*unwrapPtr != "" || *unwrapLeftPtr != "" || *unwrapRightPtr != "") && *multiDocPtr {
+		fmt.Println("Unwrap options cannot be combined with -multi-doc")
+		os.Exit(1)
+	}
+	if *watchFilePtr != "" && *multiDocPtr {
+		fmt.Println("-watch-file cannot be combined with -multi-doc")
+		os.Exit(1)
+	}
+	if *unwrapPtr != "" {
+		jsonFile1.Data, jsonFile2.Data, err = unwrapEither(jsonFile1.Data, jsonFile2.Data, *unwrapPtr)
+		if err != nil {
+			fmt.Printf("Error unwrapping: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *unwrapLeftPtr != "" {
+		jsonFile1.Data, err = unwrapDocument(jsonFile1.Data, *unwrapLeftPtr)
+		if err != nil {
+			fmt.Printf("Error unwrapping first file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *unwrapRightPtr != "" {
+		jsonFile2.Data, err = unwrapDocument(jsonFile2.Data, *unwrapRightPtr)
+		if err != nil {
+			fmt.Printf("Error unwrapping second file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *arrayLengthTolerancePtr < 0 {
+		fmt.Println("-array-length-tolerance must not be negative")
+		os.Exit(1)
+	}
+	if *maxDepthPtr < 0 {
+		fmt.Println("-max-depth must not be negative")
+		os.Exit(1)
+	}
+
+	if *collationPtr != "" {
+		if _, err := parseCollation(*collationPtr); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if *indexBasePtr != 0 && *indexBasePtr != 1 {
+		fmt.Println("-index-base must be 0 or 1")
+		os.Exit(1)
+	}
+
+	if *parseGroupedNumbersPtr && (*decimalSeparatorPtr == "" || *decimalSeparatorPtr == *groupSeparatorPtr) {
+		fmt.Println("The decimal separator must be set and differ from the group separator")
 		os.Exit(1)
 	}
 
@@ -83,6 +466,11 @@ func main() {
 				fmt.Printf("Warning: Duplicate regex match key '%s'. Only the last pattern will be used.\n", key)
 			}
 
+			if _, err := compileRegex(pattern); err != nil {
+				fmt.Printf("Error with regex match for %s: %v\n", key, err)
+				os.Exit(exitCode(err))
+			}
+
 			regexMatches[key] = pattern
 		} else {
 			fmt.Println("Invalid regex match format. Expected format: key:pattern")
@@ -96,55 +484,856 @@ func main() {
 		levenshteinKeys[key] = true
 	}
 
+	// Parse semantic version keys
+	semverKeys := make(map[string]bool)
+	for _, key := range semverKeyList {
+		semverKeys[key] = true
+	}
+
+	// Parse UUID keys
+	uuidKeys := make(map[string]bool)
+	for _, key := range uuidKeyList {
+		uuidKeys[key] = true
+	}
+
+	// Parse currency keys
+	currencyKeys := make(map[string]bool)
+	for _, key := range currencyKeyList {
+		currencyKeys[key] = true
+	}
+
+	// Parse numeric rules
+	numericRules := make(map[string]NumericRule)
+	for _, spec := range numericRuleList {
+		path, rule, err := parseNumericRule(spec)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		numericRules[path] = rule
+	}
+
+	// Parse unit keys
+	unitKeys := make(map[string]string)
+	for _, unitKey := range unitKeyList {
+		key, unit, ok := cutLast(unitKey, ":")
+		if !ok || key == "" || !validUnit(unit) {
+			fmt.Println("Invalid unit key format. Expected format: key:unit, where unit is bytes or si")
+			os.Exit(1)
+		}
+		unitKeys[key] = unit
+	}
+
+	// Parse external comparators
+	execComparators := make(map[string]string)
+	for _, execComparator := range execComparatorList {
+		parts := strings.SplitN(execComparator, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || strings.TrimSpace(parts[1]) == "" {
+			fmt.Println("Invalid exec comparator format. Expected format: key:command")
+			os.Exit(1)
+		}
+		execComparators[parts[0]] = parts[1]
+	}
+
+	// Parse enum mappings
+	enumValues := make(map[string]map[string]float64)
+	for _, spec := range protoEnumList {
+		path, values, err := parseEnumValues(spec)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		enumValues[path] = values
+	}
+
+	// Parse key prefixes to strip
+	var stripKeyPrefixLeft, stripKeyPrefixRight string
+	for _, spec := range stripKeyPrefixList {
+		side, prefix, err := parseStripKeyPrefix(spec)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if side == "left" {
+			stripKeyPrefixLeft = prefix
+		} else {
+			stripKeyPrefixRight = prefix
+		}
+	}
+
+	// Parse key renames
+	renameKeys := make(map[string]string)
+	for _, rename := range renameList {
+		parts := strings.SplitN(rename, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			fmt.Println("Invalid rename format. Expected format: old:new")
+			os.Exit(1)
+		}
+		renameKeys[parts[0]] = parts[1]
+	}
+	if err := checkRenameTargets(renameKeys); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Parse key alias groups
+	keyAliases := make(map[string]string)
+	for _, group := range aliasList {
+		aliases, err := parseAliasGroup(group)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		for alias, canonical := range aliases {
+			keyAliases[alias] = canonical
+		}
+	}
+
+	// Parse severity overrides
+	severityOverrides := make(map[string]Severity)
+	for _, override := range severityList {
+		path, name, ok := cutLast(override, ":")
+		severity, err := parseSeverity(name)
+		if !ok || err != nil {
+			fmt.Println("Invalid severity format. Expected format: path:severity, where severity is info, warning, error or critical")
+			os.Exit(1)
+		}
+		if path == "." {
+			path = ""
+		}
+		severityOverrides[path] = severity
+	}
+
+	// Parse similarity weights
+	weights := make(map[string]float64)
+	for _, rule := range weightList {
+		path, weight, err := parseWeight(rule)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		weights[path] = weight
+	}
+
+	// Validate path expressions
+	for _, expr := range append(append(append([]string(nil), ignorePathList...), onlyPathList...), ignoreOrderList...) {
+		if err := validatePathExpr(expr); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// Parse path tags
+	pathTags := make(map[string][]string)
+	for _, rule := range tagList {
+		path, tag, err := parseTagRule(rule)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		pathTags[path] = append(pathTags[path], tag)
+	}
+
+	failOnSeverity := SeverityInfo
+	if *failOnSeverityPtr != "" {
+		failOnSeverity, err = parseSeverity(*failOnSeverityPtr)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// Parse type normalizers
+	typeNormalizers := make(map[string]Normalizer)
+	for _, spec := range normalizeTypeList {
+		if err := addTypeNormalizer(typeNormalizers, spec); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// Parse required keys
+	requiredKeys := make(map[string]map[string]bool)
+	for _, spec := range requiredList {
+		if err := addRequiredKeys(requiredKeys, spec); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// Parse oneof groups
+	oneofGroups := make(map[string][][]string)
+	for _, spec := range oneofList {
+		if err := addOneof(oneofGroups, spec); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// Parse open object paths
+	ignoreExtraAt := make(map[string]bool)
+	for _, objPath := range ignoreExtraAtList {
+		if objPath == "." {
+			objPath = ""
+		}
+		ignoreExtraAt[objPath] = true
+	}
+
+	// Parse the types whose default values may be extra
+	var defaultExtraTypes map[string]bool
+	if *ignoreDefaultExtrasPtr {
+		defaultExtraTypes, err = parseDefaultTypes(strings.Split(*defaultTypesPtr, ","))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// Parse key style transformations
+	keyStyles, err := parseKeyStyles(keyStyleList)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Parse conditional ignore rules
+	var ignoreWhen []ConditionalIgnore
+	for _, rule := range ignoreWhenList {
+		parsed, err := parseConditionalIgnore(rule)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		ignoreWhen = append(ignoreWhen, parsed)
+	}
+
+	// Parse derived field hints
+	var derivedFields []DerivedField
+	for _, spec := range derivedList {
+		parsed, err := parseDerivedField(spec)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		derivedFields = append(derivedFields, parsed)
+	}
+
+	// Parse duplicate key checks
+	var dupKeyChecks []DuplicateKeyCheck
+	for _, spec := range dupKeyList {
+		check, err := parseDuplicateKeyCheck(spec)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		dupKeyChecks = append(dupKeyChecks, check)
+	}
+
+	// Build comparison options
+	options := CompareOptions{
+		IgnoreCase:           *ignoreCasePtr,
+		IgnoreWhitespaceKeys: *ignoreWhitespaceKeysPtr,
+		NormalizeNumericKeys: *normalizeNumericKeysPtr,
+		KeyStyles:            keyStyles,
+		IgnoreCaseValues:     *ignoreCaseValuesPtr,
+		ReportCaseDiffs:      *reportCaseDiffsPtr,
+		FoldUnicode:          *foldUnicodePtr,
+		Collation:            *collationPtr,
+		CollationLoose:       *collationLoosePtr,
+		IgnoreNumericType:    *ignoreNumericTypePtr,
+		CoerceLeftNumStrings: *coerceLeftNumStringsPtr,
+		FloatTolerance:       *floatTolerancePtr,
+		NumericRules:         numericRules,
+		ParseGroupedNumbers:  *parseGroupedNumbersPtr,
+		DecimalSeparator:     *decimalSeparatorPtr,
+		GroupSeparator:       *groupSeparatorPtr,
+		NormalizeNumStrings:  *normalizeNumericStringsPtr,
+		IgnoreBooleanType:    *ignoreBooleanTypePtr,
+		NumericBooleans:      *numericBooleansPtr,
+		IgnoreNullValues:     *ignoreNullValuesPtr,
+		EnumValues:           enumValues,
+		CoerceNumericObjects: *coerceNumericObjectsPtr,
+		UnwrapSingletons:     *unwrapSingletonsPtr,
+		UnwrapValueKey:       *unwrapValueKeyPtr,
+		WildcardValue:        *wildcardValuePtr,
+		DeepTypeMismatch:     *deepTypeMismatchPtr,
+		KeysOnly:             *keysOnlyPtr,
+		ArrayTypeCheck:       *arrayTypeCheckPtr,
+		RegexMatches:         regexMatches,
+		LevenshteinKeys:      levenshteinKeys,
+		LevenshteinThreshold: *levenshteinThresholdPtr,
+		SemverKeys:           semverKeys,
+		UUIDKeys:             uuidKeys,
+		CurrencyKeys:         currencyKeys,
+		UnitKeys:             unitKeys,
+		ExecComparators:      execComparators,
+		ExecTimeout:          *execTimeoutPtr,
+		TypeNormalizers:      typeNormalizers,
+		IgnoreKeyNames:       ignoreKeyList,
+		RenameKeys:           renameKeys,
+		StripKeyPrefixLeft:   stripKeyPrefixLeft,
+		StripKeyPrefixRight:  stripKeyPrefixRight,
+		KeyAliases:           keyAliases,
+		SampleArrays:         *sampleArraysPtr,
+		SampleSeed:           *seedPtr,
+		AutoArrayKey:         *autoArrayKeyPtr,
+		CacheSubtrees:        *cacheSubtreesPtr,
+		SeverityOverrides:    severityOverrides,
+		PathTags:             pathTags,
+		IgnorePaths:          ignorePathList,
+		OnlyPaths:            onlyPathList,
+		IgnoreOrderPaths:     ignoreOrderList,
+		Weights:              weights,
+		MaxArrayDiffs:        *maxArrayDiffsPtr,
+		MaxDepth:             *maxDepthPtr,
+		ArrayLengthTolerance: *arrayLengthTolerancePtr,
+		RequiredKeys:         requiredKeys,
+		OneofGroups:          oneofGroups,
+		IgnoreExtraAt:        ignoreExtraAt,
+		DefaultExtraTypes:    defaultExtraTypes,
+		IgnoreWhen:           ignoreWhen,
+		DerivedFields:        derivedFields,
+	}
+
+	if *protoPtr {
+		options = applyProtoPreset(options)
+	}
+
+	// Merge in the config file, letting explicitly set flags take precedence
+	if *configPtr != "" {
+		config, err := LoadConfig(*configPtr)
+		if err != nil {
+			fmt.Printf("Error with config file: %v\n", err)
+			os.Exit(exitCode(err))
+		}
+
+		setFlags := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) {
+			setFlags[f.Name] = true
+		})
+		options = config.mergeFlags(options, setFlags)
+		if err := checkRenameTargets(options.RenameKeys); err != nil {
+			fmt.Printf("Error with config file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Show which options took effect
+	if *printOptionsPtr {
+		optionsJSON, err := marshalOptions(options)
+		if err != nil {
+			fmt.Printf("Error marshaling options to JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, string(optionsJSON))
+	}
+
+	// Record fuzzy matches for the threshold report
+	var fuzzyMatches []FuzzyMatch
+	if *thresholdReportPtr > 0 {
+		options.FuzzyMatches = &fuzzyMatches
+	}
+
+	// Record values equal only as numbers if asked to show them
+	var promotions []Promotion
+	if *showPromotionsPtr {
+		options.Promotions = &promotions
+	}
+
+	// Record sampled arrays so the report says what wasn't compared
+	var arraySamples []ArraySample
+	options.SampledArrays = &arraySamples
+
+	// Record the keys inferred to match arrays so the report says how elements were paired
+	var arrayKeys []ArrayKeyChoice
+	options.ArrayKeys = &arrayKeys
+
+	// Record how unordered arrays were paired if asked to show it
+	var arrayMatches []ArrayMatch
+	if *showArrayMatchesPtr {
+		options.ArrayMatches = &arrayMatches
+	}
+
+	// Values are masked in every output, all of them with -redact-values
+	redacting := *redactValuesPtr || *redactPathPtr != ""
+	var redactFields []string
+	if !*redactValuesPtr && *redactPathPtr != "" {
+		redactFields = strings.Split(*redactPathPtr, ",")
+	}
+
+	// Compare archives entry by entry, with a header per differing entry
+	if archiveMode {
+		results := compareArchives(archive1, archive2, readOptions, options)
+		if redacting {
+			results = redactArchiveEntries(results, redactFields)
+		}
+		failed := false
+		for _, result := range results {
+			if archiveEntryFails(result, failOnSeverity) {
+				failed = true
+			}
+		}
+
+		if !quiet {
+			var report strings.Builder
+			for _, result := range results {
+				if *onlyChangedFilesPtr {
+					report.WriteString(formatChangedEntry(result))
+				} else {
+					report.WriteString(formatArchiveEntry(result))
+				}
+			}
+			if report.Len() == 0 {
+				if !quietEqual {
+					fmt.Println("The archives are identical.")
+				}
+			} else if *onlyChangedFilesPtr {
+				fmt.Println("The archives are different.")
+				fmt.Print("\nChanged entries:\n" + report.String())
+			} else {
+				fmt.Println("The archives are different.")
+				fmt.Print("\nDifferences found:\n" + report.String())
+			}
+		}
+
+		if failed {
+			os.Exit(exitDifferent)
+		}
+		os.Exit(exitIdentical)
+	}
+
+	// Correlate the changes each side made to the base; only conflicts fail
+	if baseFile != nil {
+		results := compareThreeWay(baseFile.Data, jsonFile1.Data, jsonFile2.Data, options)
+		conflicts := countConflicts(results)
+		if redacting {
+			results = redactThreeWay(results, redactFields)
+		}
+
+		if !quiet {
+			if len(results) == 0 {
+				if !quietEqual {
+					fmt.Println("Neither file changed the base.")
+				}
+			} else {
+				fmt.Printf("%d changes from the base, %d conflicting.\n", len(results), conflicts)
+				fmt.Println("\nChanges found:")
+				for _, result := range results {
+					fmt.Print(formatThreeWayDiff(result))
+				}
+			}
+		}
+
+		if conflicts > 0 {
+			os.Exit(exitDifferent)
+		}
+		os.Exit(exitIdentical)
+	}
+
+	// Find where the canonical texts start to differ instead of listing differences
+	if *firstDivergencePtr {
+		data1, data2 := jsonFile1.Data, jsonFile2.Data
+		if *multiDocPtr {
+			data1, data2 = jsonFile1.Documents, jsonFile2.Documents
+		}
+		text1, err1 := canonicalJSON(data1)
+		text2, err2 := canonicalJSON(data2)
+		if err := errors.Join(err1, err2); err != nil {
+			fmt.Printf("Error canonicalizing JSON: %v\n", err)
+			os.Exit(1)
+		}
+		div, diverged := firstDivergence(text1, text2)
+		if !diverged {
+			if !quietEqual {
+				fmt.Println("The canonical JSON of the files is identical.")
+			}
+			os.Exit(exitIdentical)
+		}
+		if !quiet {
+			fmt.Print(formatDivergence(div, text1, text2))
+		}
+		os.Exit(exitDifferent)
+	}
+
+	// Score how alike the files are instead of listing differences
+	if *similarityPtr {
+		data1, data2 := jsonFile1.Data, jsonFile2.Data
+		if *multiDocPtr {
+			data1, data2 = jsonFile1.Documents, jsonFile2.Documents
+		}
+		fmt.Printf("%.4f\n", SimilarityScore(data1, data2, options))
+		os.Exit(exitIdentical)
+	}
+
+	// Cut the comparison short at the deadline, keeping what was found by then
+	if *maxRuntimePtr > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), *maxRuntimePtr)
+		defer cancel()
+		options.Context = ctx
+	}
+
 	// Get differences based on options
-	differences := FindDifferences(jsonFile1.Data, jsonFile2.Data, "", *ignoreCasePtr, *ignoreCaseValuesPtr, *ignoreNumericTypePtr, *ignoreBooleanTypePtr, *ignoreNullValuesPtr, *keysOnlyPtr, regexMatches, levenshteinKeys, *levenshteinThresholdPtr)
-	
+	var result Result
+	var watchedPaths []string
+	if *watchFilePtr != "" {
+		watchedPaths, err = loadWatchFile(*watchFilePtr)
+		if err != nil {
+			fmt.Printf("Error with watch file: %v\n", err)
+			os.Exit(1)
+		}
+		result = newResult(compareWatchedPaths(jsonFile1.Data, jsonFile2.Data, watchedPaths, options), options)
+	} else if *multiDocPtr {
+		result = newResult(compareDocuments(jsonFile1.Documents, jsonFile2.Documents, options), options)
+	} else {
+		result = Compare(jsonFile1.Data, jsonFile2.Data, options)
+	}
+	differences := result.Diffs
+
+	// Pair removed and added keys holding the same value
+	if *detectMovesPtr {
+		differences = detectMoves(differences, options)
+	}
+
+	// Point each difference at its source, while paths are as the comparison reported them
+	if *withLocationsPtr {
+		differences = addLocations(differences, jsonFile1.Locations, jsonFile2.Locations)
+	}
+
+	// Keep only keys that appeared or disappeared
+	if *structureDeltaPtr {
+		differences = filterPresenceDiffs(differences)
+	}
+
+	// Keep only what the second file adds
+	if *additionsOnlyPtr {
+		differences = filterAdditions(differences)
+	}
+
+	// Keep only what the second file drops
+	if *removalsOnlyPtr {
+		differences = filterRemovals(differences)
+	}
+
+	// Keep only the tagged categories asked for
+	if len(onlyTagList) > 0 {
+		differences = filterTags(differences, onlyTagList)
+	}
+
+	// Apply the differences to the first file while their paths and values are unchanged
+	var merged interface{}
+	if *outputMergedPtr != "" {
+		data1, data2 := jsonFile1.Data, jsonFile2.Data
+		if *multiDocPtr {
+			// Document paths start with doc[n]
+			data1 = map[string]interface{}{"doc": jsonFile1.Documents}
+			data2 = map[string]interface{}{"doc": jsonFile2.Documents}
+		}
+		merged, err = ApplyDiffs(data1, data2, differences)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error merging documents: %v\n", err)
+			os.Exit(1)
+		}
+		if *multiDocPtr {
+			merged = merged.(map[string]interface{})["doc"]
+		}
+		if redacting {
+			merged = redactDocument(merged, "", redactFields)
+		}
+	}
+
+	// Mask values before any output is produced; the comparison above used the real values
+	if redacting {
+		differences = redactDifferences(differences, redactFields)
+		fuzzyMatches = redactFuzzyMatches(fuzzyMatches, redactFields)
+		promotions = redactPromotions(promotions, redactFields)
+	}
+
+	// Number array indices from the requested base; the prefix below is used as given
+	if *indexBasePtr != 0 {
+		differences = applyIndexBase(differences, *indexBasePtr)
+		for i := range fuzzyMatches {
+			fuzzyMatches[i].Path = rebaseIndices(fuzzyMatches[i].Path, *indexBasePtr)
+		}
+		for i := range promotions {
+			promotions[i].Path = rebaseIndices(promotions[i].Path, *indexBasePtr)
+		}
+		for i := range arraySamples {
+			arraySamples[i].Path = rebaseIndices(arraySamples[i].Path, *indexBasePtr)
+		}
+		for i := range arrayKeys {
+			arrayKeys[i].Path = rebaseIndices(arrayKeys[i].Path, *indexBasePtr)
+		}
+		for i := range arrayMatches {
+			arrayMatches[i].Path = rebaseIndices(arrayMatches[i].Path, *indexBasePtr)
+		}
+		for i := range watchedPaths {
+			watchedPaths[i] = rebaseIndices(watchedPaths[i], *indexBasePtr)
+		}
+	}
+
+	// Report paths relative to the parent document if requested
+	if *pathPrefixPtr != "" {
+		differences = applyPathPrefix(differences, *pathPrefixPtr)
+		fuzzyMatches = prefixFuzzyMatches(fuzzyMatches, *pathPrefixPtr)
+		promotions = prefixPromotions(promotions, *pathPrefixPtr)
+		arraySamples = prefixArraySamples(arraySamples, *pathPrefixPtr)
+		arrayKeys = prefixArrayKeyChoices(arrayKeys, *pathPrefixPtr)
+		arrayMatches = prefixArrayMatches(arrayMatches, *pathPrefixPtr)
+		for i := range watchedPaths {
+			watchedPaths[i] = prefixPath(*pathPrefixPtr, watchedPaths[i])
+		}
+	}
+
+	// Drop differences already accepted in the baseline
+	if *baselinePtr != "" {
+		baseline, err := loadBaseline(*baselinePtr)
+		if err != nil {
+			fmt.Printf("Error with baseline file: %v\n", err)
+			os.Exit(1)
+		}
+		differences = filterBaseline(differences, baseline)
+	}
+	result = result.withDiffs(differences)
+
 	// Write differences to JSON file if requested
 	if *outputJSONPtr != "" {
-		outputJSON, err := json.MarshalIndent(differences, "", "  ")
+		marshal, write := marshalDifferences, writeDifferencesJSON
+		if *jsonVersionPtr {
+			marshal, write = marshalVersionedDifferences, writeVersionedDifferencesJSON
+		}
+		if jsonToStdout {
+			outputJSON, err := marshal(differences)
+			if err != nil {
+				fmt.Printf("Error marshaling differences to JSON: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Println(string(outputJSON))
+			if !*quietPtr {
+				fmt.Fprintln(os.Stderr, "Differences written to stdout")
+			}
+		} else {
+			err = write(differences, *outputJSONPtr)
+			if err != nil {
+				fmt.Printf("Error writing differences to file: %v\n", err)
+				os.Exit(1)
+			}
+
+			if !quiet {
+				fmt.Printf("Differences written to %s\n", *outputJSONPtr)
+			}
+		}
+	}
+
+	// Write one compact JSON object per difference
+	if *outputJSONLPtr != "" {
+		file := os.Stdout
+		if *outputJSONLPtr != "-" {
+			file, err = os.Create(*outputJSONLPtr)
+			if err != nil {
+				fmt.Printf("Error writing differences to file: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		err = writeDifferencesJSONL(file, differences)
+		if file != os.Stdout {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
 		if err != nil {
-			fmt.Printf("Error marshaling differences to JSON: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error writing differences: %v\n", err)
 			os.Exit(1)
 		}
-		
-		err = os.WriteFile(*outputJSONPtr, outputJSON, 0644)
-		if err != nil {
-			fmt.Printf("Error writing differences to file: %v\n", err)
+		if !quiet {
+			fmt.Printf("Differences written to %s\n", *outputJSONLPtr)
+		}
+	}
+
+	// Accumulate differences from repeated runs in one file
+	if *outputJSONAppendPtr != "" {
+		label := *runLabelPtr
+		if label == "" {
+			label = file1Path + " vs " + file2Path
+		}
+		if err := appendDifferencesJSON(AppendedRun{Label: label, Differences: differences}, *outputJSONAppendPtr); err != nil {
+			fmt.Printf("Error appending differences to file: %v\n", err)
+			os.Exit(1)
+		}
+		if !quiet {
+			fmt.Printf("Differences appended to %s\n", *outputJSONAppendPtr)
+		}
+	}
+
+	// Write the jsondiffpatch delta, built from the documents rather than the differences
+	if *outputJSONDiffPatchPtr != "" {
+		var delta interface{}
+		var changed bool
+		if *multiDocPtr {
+			delta, changed = jsonDiffPatchDocuments(jsonFile1.Documents, jsonFile2.Documents, options)
+		} else {
+			delta, changed = jsonDiffPatch(jsonFile1.Data, jsonFile2.Data, options)
+		}
+		if redacting {
+			delta = redactJSONDiffPatch(delta, "", redactFields)
+		}
+		if err := writeJSONDiffPatch(delta, changed, *outputJSONDiffPatchPtr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing jsondiffpatch delta: %v\n", err)
 			os.Exit(1)
 		}
-		
-		if !*quietPtr {
-			fmt.Printf("Differences written to %s\n", *outputJSONPtr)
+		if !quiet {
+			fmt.Printf("Delta written to %s\n", *outputJSONDiffPatchPtr)
+		}
+	}
+
+	// Write the first file with the differences applied
+	if *outputMergedPtr != "" {
+		if err := writeMergedJSON(merged, *outputMergedPtr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing merged document: %v\n", err)
+			os.Exit(1)
+		}
+		if !quiet {
+			fmt.Printf("Merged document written to %s\n", *outputMergedPtr)
+		}
+	}
+
+	// Stream differences as Server-Sent Events; other stdout output is suppressed
+	if *outputSSEPtr {
+		if err := writeDifferencesSSE(os.Stdout, differences, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing differences: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Print the stable scripting format; other stdout output is suppressed
+	if *porcelainPtr {
+		for _, diff := range differences {
+			line, err := formatPorcelain(diff)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error formatting differences: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(line)
+		}
+	}
+
+	// Show the status of every watched path
+	if *watchFilePtr != "" && !quiet {
+		fmt.Print(formatWatchReport(watchedPaths, differences))
+	}
+
+	// Show data-quality warnings for duplicate identity values; these don't affect the exit code
+	if len(dupKeyChecks) > 0 && !quiet {
+		for i, file := range []*JSONFile{jsonFile1, jsonFile2} {
+			duplicates, err := checkDuplicateKeys(file, dupKeyChecks, *pathPrefixPtr, *indexBasePtr)
+			if err != nil {
+				fmt.Printf("Error checking duplicate keys: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(formatDuplicateKeys([]string{"first file", "second file"}[i], duplicates))
 		}
 	}
 
+	// Show which arrays were only sampled
+	if !quiet {
+		fmt.Print(formatSampleReport(arraySamples))
+		fmt.Print(formatArrayKeyReport(arrayKeys))
+		fmt.Print(formatArrayMatchReport(arrayMatches, *indexBasePtr))
+		fmt.Print(formatPromotionReport(promotions))
+	}
+
+	// Show the fuzzy matches that came closest to failing
+	if *thresholdReportPtr > 0 && !quiet {
+		fmt.Print(formatThresholdReport(fuzzyMatches, *thresholdReportPtr))
+	}
+
+	// A comparison cut short exits with its own status, whatever it found
+	exit := func(code int) {
+		if result.Partial {
+			fmt.Fprintln(os.Stderr, "Warning: comparison timed out, results partial")
+			code = exitTimeout
+		}
+		os.Exit(code)
+	}
+
 	// Check if files are identical
-	if len(differences) == 0 {
-		if !*quietPtr {
+	if result.Equal {
+		if !quietEqual && !result.Partial {
 			fmt.Println("The JSON files are identical.")
 		}
-		os.Exit(0)
+		exit(exitIdentical)
 	} else {
-		if !*quietPtr {
+		if !quiet {
 			fmt.Println("The JSON files are different.")
 
-			// Show the differences
-			fmt.Println("\nDifferences found:")
-			for _, diff := range differences {
-				switch diff.Type {
-				case ValueMismatch:
-					fmt.Printf("%s: value mismatch\n- %v\n+ %v\n", diff.Path, diff.Value1, diff.Value2)
-				case KeyOnlyInFirst:
-					fmt.Printf("%s: key exists only in first file\n", diff.Path)
-				case KeyOnlyInSecond:
-					fmt.Printf("%s: key exists only in second file\n", diff.Path)
-				case ArrayLength:
-					fmt.Printf("%s: array length mismatch\n- %v\n+ %v\n", diff.Path, diff.Value1, diff.Value2)
-				case TypeMismatch:
-					fmt.Printf("%s: type mismatch\n- %v\n+ %v\n", diff.Path, diff.Value1, diff.Value2)
+			if *interactivePtr {
+				// Review the differences one at a time
+				accepted := reviewDifferences(differences, os.Stdin, os.Stdout)
+				if len(accepted) > 0 {
+					if *baselinePtr == "" {
+						fmt.Printf("Accepted %d differences, but no -baseline file was given to record them\n", len(accepted))
+					} else if err := appendBaseline(*baselinePtr, accepted); err != nil {
+						fmt.Printf("Error writing baseline file: %v\n", err)
+						os.Exit(1)
+					} else {
+						fmt.Printf("Accepted %d differences into %s\n", len(accepted), *baselinePtr)
+					}
+				}
+				if *baselinePtr != "" && len(accepted) == len(differences) {
+					exit(exitIdentical)
+				}
+			} else {
+				// Show the differences, with those inside arrays rolled up if requested
+				listed := result
+				var groups []ArrayGroup
+				if *groupArraysPtr && !*outputGitHubPtr && !*structureDeltaPtr {
+					var rest []Diff
+					groups, rest = groupArrayDiffs(differences)
+					listed = result.withDiffs(rest)
 				}
+				shown, _ := headDifferences(listed.Diffs, *headPtr)
+				out := newLimitedWriter(os.Stdout, *limitOutputBytesPtr)
+				if *outputGitHubPtr {
+					for _, diff := range shown {
+						fmt.Fprint(out, formatGitHubAnnotation(diff, file2Path))
+					}
+				} else if *structureDeltaPtr {
+					fmt.Fprintln(out, "\nStructure delta:")
+					for _, diff := range shown {
+						fmt.Fprint(out, formatStructureDelta(diff))
+					}
+				} else {
+					if len(groups) > 0 {
+						fmt.Fprintln(out, "\nArray changes:")
+						for _, group := range groups {
+							fmt.Fprint(out, formatArrayGroup(group))
+						}
+					}
+					if len(shown) > 0 {
+						fmt.Fprintln(out, "\nDifferences found:")
+					}
+					for _, diff := range shown {
+						text := formatTags(diff) + formatDiffText(diff)
+						if *charDiffPtr {
+							text += formatCharDiff(diff)
+						}
+						fmt.Fprint(out, text)
+					}
+				}
+				if out.Truncated() {
+					fmt.Println("(output truncated)")
+				}
+				fmt.Print(formatRemainingSummary(listed.Stats.without(shown)))
+			}
+		}
+
+		// Only fail for differences at or above the requested severity
+		if !hasSeverity(differences, failOnSeverity) {
+			if !quietEqual {
+				fmt.Printf("No differences with severity %s or higher.\n", failOnSeverity)
 			}
+			exit(exitIdentical)
 		}
-		os.Exit(1) // Exit with non-zero status if files differ
+		exit(exitDifferent) // Exit with non-zero status if files differ
 	}
 }
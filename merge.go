@@ -0,0 +1,283 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ApplyDiffs returns a copy of data1 with differences found against data2
+// applied: changed and added values are taken from data2 and removed keys are
+// dropped, so applying every difference yields data2. Differences that were
+// filtered out keep data1's value. A type change, a key whose case changed,
+// an array with a summarized or uncompared tail and an array whose elements
+// were matched by key or regardless of order are replaced by data2's value
+// as a whole. data1 and data2 are not modified.
+func ApplyDiffs(data1, data2 interface{}, differences []Diff) (interface{}, error) {
+	result := copyValue(data1)
+
+	// Find the values replaced as a whole; differences inside them are already applied
+	var replaced []string
+	for _, diff := range differences {
+		path, whole := replacedPath(diff)
+		if !whole {
+			continue
+		}
+		covered := false
+		for i, other := range replaced {
+			if isUnderPath(path, other) {
+				covered = true
+				break
+			}
+			if isUnderPath(other, path) {
+				replaced[i] = path
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			replaced = append(replaced, path)
+		}
+	}
+	for _, path := range replaced {
+		var err error
+		if result, err = setFromOther(result, data2, path); err != nil {
+			return nil, err
+		}
+	}
+
+	// A key whose case changed was dropped above, as the second file has no
+	// key by its old name; add it back under the new one
+	for _, diff := range differences {
+		if diff.Type != KeyCaseMismatch {
+			continue
+		}
+		key2, ok := diff.Value2.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply difference at %s", displayPath(diff.Path))
+		}
+		var err error
+		if result, err = setFromOther(result, data2, joinPath(parentPath(diff.Path), key2)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, diff := range differences {
+		underReplaced := false
+		for _, path := range replaced {
+			if isUnderPath(diff.Path, path) {
+				underReplaced = true
+				break
+			}
+		}
+		if underReplaced {
+			continue
+		}
+
+		var err error
+		switch diff.Type {
+		case ValueMismatch:
+			// Keys matched regardless of case are reported by their name in the first file
+			if _, found, _ := lookupPath(data2, diff.Path); found {
+				result, err = setFromOther(result, data2, diff.Path)
+			} else {
+				result, err = setAt(result, mustParsePath(diff.Path), copyValue(diff.Value2), diff.Path)
+			}
+		case KeyOnlyInSecond:
+			result, err = setFromOther(result, data2, diff.Path)
+		case KeyOnlyInFirst, RequiredMissing:
+			result = deleteAt(result, mustParsePath(diff.Path))
+		case ArrayLength:
+			result, err = resizeArray(result, data2, diff.Path)
+		case Moved:
+			from, _ := diff.Value1.(string)
+			result = deleteAt(result, mustParsePath(from))
+			result, err = setFromOther(result, data2, diff.Path)
+		case OneofChanged:
+			field1, field2 := soleKey(diff.Value1), soleKey(diff.Value2)
+			result = deleteAt(result, mustParsePath(joinPath(diff.Path, field1)))
+			result, err = setFromOther(result, data2, joinPath(diff.Path, field2))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// replacedPath returns the path of the value a difference makes ApplyDiffs
+// replace as a whole, if any
+func replacedPath(diff Diff) (string, bool) {
+	switch diff.Type {
+	case TypeMismatch, DepthExceeded, ArrayDiffsTruncated, DocumentCount, KeyCaseMismatch:
+		return diff.Path, true
+	case KeyOnlyInFirst, KeyOnlyInSecond:
+		// Elements only in one array come from matching by key or regardless of order
+		if diff.ParentType == ParentArray {
+			return parentPath(diff.Path), true
+		}
+	}
+	return "", false
+}
+
+// soleKey returns the key of a single-key object, as held by a OneofChanged difference
+func soleKey(val interface{}) string {
+	for key := range val.(map[string]interface{}) {
+		return key
+	}
+	return ""
+}
+
+// mustParsePath parses a path reported by the comparison, which is always
+// well-formed; a path that doesn't parse yields no segments
+func mustParsePath(path string) []pathSegment {
+	segments, _ := parsePath(path)
+	return segments
+}
+
+// setFromOther sets the value at path in result to the value at the same
+// path in other, removing it if other has none
+func setFromOther(result, other interface{}, path string) (interface{}, error) {
+	val, found, err := lookupPath(other, path)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return deleteAt(result, mustParsePath(path)), nil
+	}
+	return setAt(result, mustParsePath(path), copyValue(val), path)
+}
+
+// setAt returns cur with the value at segments set to val. An object key may
+// be added and an array may be extended by one element at its end.
+func setAt(cur interface{}, segments []pathSegment, val interface{}, path string) (interface{}, error) {
+	if len(segments) == 0 {
+		return val, nil
+	}
+	segment := segments[0]
+
+	if segment.IsIndex {
+		arr, ok := cur.([]interface{})
+		if !ok || segment.Index > len(arr) || (segment.Index == len(arr) && len(segments) > 1) {
+			return nil, fmt.Errorf("cannot apply difference at %s", displayPath(path))
+		}
+		if segment.Index == len(arr) {
+			return append(arr, val), nil
+		}
+		child, err := setAt(arr[segment.Index], segments[1:], val, path)
+		if err != nil {
+			return nil, err
+		}
+		arr[segment.Index] = child
+		return arr, nil
+	}
+
+	obj, ok := cur.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot apply difference at %s", displayPath(path))
+	}
+	existing, ok := obj[segment.Key]
+	if !ok && len(segments) > 1 {
+		return nil, fmt.Errorf("cannot apply difference at %s", displayPath(path))
+	}
+	child, err := setAt(existing, segments[1:], val, path)
+	if err != nil {
+		return nil, err
+	}
+	obj[segment.Key] = child
+	return obj, nil
+}
+
+// deleteAt returns cur with the object key at segments removed. Missing keys
+// are ignored; array elements are not removed, since that would shift the
+// indices of the differences still to be applied.
+func deleteAt(cur interface{}, segments []pathSegment) interface{} {
+	if len(segments) == 0 {
+		return nil
+	}
+	segment := segments[0]
+
+	if segment.IsIndex {
+		arr, ok := cur.([]interface{})
+		if ok && segment.Index < len(arr) && len(segments) > 1 {
+			arr[segment.Index] = deleteAt(arr[segment.Index], segments[1:])
+		}
+		return cur
+	}
+
+	obj, ok := cur.(map[string]interface{})
+	if !ok {
+		return cur
+	}
+	if len(segments) == 1 {
+		delete(obj, segment.Key)
+	} else if child, ok := obj[segment.Key]; ok {
+		obj[segment.Key] = deleteAt(child, segments[1:])
+	}
+	return obj
+}
+
+// resizeArray truncates the array at path in result, or extends it with the
+// trailing elements of the array at the same path in other, to other's length
+func resizeArray(result, other interface{}, path string) (interface{}, error) {
+	val1, found1, err := lookupPath(result, path)
+	if err != nil {
+		return nil, err
+	}
+	val2, found2, err := lookupPath(other, path)
+	if err != nil {
+		return nil, err
+	}
+	arr1, ok1 := val1.([]interface{})
+	arr2, ok2 := val2.([]interface{})
+	if !found1 || !found2 || !ok1 || !ok2 {
+		return nil, fmt.Errorf("cannot apply difference at %s", displayPath(path))
+	}
+
+	if len(arr1) > len(arr2) {
+		arr1 = arr1[:len(arr2)]
+	} else {
+		for _, elem := range arr2[len(arr1):] {
+			arr1 = append(arr1, copyValue(elem))
+		}
+	}
+	return setAt(result, mustParsePath(path), arr1, path)
+}
+
+// copyValue returns a deep copy of a decoded JSON value
+func copyValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			copied[key] = copyValue(elem)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, elem := range v {
+			copied[i] = copyValue(elem)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// writeMergedJSON writes a merged document as indented JSON to filePath, or
+// to stdout if filePath is "-"
+func writeMergedJSON(merged interface{}, filePath string) error {
+	output, err := json.MarshalIndent(encodeNonFinite(merged), "", "  ")
+	if err != nil {
+		return err
+	}
+	if filePath == "-" {
+		_, err = fmt.Fprintln(os.Stdout, string(output))
+		return err
+	}
+	return os.WriteFile(filePath, output, 0644)
+}
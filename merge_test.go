@@ -0,0 +1,88 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyDiffs(t *testing.T) {
+	data1 := map[string]interface{}{
+		"name":    "Alice",
+		"age":     30.0,
+		"city":    "Paris",
+		"hobbies": []interface{}{"reading", "chess", "golf"},
+		"scores":  []interface{}{1.0},
+		"address": map[string]interface{}{"zip": "75001"},
+		"tags":    []interface{}{"a", "b"},
+		"Email":   "a@example.com",
+	}
+	data2 := map[string]interface{}{
+		"name":    "Alice",
+		"age":     31.0,
+		"country": "France",
+		"hobbies": []interface{}{"reading", "go"},
+		"scores":  []interface{}{1.0, 2.0, 3.0},
+		"address": "10 Rue de Rivoli",
+		"tags":    []interface{}{"b", "c"},
+		"email":   "a@example.com",
+	}
+	options := CompareOptions{IgnoreOrderPaths: []string{"tags"}, ReportCaseDiffs: true}
+
+	// Applying every difference yields the second document, leaving both untouched
+	differences := findDifferencesWithOptions(data1, data2, "", options)
+	merged, err := ApplyDiffs(data1, data2, differences)
+	if err != nil {
+		t.Fatalf("ApplyDiffs returned error: %v", err)
+	}
+	if !reflect.DeepEqual(merged, data2) {
+		t.Errorf("Expected the merged document to equal the second, got %v", merged)
+	}
+	if data1["age"] != 30.0 || len(data1["hobbies"].([]interface{})) != 3 {
+		t.Errorf("Expected the first document to be unchanged, got %v", data1)
+	}
+
+	// Differences left out keep the first document's values
+	merged, err = ApplyDiffs(data1, data2, filterAdditions(differences))
+	if err != nil {
+		t.Fatalf("ApplyDiffs returned error: %v", err)
+	}
+	obj := merged.(map[string]interface{})
+	if obj["age"] != 30.0 || obj["city"] != "Paris" || obj["country"] != "France" {
+		t.Errorf("Expected only the additions to be applied, got %v", obj)
+	}
+	if !reflect.DeepEqual(obj["scores"], []interface{}{1.0, 2.0, 3.0}) {
+		t.Errorf("Expected the grown array to be extended, got %v", obj["scores"])
+	}
+
+	// Removing a missing key does nothing, but setting a value inside a scalar is an error
+	if _, err := ApplyDiffs(data1, data2, []Diff{{Path: "missing.key", Type: KeyOnlyInSecond}}); err != nil {
+		t.Errorf("Expected a key missing from both documents to be dropped, got %v", err)
+	}
+	if _, err := ApplyDiffs(data1, data2, []Diff{{Path: "name.first", Type: ValueMismatch, Value2: "x"}}); err == nil {
+		t.Error("Expected an error applying a value inside a string")
+	}
+}
+
+func TestApplyDiffsMoveAndOneof(t *testing.T) {
+	data1 := map[string]interface{}{
+		"old":     "value",
+		"payment": map[string]interface{}{"card": "1234"},
+	}
+	data2 := map[string]interface{}{
+		"new":     "value",
+		"payment": map[string]interface{}{"paypal": "a@example.com"},
+	}
+	options := CompareOptions{OneofGroups: map[string][][]string{"payment": {{"card", "paypal"}}}}
+
+	differences := detectMoves(findDifferencesWithOptions(data1, data2, "", options), options)
+	merged, err := ApplyDiffs(data1, data2, differences)
+	if err != nil {
+		t.Fatalf("ApplyDiffs returned error: %v", err)
+	}
+	if !reflect.DeepEqual(merged, data2) {
+		t.Errorf("Expected the merged document to equal the second, got %v", merged)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+// detectMoves replaces each pair of a key only in the first file and a key
+// only in the second file holding equal values with a single Moved difference
+// at the new path, whose Value1 and Value2 are the old and new paths. Values
+// are compared with options; each removed key is paired with the first
+// unpaired added key holding an equal value, in path order.
+func detectMoves(differences []Diff, options CompareOptions) []Diff {
+	moved := make(map[int]int) // Index of each paired addition to the index of its removal
+	paired := make(map[int]bool)
+
+	for i, removed := range differences {
+		if removed.Type != KeyOnlyInFirst {
+			continue
+		}
+		for j, added := range differences {
+			if added.Type != KeyOnlyInSecond || paired[j] {
+				continue
+			}
+			if valuesEqual(removed.Value1, added.Value2, added.Path, options) {
+				moved[j] = i
+				paired[i] = true
+				paired[j] = true
+				break
+			}
+		}
+	}
+	if len(moved) == 0 {
+		return differences
+	}
+
+	result := make([]Diff, 0, len(differences)-len(moved))
+	for i, diff := range differences {
+		if from, ok := moved[i]; ok {
+			move := []Diff{{
+				Path:       diff.Path,
+				Type:       Moved,
+				Value1:     differences[from].Path,
+				Value2:     diff.Path,
+				ParentType: diff.ParentType,
+			}}
+			result = append(result, assignTags(assignSeverities(move, options.SeverityOverrides), options.PathTags)...)
+		} else if !paired[i] {
+			result = append(result, diff)
+		}
+	}
+	return result
+}
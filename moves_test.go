@@ -0,0 +1,47 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"testing"
+)
+
+func TestDetectMoves(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"a":       map[string]interface{}{"x": 1.0},
+		"name":    "Alice",
+		"removed": "gone",
+		"dup1":    true,
+		"dup2":    true,
+		"user":    map[string]interface{}{"id": 1.0},
+	}
+	obj2 := map[string]interface{}{
+		"b":     map[string]interface{}{"x": 1.0},
+		"user":  map[string]interface{}{"id": 1.0, "name": "ALICE"},
+		"added": "new",
+		"dup3":  true,
+	}
+	options := CompareOptions{IgnoreCaseValues: true, SeverityOverrides: map[string]Severity{"user": SeverityCritical}}
+
+	diffs := detectMoves(findDifferencesWithOptions(obj1, obj2, "", options), options)
+	expected := []string{
+		"added: key exists only in second file",
+		"b: moved from a",
+		"dup2: key exists only in first file",
+		"dup3: moved from dup1",
+		"removed: key exists only in first file",
+		"user.name: moved from name",
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d differences, got %v", len(expected), diffs)
+	}
+	for i, diff := range diffs {
+		if got := formatDiff(diff); got != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], got)
+		}
+	}
+	if diffs[5].Severity != SeverityCritical {
+		t.Errorf("Expected the move to take the severity of its new path, got %v", diffs[5].Severity)
+	}
+}
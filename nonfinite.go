@@ -0,0 +1,121 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+)
+
+// nonFiniteMarker prefixes the placeholder strings that stand in for
+// non-finite numbers while a document is decoded by encoding/json
+const nonFiniteMarker = "\x00jsondiff-nonfinite:"
+
+// nonFiniteTokens maps the non-standard number literals some producers emit to their values
+var nonFiniteTokens = []struct {
+	literal string
+	value   float64
+}{
+	{"-Infinity", math.Inf(-1)},
+	{"+Infinity", math.Inf(1)},
+	{"Infinity", math.Inf(1)},
+	{"NaN", math.NaN()},
+}
+
+// quoteNonFinite replaces bare NaN, Infinity, +Infinity and -Infinity literals
+// outside of strings with placeholder strings, so the result is valid JSON.
+// restoreNonFinite turns the placeholders back into numbers after decoding.
+func quoteNonFinite(data []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(data))
+
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+			out.WriteByte(c)
+			continue
+		}
+
+		replaced := false
+		for _, token := range nonFiniteTokens {
+			if bytes.HasPrefix(data[i:], []byte(token.literal)) {
+				out.WriteString(`"\u0000jsondiff-nonfinite:` + token.literal + `"`)
+				i += len(token.literal) - 1
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			out.WriteByte(c)
+		}
+	}
+
+	return out.Bytes()
+}
+
+// restoreNonFinite replaces the placeholders written by quoteNonFinite with
+// the non-finite numbers they stand for
+func restoreNonFinite(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = restoreNonFinite(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = restoreNonFinite(val)
+		}
+	case string:
+		if literal, ok := strings.CutPrefix(v, nonFiniteMarker); ok {
+			for _, token := range nonFiniteTokens {
+				if token.literal == literal {
+					return token.value
+				}
+			}
+		}
+	}
+	return data
+}
+
+// encodeNonFinite returns a copy of data with non-finite numbers replaced by
+// the strings "NaN", "Infinity" and "-Infinity", which encoding/json can write
+func encodeNonFinite(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		encoded := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			encoded[key] = encodeNonFinite(val)
+		}
+		return encoded
+	case []interface{}:
+		encoded := make([]interface{}, len(v))
+		for i, val := range v {
+			encoded[i] = encodeNonFinite(val)
+		}
+		return encoded
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN"
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		}
+	}
+	return data
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAllowNonFinite(t *testing.T) {
+	content := `{"nan": NaN, "inf": Infinity, "neg": -Infinity, "pos": +Infinity, "text": "NaN and Infinity", "list": [1, NaN]}`
+	filePath := filepath.Join(t.TempDir(), "nonfinite.json")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// Without the flag behavior is unchanged
+	if _, err := readAndValidateJSONWithOptions(filePath, ReadOptions{Concise: true}); err == nil {
+		t.Error("Expected NaN to be rejected without -allow-nonfinite")
+	}
+
+	jsonFile, err := readAndValidateJSONWithOptions(filePath, ReadOptions{Concise: true, AllowNonFinite: true})
+	if err != nil {
+		t.Fatalf("Failed to read non-finite numbers: %v", err)
+	}
+
+	obj := jsonFile.Data.(map[string]interface{})
+	if !math.IsNaN(obj["nan"].(float64)) || !math.IsNaN(obj["list"].([]interface{})[1].(float64)) {
+		t.Errorf("Expected NaN values, got %v", obj)
+	}
+	if !math.IsInf(obj["inf"].(float64), 1) || !math.IsInf(obj["pos"].(float64), 1) || !math.IsInf(obj["neg"].(float64), -1) {
+		t.Errorf("Expected infinite values, got %v", obj)
+	}
+	if obj["text"] != "NaN and Infinity" {
+		t.Errorf("Expected strings to be left alone, got %v", obj["text"])
+	}
+}
+
+func TestNonFiniteComparison(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+
+	testCases := []struct {
+		name    string
+		val1    interface{}
+		val2    interface{}
+		options CompareOptions
+		equal   bool
+	}{
+		{"NaN never equals NaN", nan, nan, CompareOptions{}, false},
+		{"NaN never equals NaN within tolerance", nan, nan, CompareOptions{FloatTolerance: 1}, false},
+		{"Same sign infinities", inf, inf, CompareOptions{}, true},
+		{"Same sign infinities within tolerance", inf, inf, CompareOptions{FloatTolerance: 0.5}, true},
+		{"Opposite sign infinities", inf, -inf, CompareOptions{FloatTolerance: 0.5}, false},
+		{"Infinity and a number", inf, 1e308, CompareOptions{FloatTolerance: 0.5}, false},
+		{"Infinity string as number", inf, "Infinity", CompareOptions{IgnoreNumericType: true}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj1 := map[string]interface{}{"x": tc.val1}
+			obj2 := map[string]interface{}{"x": tc.val2}
+			diffs := findDifferencesWithOptions(obj1, obj2, "", tc.options)
+			if (len(diffs) == 0) != tc.equal {
+				t.Errorf("Expected equal=%v for %v and %v, got %v", tc.equal, tc.val1, tc.val2, diffs)
+			}
+		})
+	}
+
+	// Non-finite values are written as strings in the JSON output
+	outputJSON, err := marshalDifferences([]Diff{{Path: "x", Type: ValueMismatch, Value1: nan, Value2: -inf}})
+	if err != nil {
+		t.Fatalf("Failed to marshal non-finite values: %v", err)
+	}
+	if !bytes.Contains(outputJSON, []byte(`"value1": "NaN"`)) || !bytes.Contains(outputJSON, []byte(`"value2": "-Infinity"`)) {
+		t.Errorf("Unexpected JSON output: %s", outputJSON)
+	}
+}
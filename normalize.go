@@ -0,0 +1,115 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Normalizer rewrites a value before it is compared. Names lists the
+// normalizers it applies in order, e.g. lower and then trim, so that it can
+// be shown in the effective options.
+type Normalizer struct {
+	Names []string
+	apply func(interface{}) interface{}
+}
+
+// MarshalJSON renders the normalizer as the list of its names
+func (n Normalizer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Names)
+}
+
+// parseTypeNormalizer parses a type:name specification such as string:lower
+// or number:round2 into the JSON type it applies to and its normalizer.
+// Strings support lower, upper and trim; numbers support roundN, rounding to
+// N decimal places.
+func parseTypeNormalizer(spec string) (string, Normalizer, error) {
+	typeName, name, found := strings.Cut(spec, ":")
+	if !found {
+		return "", Normalizer{}, fmt.Errorf("invalid type normalizer %q, expected type:normalizer", spec)
+	}
+
+	switch typeName {
+	case "string":
+		var fn func(string) string
+		switch name {
+		case "lower":
+			fn = strings.ToLower
+		case "upper":
+			fn = strings.ToUpper
+		case "trim":
+			fn = strings.TrimSpace
+		default:
+			return "", Normalizer{}, fmt.Errorf("unknown string normalizer %q, expected lower, upper or trim", name)
+		}
+		return typeName, Normalizer{
+			Names: []string{name},
+			apply: func(val interface{}) interface{} { return fn(val.(string)) },
+		}, nil
+	case "number":
+		places, err := strconv.Atoi(strings.TrimPrefix(name, "round"))
+		if !strings.HasPrefix(name, "round") || err != nil || places < 0 {
+			return "", Normalizer{}, fmt.Errorf("unknown number normalizer %q, expected roundN (e.g. round2)", name)
+		}
+		scale := math.Pow(10, float64(places))
+		return typeName, Normalizer{
+			Names: []string{name},
+			apply: func(val interface{}) interface{} {
+				num, ok := numberValue(val)
+				if !ok {
+					return val
+				}
+				return math.Round(num*scale) / scale
+			},
+		}, nil
+	default:
+		return "", Normalizer{}, fmt.Errorf("unknown type %q for normalizer, expected string or number", typeName)
+	}
+}
+
+// addTypeNormalizer parses spec and adds its normalizer to normalizers. A
+// second normalizer for the same type runs after the first.
+func addTypeNormalizer(normalizers map[string]Normalizer, spec string) error {
+	typeName, normalizer, err := parseTypeNormalizer(spec)
+	if err != nil {
+		return err
+	}
+	if previous, ok := normalizers[typeName]; ok {
+		normalizers[typeName] = Normalizer{
+			Names: append(append([]string(nil), previous.Names...), normalizer.Names...),
+			apply: func(val interface{}) interface{} { return normalizer.apply(previous.apply(val)) },
+		}
+	} else {
+		normalizers[typeName] = normalizer
+	}
+	return nil
+}
+
+// hasPathComparator reports whether the values at path are compared by a
+// path-scoped comparator, which takes precedence over type normalizers
+func hasPathComparator(path string, options CompareOptions) bool {
+	_, regex := lookupScoped(options.RegexMatches, path)
+	_, exec := lookupScoped(options.ExecComparators, path)
+	_, enum := lookupScoped(options.EnumValues, path)
+	_, unit := lookupScoped(options.UnitKeys, path)
+	_, numeric := lookupScoped(options.NumericRules, path)
+	_, levenshtein := lookupScoped(options.LevenshteinKeys, path)
+	semver, _ := lookupScoped(options.SemverKeys, path)
+	uuid, _ := lookupScoped(options.UUIDKeys, path)
+	currency, _ := lookupScoped(options.CurrencyKeys, path)
+	return regex || exec || enum || unit || numeric || levenshtein || semver || uuid || currency
+}
+
+// normalizeByType applies the normalizer registered for the JSON type of val,
+// if any
+func normalizeByType(val interface{}, normalizers map[string]Normalizer) interface{} {
+	if normalizer, ok := normalizers[jsonTypeName(val)]; ok {
+		return normalizer.apply(val)
+	}
+	return val
+}
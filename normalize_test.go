@@ -0,0 +1,60 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"testing"
+)
+
+func TestTypeNormalizers(t *testing.T) {
+	normalizers := make(map[string]Normalizer)
+	for _, spec := range []string{"string:trim", "string:lower", "number:round2"} {
+		if err := addTypeNormalizer(normalizers, spec); err != nil {
+			t.Fatalf("addTypeNormalizer(%q) returned error: %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"string", "string:reverse", "number:round", "number:round-1", "boolean:lower"} {
+		if _, _, err := parseTypeNormalizer(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+
+	obj1 := map[string]interface{}{
+		"name":    " Alice ",
+		"price":   9.999,
+		"count":   1.0,
+		"version": "1.2",
+		"tags":    []interface{}{"A", 1.001},
+	}
+	obj2 := map[string]interface{}{
+		"name":    "alice",
+		"price":   10.0,
+		"count":   2.0,
+		"version": "1.2.0",
+		"tags":    []interface{}{"a", 1.0},
+	}
+	options := CompareOptions{TypeNormalizers: normalizers}
+
+	// The semver comparator decides at version, so "1.2" still equals "1.2.0" there
+	options.SemverKeys = map[string]bool{"version": true}
+	diffs := findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(diffs) != 1 || diffs[0].Path != "count" {
+		t.Errorf("Expected a single difference at count, got %v", diffs)
+	}
+	if diffs[0].Value1 != 1.0 || diffs[0].Value2 != 2.0 {
+		t.Errorf("Expected the original values to be reported, got %v", diffs[0])
+	}
+
+	// A path-scoped comparator takes precedence over the type normalizer
+	options.SemverKeys = nil
+	options.RegexMatches = map[string]string{"name": "^[a-z]+$"}
+	diffs = findDifferencesWithOptions(obj1, obj2, "", options)
+	paths := make([]string, len(diffs))
+	for i, diff := range diffs {
+		paths[i] = diff.Path
+	}
+	if len(paths) != 3 || paths[0] != "count" || paths[1] != "name" || paths[2] != "version" {
+		t.Errorf("Expected differences at count, name and version, got %v", diffs)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+)
+
+// decodeNumbers decodes a single JSON document, keeping numbers as
+// json.Number in their canonical textual form (see canonicalNumber)
+func decodeNumbers(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the document")
+	}
+	return canonicalizeNumbers(doc), nil
+}
+
+// canonicalizeNumbers rewrites every json.Number in data into its canonical form
+func canonicalizeNumbers(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = canonicalizeNumbers(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = canonicalizeNumbers(val)
+		}
+	case json.Number:
+		return canonicalNumber(v)
+	}
+	return data
+}
+
+// canonicalNumber returns the exact value of a JSON number in a single
+// textual form: plain decimal notation without exponent, leading zeros,
+// trailing fractional zeros or a negative sign on zero. "1e3" and "1000.0"
+// both become "1000", and "1.10" becomes "1.1". Unlike converting to
+// float64, digits beyond float64 precision are kept.
+func canonicalNumber(num json.Number) json.Number {
+	r, ok := new(big.Rat).SetString(string(num))
+	if !ok {
+		return num
+	}
+	if r.IsInt() {
+		return json.Number(r.Num().String())
+	}
+
+	// A decimal's denominator is 2^a * 5^b, so max(a, b) fractional digits are exact
+	denom := new(big.Int).Set(r.Denom())
+	twos := denom.TrailingZeroBits()
+	denom.Rsh(denom, twos)
+
+	fives := uint(0)
+	five := big.NewInt(5)
+	mod := new(big.Int)
+	for {
+		quo, rem := new(big.Int).QuoRem(denom, five, mod)
+		if rem.Sign() != 0 {
+			break
+		}
+		denom = quo
+		fives++
+	}
+
+	digits := twos
+	if fives > digits {
+		digits = fives
+	}
+	return json.Number(r.FloatString(int(digits)))
+}
+
+// numericStringPattern matches strings written in JSON number syntax. Leading
+// zeros are not allowed, so codes such as "007" stay distinct from "7".
+var numericStringPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE]([+-]?[0-9]+))?$`)
+
+// maxNumericStringExponent bounds the exponents normalizeNumericString expands,
+// so a string like "1e999999999" can't make it build an enormous number
+const maxNumericStringExponent = 1000
+
+// normalizeNumericString returns the canonical form of a string written as a
+// number (see canonicalNumber), so "1.50" and "1.5e0" both become "1.5".
+// The boolean result is false for strings that don't look like numbers.
+func normalizeNumericString(s string) (string, bool) {
+	match := numericStringPattern.FindStringSubmatch(s)
+	if match == nil {
+		return "", false
+	}
+	if match[4] != "" {
+		exp, err := strconv.Atoi(match[4])
+		if err != nil || exp > maxNumericStringExponent || exp < -maxNumericStringExponent {
+			return "", false
+		}
+	}
+	return string(canonicalNumber(json.Number(s))), true
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonicalNumber(t *testing.T) {
+	testCases := []struct {
+		num      json.Number
+		expected json.Number
+	}{
+		{"1000", "1000"},
+		{"1e3", "1000"},
+		{"1E+3", "1000"},
+		{"1000.0", "1000"},
+		{"1.10", "1.1"},
+		{"0.5e-2", "0.005"},
+		{"-0", "0"},
+		{"-0.0", "0"},
+		{"-12.500", "-12.5"},
+		{"0.1", "0.1"},
+		{"12345678901234567890", "12345678901234567890"},
+		{"1.2345678901234567890123", "1.2345678901234567890123"},
+	}
+
+	for _, tc := range testCases {
+		if result := canonicalNumber(tc.num); result != tc.expected {
+			t.Errorf("canonicalNumber(%s) = %s, want %s", tc.num, result, tc.expected)
+		}
+	}
+}
+
+func TestNormalizeNumbers(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "a.json")
+	file2 := filepath.Join(dir, "b.json")
+	if err := os.WriteFile(file1, []byte(`{"count": 1e3, "price": 1.10, "id": 12345678901234567890, "code": "1000"}`), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := os.WriteFile(file2, []byte(`{"count": 1000, "price": 1.1, "id": 12345678901234567891, "code": 1000}`), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	readOptions := ReadOptions{Concise: true, NormalizeNumbers: true}
+	json1, err := readAndValidateJSONWithOptions(file1, readOptions)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", file1, err)
+	}
+	json2, err := readAndValidateJSONWithOptions(file2, readOptions)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", file2, err)
+	}
+
+	// Formatting differences vanish; the ids differ beyond float64 precision; strings aren't numbers
+	diffs := findDifferencesWithOptions(json1.Data, json2.Data, "", CompareOptions{})
+	if len(diffs) != 2 || diffs[0].Path != "code" || diffs[1].Path != "id" {
+		t.Errorf("Expected differences at code and id, got %v", diffs)
+	}
+
+	// Numeric options still apply to normalized numbers
+	diffs = findDifferencesWithOptions(json1.Data, json2.Data, "", CompareOptions{FloatTolerance: 10})
+	if len(diffs) != 1 || diffs[0].Path != "code" {
+		t.Errorf("Expected only code to differ within tolerance, got %v", diffs)
+	}
+
+	// Trailing data is still rejected
+	if err := os.WriteFile(file1, []byte(`{"a": 1} {"b": 2}`), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if _, err := readAndValidateJSONWithOptions(file1, readOptions); err == nil {
+		t.Error("Expected trailing data to be rejected")
+	}
+}
+
+func TestNormalizeNumericStrings(t *testing.T) {
+	tests := []struct {
+		val1, val2 interface{}
+		equal      bool
+	}{
+		{"1.50", "1.5", true},
+		{"15e-1", "1.5", true},
+		{"-0.0", "0", true},
+		{"1000", "1e3", true},
+		{"1.50", "1.51", false},
+		{"007", "7", false},  // Leading zeros aren't numbers
+		{"1.50", 1.5, false}, // Strings never equal numbers
+		{"1e999999999", "1e999999999", true},
+		{"1e999999999", "1e0999999999", false}, // Huge exponents are compared as plain strings
+		{"abc", "abc", true},
+	}
+
+	for _, test := range tests {
+		obj1 := map[string]interface{}{"code": test.val1}
+		obj2 := map[string]interface{}{"code": test.val2}
+		diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{NormalizeNumStrings: true})
+		if (len(diffs) == 0) != test.equal {
+			t.Errorf("Comparing %#v and %#v: expected equal=%v, got %v", test.val1, test.val2, test.equal, diffs)
+		}
+	}
+
+	if diffs := findDifferencesWithOptions(map[string]interface{}{"a": "1.50"}, map[string]interface{}{"a": "1.5"}, "", CompareOptions{}); len(diffs) != 1 {
+		t.Errorf("Expected a difference without the option, got %v", diffs)
+	}
+}
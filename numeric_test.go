@@ -58,11 +58,196 @@ func TestNumericTypeComparison(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result := compareNumericValues(tc.val1, tc.val2)
+			result := compareNumericValues(tc.val1, tc.val2, 0, false)
 			if result != tc.equal {
 				t.Errorf("compareNumericValues(%v, %v) = %v, want %v", 
 					tc.val1, tc.val2, result, tc.equal)
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestNumericTolerance(t *testing.T) {
+	testCases := []struct {
+		name      string
+		val1      interface{}
+		val2      interface{}
+		tolerance float64
+		equal     bool
+	}{
+		{"String within tolerance", "1.0000001", 1.0, 1e-3, true},
+		{"String outside tolerance", "1.01", 1.0, 1e-3, false},
+		{"Both strings within tolerance", "2.5004", "2.5", 1e-3, true},
+		{"Scientific string within tolerance", "1e-4", 0.0, 1e-3, true},
+		{"No tolerance requires exact match", "1.0000001", 1.0, 0, false},
+		{"Non-numeric string", "abc", 1.0, 1e-3, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := compareNumericValues(tc.val1, tc.val2, tc.tolerance, false)
+			if result != tc.equal {
+				t.Errorf("compareNumericValues(%v, %v, %v) = %v, want %v",
+					tc.val1, tc.val2, tc.tolerance, result, tc.equal)
+			}
+		})
+	}
+
+	obj1 := map[string]interface{}{"price": "9.9999", "qty": 3.0001}
+	obj2 := map[string]interface{}{"price": 10.0, "qty": 3.0}
+
+	// Tolerance alone applies to numbers but not to numeric strings
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{FloatTolerance: 1e-3})
+	if len(diffs) != 1 || diffs[0].Path != "price" {
+		t.Errorf("Expected only price to differ with tolerance alone, got %v", diffs)
+	}
+
+	// Combined with ignore-numeric-type, numeric strings are compared within tolerance
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{FloatTolerance: 1e-3, IgnoreNumericType: true})
+	if len(diffs) != 0 {
+		t.Errorf("Expected no differences with tolerance and ignore-numeric-type, got %v", diffs)
+	}
+}
+
+func TestGroupedNumbers(t *testing.T) {
+	testCases := []struct {
+		val      string
+		decimal  string
+		group    string
+		expected interface{}
+	}{
+		{"1,234.56", ".", ",", "1234.56"},
+		{"-1,234,567", ".", ",", "-1234567"},
+		{"1234.5", ".", ",", "1234.5"},
+		{"1.234,56", ",", ".", "1234.56"},
+		{"1 234,56", ",", " ", "1234.56"},
+		{"1,23.4", ".", ",", "1,23.4"},
+		{"1234,567.8", ".", ",", "1234,567.8"},
+		{"12a,345", ".", ",", "12a,345"},
+		{"1.234,56", ".", ",", "1.234,56"},
+	}
+
+	for _, tc := range testCases {
+		if result := ungroupNumber(tc.val, tc.decimal, tc.group); result != tc.expected {
+			t.Errorf("ungroupNumber(%q, %q, %q) = %v, want %v", tc.val, tc.decimal, tc.group, result, tc.expected)
+		}
+	}
+
+	obj1 := map[string]interface{}{"total": "1,234.56", "label": "n/a"}
+	obj2 := map[string]interface{}{"total": 1234.56, "label": "N/A"}
+
+	// Grouped numbers only parse when requested
+	options := CompareOptions{IgnoreNumericType: true, DecimalSeparator: ".", GroupSeparator: ","}
+	if diffs := findDifferencesWithOptions(obj1, obj2, "", options); len(diffs) != 2 {
+		t.Errorf("Expected 2 differences without -parse-grouped-numbers, got %v", diffs)
+	}
+
+	options.ParseGroupedNumbers = true
+	diffs := findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(diffs) != 1 || diffs[0].Path != "label" {
+		t.Errorf("Expected only the non-numeric label to differ, got %v", diffs)
+	}
+
+	// European format
+	options.DecimalSeparator, options.GroupSeparator = ",", "."
+	obj1["total"] = "1.234,56"
+	if diffs := findDifferencesWithOptions(obj1, obj2, "", options); len(diffs) != 1 {
+		t.Errorf("Expected European grouping to parse, got %v", diffs)
+	}
+}
+
+func TestNumericBooleans(t *testing.T) {
+	testCases := []struct {
+		name    string
+		val1    interface{}
+		val2    interface{}
+		numeric bool
+		equal   bool
+	}{
+		{"One vs true", 1.0, true, true, true},
+		{"Zero vs false", false, 0.0, true, true},
+		{"Zero vs string false", 0.0, "false", true, true},
+		{"One vs false", 1.0, false, true, false},
+		{"Two vs true", 2.0, true, true, false},
+		{"Numeric string is not a boolean", "1", true, true, false},
+		{"One vs true without numeric booleans", 1.0, true, false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj1 := map[string]interface{}{"active": tc.val1}
+			obj2 := map[string]interface{}{"active": tc.val2}
+			diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{IgnoreBooleanType: true, NumericBooleans: tc.numeric})
+			if (len(diffs) == 0) != tc.equal {
+				t.Errorf("Comparing %v and %v: expected equal=%v, got %v", tc.val1, tc.val2, tc.equal, diffs)
+			}
+		})
+	}
+
+	// Numeric booleans need -ignore-boolean-type
+	diffs := findDifferencesWithOptions(map[string]interface{}{"a": 1.0}, map[string]interface{}{"a": true}, "", CompareOptions{NumericBooleans: true})
+	if len(diffs) != 1 {
+		t.Errorf("Expected a difference without IgnoreBooleanType, got %v", diffs)
+	}
+}
+
+func TestCoercionDetail(t *testing.T) {
+	testCases := []struct {
+		name   string
+		val1   interface{}
+		val2   interface{}
+		detail string
+	}{
+		{"Numeric string vs number", "5", 5.0, "string vs number"},
+		{"Number vs different numeric string", 5.0, "6", "number vs string"},
+		{"Boolean string vs boolean", "true", true, "string vs boolean"},
+		{"Number vs boolean", 1.0, false, "number vs boolean"},
+		{"Non-numeric string vs number", "five", 5.0, ""},
+		{"Two vs boolean", 2.0, true, ""},
+		{"Same types", "a", "b", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj1 := map[string]interface{}{"value": tc.val1}
+			obj2 := map[string]interface{}{"value": tc.val2}
+			diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{})
+			if len(diffs) != 1 || diffs[0].Type != ValueMismatch || diffs[0].Detail != tc.detail {
+				t.Errorf("Expected a value mismatch with detail %q, got %v", tc.detail, diffs)
+			}
+		})
+	}
+}
+
+func TestCoerceLeftNumericStrings(t *testing.T) {
+	testCases := []struct {
+		name  string
+		val1  interface{}
+		val2  interface{}
+		equal bool
+	}{
+		{"Left numeric string vs number", "42", 42.0, true},
+		{"Left decimal string vs number", "42.0", 42.0, true},
+		{"Number vs right numeric string", 42.0, "42", false},
+		{"Numeric strings on both sides", "42", "42.0", false},
+		{"Different values", "42", 43.0, false},
+		{"Non-numeric string", "abc", 42.0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj1 := map[string]interface{}{"value": tc.val1}
+			obj2 := map[string]interface{}{"value": tc.val2}
+			diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{CoerceLeftNumStrings: true})
+			if (len(diffs) == 0) != tc.equal {
+				t.Errorf("Comparing %v and %v: expected equal=%v, got %v", tc.val1, tc.val2, tc.equal, diffs)
+			}
+		})
+	}
+
+	// Without the option the numeric string differs
+	diffs := findDifferencesWithOptions(map[string]interface{}{"a": "42"}, map[string]interface{}{"a": 42.0}, "", CompareOptions{})
+	if len(diffs) != 1 {
+		t.Errorf("Expected a difference without the option, got %v", diffs)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// NumericRule says how the numbers at one path are compared. Both numbers
+// are first rounded to Places decimal places, if Places is not negative, and
+// are then equal if they are identical, differ by at most AbsTolerance, or
+// differ by at most RelTolerance times the larger magnitude.
+type NumericRule struct {
+	Places       int     // Decimal places numbers are rounded to before comparison (-1 to not round)
+	AbsTolerance float64 // Maximum absolute difference
+	RelTolerance float64 // Maximum difference relative to the larger magnitude, e.g. 0.01 for 1%
+}
+
+// parseNumericRule parses a rule in the form path:terms, e.g. "lat:round4" or
+// "price:round2,abs0.01". See parseNumericRuleTerms for the terms.
+func parseNumericRule(spec string) (string, NumericRule, error) {
+	path, terms, ok := cutLast(spec, ":")
+	if !ok || path == "" {
+		return "", NumericRule{}, fmt.Errorf("invalid numeric rule %q, expected path:rule (e.g. lat:round4 or price:abs0.01)", spec)
+	}
+
+	rule, err := parseNumericRuleTerms(terms)
+	if err != nil {
+		return "", NumericRule{}, fmt.Errorf("invalid numeric rule %q: %v", spec, err)
+	}
+	return path, rule, nil
+}
+
+// parseNumericRuleTerms parses comma-separated rule terms, each given at most
+// once: roundN rounds to N decimal places, absX accepts an absolute difference
+// up to X and relX a relative difference up to X. The term exact, which
+// can't be combined with others, requires numbers to be equal.
+func parseNumericRuleTerms(terms string) (NumericRule, error) {
+	rule := NumericRule{Places: -1}
+	seen := make(map[string]bool)
+	for _, term := range strings.Split(terms, ",") {
+		term = strings.TrimSpace(term)
+		name := strings.TrimRight(term, "0123456789.eE+-")
+		arg := strings.TrimPrefix(term, name)
+
+		switch name {
+		case "exact":
+			if arg != "" {
+				return NumericRule{}, fmt.Errorf("exact takes no argument, got %q", term)
+			}
+		case "round":
+			places, err := strconv.Atoi(arg)
+			if err != nil || places < 0 || places > 15 {
+				return NumericRule{}, fmt.Errorf("round takes 0 to 15 decimal places, got %q", term)
+			}
+			rule.Places = places
+		case "abs", "rel":
+			tolerance, err := strconv.ParseFloat(arg, 64)
+			if err != nil || tolerance < 0 || math.IsInf(tolerance, 0) || math.IsNaN(tolerance) {
+				return NumericRule{}, fmt.Errorf("%s takes a non-negative tolerance, got %q", name, term)
+			}
+			if name == "abs" {
+				rule.AbsTolerance = tolerance
+			} else {
+				rule.RelTolerance = tolerance
+			}
+		default:
+			return NumericRule{}, fmt.Errorf("unknown term %q, expected exact, roundN, absX or relX", term)
+		}
+		if seen[name] {
+			return NumericRule{}, fmt.Errorf("term %s given more than once", name)
+		}
+		seen[name] = true
+	}
+	if seen["exact"] && len(seen) > 1 {
+		return NumericRule{}, fmt.Errorf("exact can't be combined with other terms")
+	}
+	return rule, nil
+}
+
+// compare compares two numbers under the rule, returning whether they are
+// equal, how far apart they are once rounded and the largest distance the
+// rule accepts for them
+func (r NumericRule) compare(num1, num2 float64) (bool, float64, float64) {
+	if r.Places >= 0 {
+		scale := math.Pow(10, float64(r.Places))
+		num1, num2 = math.Round(num1*scale)/scale, math.Round(num2*scale)/scale
+	}
+	if num1 == num2 {
+		// Checked first so infinities of the same sign are equal
+		return true, 0, 0
+	}
+	if math.IsInf(num1, 0) || math.IsInf(num2, 0) {
+		return false, math.Inf(1), 0
+	}
+
+	magnitude := math.Max(math.Abs(num1), math.Abs(num2))
+	threshold := math.Max(r.AbsTolerance, r.RelTolerance*magnitude)
+	distance := math.Abs(num1 - num2)
+
+	// Allow for the rounding error of the subtraction, so 1.01 and 1.00 are
+	// within 0.01 of each other
+	slack := 0.0
+	if threshold > 0 {
+		slack = magnitude * 1e-12
+	}
+	return distance <= threshold+slack, distance, threshold
+}
+
+// ruleNumber returns the value of a number compared under a numeric rule,
+// which includes numeric strings when numeric types are ignored
+func ruleNumber(val interface{}, options CompareOptions) (float64, bool) {
+	if options.IgnoreNumericType {
+		return convertToFloat64(val)
+	}
+	return numberValue(val)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseNumericRule(t *testing.T) {
+	tests := []struct {
+		spec string
+		path string
+		want NumericRule
+	}{
+		{"lat:round4", "lat", NumericRule{Places: 4}},
+		{"price:abs0.01", "price", NumericRule{Places: -1, AbsTolerance: 0.01}},
+		{"count:exact", "count", NumericRule{Places: -1}},
+		{"total:round2, abs0.01", "total", NumericRule{Places: 2, AbsTolerance: 0.01}},
+		{"ratio:rel1e-3,abs0", "ratio", NumericRule{Places: -1, RelTolerance: 0.001}},
+		{"a.b[0]:rel0.5", "a.b[0]", NumericRule{Places: -1, RelTolerance: 0.5}},
+	}
+	for _, test := range tests {
+		path, rule, err := parseNumericRule(test.spec)
+		if err != nil || path != test.path || rule != test.want {
+			t.Errorf("parseNumericRule(%q) = %q, %+v, %v, want %q, %+v", test.spec, path, rule, err, test.path, test.want)
+		}
+	}
+
+	for _, spec := range []string{"lat", ":round2", "lat:", "lat:round", "lat:round-1", "lat:round16", "lat:abs", "lat:abs-1", "lat:absInf", "lat:exact1", "lat:exact,abs1", "lat:abs1,abs2", "lat:ceil2", "lat:5"} {
+		if _, _, err := parseNumericRule(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestNumericRuleCompare(t *testing.T) {
+	tests := []struct {
+		rule       NumericRule
+		num1, num2 float64
+		want       bool
+	}{
+		{NumericRule{Places: 4}, 51.50071, 51.50074, true},
+		{NumericRule{Places: 4}, 51.50071, 51.50076, false},
+		{NumericRule{Places: -1, AbsTolerance: 0.01}, 10.00, 10.01, true},
+		{NumericRule{Places: -1, AbsTolerance: 0.01}, 10.00, 10.02, false},
+		{NumericRule{Places: -1, RelTolerance: 0.01}, 1000, 1009, true},
+		{NumericRule{Places: -1, RelTolerance: 0.01}, 1, 1.02, false},
+		{NumericRule{Places: 2, AbsTolerance: 0.01}, 1.004, 1.014, true},
+		{NumericRule{Places: 2, AbsTolerance: 0.01}, 1.004, 1.016, false},
+		{NumericRule{Places: -1}, 3, 3, true},
+		{NumericRule{Places: -1}, 3, 3.0000001, false},
+		{NumericRule{Places: -1, RelTolerance: 1}, math.Inf(1), math.Inf(-1), false},
+		{NumericRule{Places: -1, RelTolerance: 1}, math.Inf(1), math.Inf(1), true},
+	}
+	for _, test := range tests {
+		if got, _, _ := test.rule.compare(test.num1, test.num2); got != test.want {
+			t.Errorf("%+v.compare(%v, %v) = %v, want %v", test.rule, test.num1, test.num2, got, test.want)
+		}
+	}
+}
+
+func TestNumericRules(t *testing.T) {
+	obj1 := map[string]interface{}{"lat": 51.50071, "price": 10.00, "count": 3.0, "other": 1.0, "label": "x"}
+	obj2 := map[string]interface{}{"lat": 51.50074, "price": 10.01, "count": 3.001, "other": 1.001, "label": "x"}
+	options := CompareOptions{NumericRules: map[string]NumericRule{
+		"lat":   {Places: 4},
+		"price": {Places: -1, AbsTolerance: 0.01},
+		"count": {Places: -1},
+		"label": {Places: 2},
+	}}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(diffs) != 2 || diffs[0].Path != "count" || diffs[1].Path != "other" {
+		t.Errorf("Expected count and other to differ, got %v", diffs)
+	}
+
+	// A rule replaces the float tolerance at its path, even when exact
+	options.FloatTolerance = 0.01
+	var matches []FuzzyMatch
+	options.FuzzyMatches = &matches
+	diffs = findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(diffs) != 1 || diffs[0].Path != "count" {
+		t.Errorf("Expected only count to differ, got %v", diffs)
+	}
+	if len(matches) != 2 || matches[0].Path != "other" || matches[1].Path != "price" || matches[1].Threshold != 0.01 {
+		t.Errorf("Expected fuzzy matches for other and price, got %+v", matches)
+	}
+
+	// Numeric strings follow the rule when numeric types are ignored
+	options = CompareOptions{NumericRules: options.NumericRules, IgnoreNumericType: true}
+	diffs = findDifferencesWithOptions(map[string]interface{}{"price": "10.00"}, map[string]interface{}{"price": 10.005}, "", options)
+	if len(diffs) != 0 {
+		t.Errorf("Expected a numeric string to match within the rule, got %v", diffs)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseOneof parses a path:field1,field2 specification of fields of the
+// object at path that are alternatives of one oneof, using . for the root
+func parseOneof(spec string) (string, []string, error) {
+	path, list, found := cutLast(spec, ":")
+	if !found || path == "" || list == "" {
+		return "", nil, fmt.Errorf("invalid oneof %q, expected path:field1,field2", spec)
+	}
+	if path == "." {
+		path = ""
+	}
+	if err := validatePathExpr(path); err != nil {
+		return "", nil, err
+	}
+
+	fields := strings.Split(list, ",")
+	if len(fields) < 2 {
+		return "", nil, fmt.Errorf("invalid oneof %q, expected at least two fields", spec)
+	}
+	for _, field := range fields {
+		if field == "" {
+			return "", nil, fmt.Errorf("invalid oneof %q, field names must not be empty", spec)
+		}
+	}
+	return path, fields, nil
+}
+
+// addOneof parses spec and adds its group of fields to groups
+func addOneof(groups map[string][][]string, spec string) error {
+	path, fields, err := parseOneof(spec)
+	if err != nil {
+		return err
+	}
+	groups[path] = append(groups[path], fields)
+	return nil
+}
+
+// setOneofField returns the only field of group present in obj. It fails if
+// none or several of them are present.
+func setOneofField(obj map[string]interface{}, group []string) (string, bool) {
+	set := ""
+	for _, field := range group {
+		if _, ok := obj[field]; ok {
+			if set != "" {
+				return "", false
+			}
+			set = field
+		}
+	}
+	return set, set != ""
+}
+
+// oneofChanges finds the oneof groups of the object at path whose set field
+// differs between the two objects. Each change is reported as a single
+// OneofChanged difference at the object's path, whose Value1 and Value2 hold
+// the set field and its value. The changes are keyed by the field set in the
+// first object, and both fields are returned so they aren't reported again.
+func oneofChanges(map1, map2 map[string]interface{}, path string, options CompareOptions) (map[string]Diff, map[string]bool) {
+	changes := make(map[string]Diff)
+	handled := make(map[string]bool)
+	groups, _ := lookupScoped(options.OneofGroups, path)
+	for _, group := range groups {
+		field1, ok1 := setOneofField(map1, group)
+		field2, ok2 := setOneofField(map2, group)
+		if !ok1 || !ok2 || field1 == field2 {
+			continue
+		}
+		changes[field1] = Diff{
+			Path:       path,
+			Type:       OneofChanged,
+			Value1:     map[string]interface{}{field1: map1[field1]},
+			Value2:     map[string]interface{}{field2: map2[field2]},
+			ParentType: ParentObject,
+		}
+		handled[field1] = true
+		handled[field2] = true
+	}
+	return changes, handled
+}
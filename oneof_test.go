@@ -0,0 +1,61 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOneof(t *testing.T) {
+	groups := make(map[string][][]string)
+	for _, spec := range []string{"payment:card,paypal,bank", ".:id,uuid"} {
+		if err := addOneof(groups, spec); err != nil {
+			t.Fatalf("addOneof(%q) returned error: %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"payment", "payment:", ":card,paypal", "payment:card", "payment:card,,bank"} {
+		if _, _, err := parseOneof(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+
+	obj1 := map[string]interface{}{
+		"id":      1.0,
+		"payment": map[string]interface{}{"card": map[string]interface{}{"last4": "1234"}, "amount": 10.0},
+		"other":   map[string]interface{}{"card": "x"},
+	}
+	obj2 := map[string]interface{}{
+		"id":      1.0,
+		"payment": map[string]interface{}{"paypal": map[string]interface{}{"email": "a@example.com"}, "amount": 12.0},
+		"other":   map[string]interface{}{"paypal": "y"},
+	}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{OneofGroups: groups})
+	expected := []string{
+		"other.card: key exists only in first file",
+		"other.paypal: key exists only in second file",
+		"payment.amount: value mismatch - 10 vs 12",
+		"payment: oneof branch changed - map[card:map[last4:1234]] vs map[paypal:map[email:a@example.com]]",
+	}
+	var got []string
+	for _, diff := range diffs {
+		got = append(got, formatDiff(diff))
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if diffs[3].Severity != SeverityError {
+		t.Errorf("Expected a changed oneof to be an error, got %s", diffs[3].Severity)
+	}
+
+	// The same branch on both sides, or an invalid oneof, is compared as usual
+	obj2["payment"] = map[string]interface{}{"card": map[string]interface{}{"last4": "5678"}, "paypal": "z", "amount": 10.0}
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{OneofGroups: groups})
+	for _, diff := range diffs {
+		if diff.Type == OneofChanged {
+			t.Errorf("Expected no oneof change when several fields are set, got %v", diff)
+		}
+	}
+}
@@ -3,15 +3,101 @@
 
 package main
 
+import (
+	"context"
+	"time"
+
+	"golang.org/x/text/collate"
+)
+
 // CompareOptions contains options for JSON comparison
 type CompareOptions struct {
-	IgnoreCase           bool              // If true, key comparisons will be case-insensitive
-	IgnoreCaseValues     bool              // If true, string value comparisons will be case-insensitive
-	IgnoreNumericType    bool              // If true, numeric types are compared by value, not type (e.g., 1 == "1" == "1.0")
-	IgnoreBooleanType    bool              // If true, boolean types are compared by value, not type (e.g., true == "true")
-	IgnoreNullValues     bool              // If true, null values are considered equal to any value
-	KeysOnly             bool              // If true, only compare keys/structure, not values
-	RegexMatches         map[string]string // Map of key paths to regex patterns for value matching
-	LevenshteinKeys      map[string]bool   // Map of key paths to apply Levenshtein distance matching
-	LevenshteinThreshold int               // Maximum Levenshtein distance to consider strings as equal
-}
\ No newline at end of file
+	IgnoreCase            bool                          // If true, key comparisons will be case-insensitive
+	IgnoreWhitespaceKeys  bool                          // If true, whitespace in keys is ignored when matching them (e.g., "first name " == "firstname")
+	NormalizeNumericKeys  bool                          // If true, keys written as numbers are matched in canonical form (e.g., "01" == "1")
+	KeyStyles             []string                      // Key style transformations ("camel-to-snake", "singularize") applied in order to keys before matching them
+	IgnoreCaseValues      bool                          // If true, string value comparisons will be case-insensitive
+	ReportCaseDiffs       bool                          // If true, keys are matched case-insensitively and casing differences are reported
+	FoldUnicode           bool                          // If true, string values and keys are NFC-normalized before comparison
+	Collation             string                        // If set, a BCP 47 language tag (e.g. de-DE) whose collation decides which string values are equal
+	CollationLoose        bool                          // If true, the collation also ignores diacritics, case and width
+	IgnoreNumericType     bool                          // If true, numeric types are compared by value, not type (e.g., 1 == "1" == "1.0")
+	CoerceLeftNumStrings  bool                          // If true, numeric strings in the first value are compared by value with numbers in the second, but not the reverse
+	FloatTolerance        float64                       // Maximum absolute difference for numbers to be considered equal, including numeric strings under IgnoreNumericType
+	NumericRules          map[string]NumericRule        // Map of key paths to the rule their numbers are compared by, in place of FloatTolerance
+	ParseGroupedNumbers   bool                          // If true, numeric strings with group separators (e.g., "1,234.56") are parsed under IgnoreNumericType
+	DecimalSeparator      string                        // Decimal separator used when parsing grouped numbers
+	GroupSeparator        string                        // Group separator used when parsing grouped numbers
+	NormalizeNumStrings   bool                          // If true, strings written as numbers are compared in canonical form (e.g., "1.50" == "1.5"), without being equal to numbers
+	IgnoreBooleanType     bool                          // If true, boolean types are compared by value, not type (e.g., true == "true")
+	NumericBooleans       bool                          // If true with IgnoreBooleanType, the numbers 1 and 0 are compared as true and false
+	IgnoreNullValues      bool                          // If true, null values are considered equal to any value
+	TreatMissingAsDefault bool                          // If true, a key missing from one object equals a default value (0, "", false, null, [] or {}) in the other
+	EnumValues            map[string]map[string]float64 // Map of key paths to enum names and their numbers, so a name equals its number
+	CoerceNumericObjects  bool                          // If true, an object keyed by sequential indices ("0", "1", ...) is compared as an array when the other value is an array
+	DeepTypeMismatch      bool                          // If true, a type mismatch between an object or array and a scalar also lists the object's keys or the array's elements as only in one file
+	UnwrapValueKey        string                        // If set, any object containing this key is compared as the value at the key, at every level
+	UnwrapSingletons      bool                          // If true, a one-element array holding an object is compared as that object when the other value is an object
+	WildcardValue         string                        // If set, a string with this value in the first file accepts any value at the same path in the second, which must still be present
+	KeysOnly              bool                          // If true, only compare keys/structure, not values
+	ArrayTypeCheck        bool                          // If true, elements of arrays compared by position only have to have the same JSON type
+	RegexMatches          map[string]string             // Map of key paths to regex patterns for value matching
+	LevenshteinKeys       map[string]bool               // Map of key paths to apply Levenshtein distance matching
+	LevenshteinThreshold  int                           // Maximum Levenshtein distance to consider strings as equal
+	SemverKeys            map[string]bool               // Map of key paths whose values are compared as semantic versions
+	UUIDKeys              map[string]bool               // Map of key paths whose values are compared as UUIDs, ignoring case, hyphens and braces
+	CurrencyKeys          map[string]bool               // Map of key paths whose values are compared as currency amounts, ignoring currency symbols and grouping
+	TypeNormalizers       map[string]Normalizer         // Map of JSON type names ("string", "number") to normalizers applied to values of that type before comparison, except at paths with a path-scoped comparator
+	IgnoreKeyNames        []string                      // Key names dropped from objects at every level before comparison
+	UnitKeys              map[string]string             // Map of key paths to a unit kind ("bytes" or "si") whose values are compared after parsing units
+	ExecComparators       map[string]string             // Map of key paths to external commands deciding whether the values there are equal
+	ExecTimeout           time.Duration                 // Maximum time an external comparator may run (0 for the default)
+	RenameKeys            map[string]string             // Map of first-file key names or paths to the key name they are compared as
+	StripKeyPrefixLeft    string                        // Prefix removed from the keys of first-file objects that start with it, at every level
+	StripKeyPrefixRight   string                        // Prefix removed from the keys of second-file objects that start with it, at every level
+	KeyAliases            map[string]string             // Map of synonym key names, in either file, to the canonical name they are compared as
+	SampleArrays          int                           // Number of index-aligned elements compared in longer arrays, chosen at random (0 compares all)
+	SampleSeed            int64                         // Seed for choosing the sampled elements
+	SampledArrays         *[]ArraySample                `json:"-"` // If set, arrays that were only sampled are recorded here
+	AutoArrayKey          bool                          // If true, arrays of objects are matched by an inferred identity key when one is found
+	ArrayKeys             *[]ArrayKeyChoice             `json:"-"` // If set, the keys inferred for AutoArrayKey are recorded here
+	ArrayMatches          *[]ArrayMatch                 `json:"-"` // If set, the element pairings of arrays compared regardless of order are recorded here
+	SeverityOverrides     map[string]Severity           // Map of paths to the severity of differences at or under them, overriding the type-based default
+	PathTags              map[string][]string           // Map of paths to the tags of differences at or under them
+	IgnorePaths           []string                      // Path expressions whose differences are not reported
+	OnlyPaths             []string                      // If set, only differences matching one of these path expressions are reported
+	Weights               map[string]float64            // Map of path expressions to the weight of the leaves under them in SimilarityScore (1 if not set)
+	IgnoreOrderPaths      []string                      // Path expressions of arrays whose elements are compared regardless of order
+	MaxArrayDiffs         int                           // Maximum differing elements reported per array before summarizing (0 for no limit)
+	ArrayLengthTolerance  int                           // Array length differences up to this many elements are not reported
+	MaxDepth              int                           // Nesting depth of objects and arrays below which values are not compared (0 for the default of 10000)
+	RequiredKeys          map[string]map[string]bool    // Map of object paths to key names whose absence from the second object is reported as RequiredMissing ("" is the root)
+	OneofGroups           map[string][][]string         // Map of object paths to groups of fields that are alternatives of a oneof ("" is the root)
+	IgnoreExtraAt         map[string]bool               // Set of object paths where keys only in the second object are ignored ("" is the root)
+	DefaultExtraTypes     map[string]bool               // JSON type names whose default value (false, 0, "", [], {} or null) is ignored on a key only in the second object
+	IgnoreWhen            []ConditionalIgnore           // Rules ignoring a field while a sibling field has a given value in both objects
+	DerivedFields         []DerivedField                // Fields ignored while the sibling fields they are computed from are equal in both objects
+	FuzzyMatches          *[]FuzzyMatch                 `json:"-"` // If set, values that were only equal within a threshold are recorded here
+	Promotions            *[]Promotion                  `json:"-"` // If set, values that were only equal once converted to a common numeric type are recorded here
+	Context               context.Context               `json:"-"` // If set, objects and arrays are no longer compared once it is done, leaving the differences partial
+	OnDiff                func(Diff)                    `json:"-"` // If set, called with every difference found, before any filtering
+	CacheSubtrees         bool                          // If true, differences between repeated identical pairs of objects or arrays are computed once and reused
+	depth                 int                           // Nesting depth of the values being compared, tracked during traversal
+	cache                 *comparisonCache              // Memoized differences for CacheSubtrees, shared during one comparison
+	collator              *collate.Collator             // Collator for Collation, shared during one comparison
+}
+
+// ReadOptions contains options for reading and parsing JSON files
+type ReadOptions struct {
+	Concise          bool            // If true, validation messages are not printed
+	ResolveRefs      bool            // If true, local "$ref" pointers are replaced by the fragments they reference
+	ResolveFileRefs  bool            // If true, "$ref" pointers into other files are resolved too, as well as local ones
+	MultiDoc         bool            // If true, the file may contain several concatenated JSON documents
+	XML              bool            // If true, the file is parsed as XML and converted to a JSON-like structure
+	XMLArrays        map[string]bool // XML element names that always become arrays, even when they occur once
+	NormalizeNumbers bool            // If true, numbers are kept as exact text in canonical form instead of float64
+	AllowNonFinite   bool            // If true, the non-standard NaN, Infinity and -Infinity number literals are accepted
+	ExpandEnv        bool            // If true, ${VAR} and $VAR placeholders are replaced by environment variables before parsing
+	OnMissingEnv     string          // How ExpandEnv handles unset variables: MissingEnvKeep (the default) or MissingEnvError
+	RecordLocations  bool            // If true, where each value starts in the file is recorded in JSONFile.Locations
+}
@@ -0,0 +1,272 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxOptimalMatch is the largest number of elements left unpaired on either
+// side, after pairing identical elements, that are matched optimally. Beyond
+// it the remaining elements are reported as only in one file, since the cost
+// of optimal matching grows with the cube of their number.
+const maxOptimalMatch = 500
+
+// ArrayMatch records how the elements of an array compared regardless of order
+// were paired
+type ArrayMatch struct {
+	Path  string
+	Pairs [][2]int // Index in the first array and in the second of each paired element, in first-array order
+}
+
+// ignoresOrder reports whether the array at path is compared without regard
+// to the order of its elements
+func ignoresOrder(path string, options CompareOptions) bool {
+	return matchAnyPathExpr(options.IgnoreOrderPaths, path)
+}
+
+// compareUnorderedArrays compares two arrays as multisets, pairing elements
+// so that as few differences as possible are reported. Identical elements
+// are paired first; the rest are paired by minimum-cost matching, where a
+// pair costs the differences found between its elements and an unpaired
+// element costs one. Only objects with objects and arrays with arrays are
+// paired when not equal, and only if that reports fewer differences than
+// leaving both unpaired. Ties go to the pairing that moves elements the
+// least. Paired elements are reported at their index in the first array;
+// unpaired elements as only in one file, at their index in that file's array.
+func compareUnorderedArrays(arr1, arr2 []interface{}, path string, options CompareOptions) []Diff {
+	differences := []Diff{}
+	match1 := make([]int, len(arr1))
+	match2 := make([]int, len(arr2))
+	for i := range match1 {
+		match1[i] = -1
+	}
+	for j := range match2 {
+		match2[j] = -1
+	}
+
+	// Identical elements can always be paired without losing optimality
+	for i, elem1 := range arr1 {
+		for j, elem2 := range arr2 {
+			if match2[j] < 0 && jsonEqual(elem1, elem2) {
+				match1[i], match2[j] = j, i
+				break
+			}
+		}
+	}
+	matchRemaining(arr1, arr2, match1, match2, path, options)
+
+	for i, elem1 := range arr1 {
+		newPath := fmt.Sprintf("%s[%d]", path, i)
+		if j := match1[i]; j >= 0 {
+			if !valuesEqual(elem1, arr2[j], newPath, options) {
+				differences = append(differences, findDifferencesWithParent(elem1, arr2[j], newPath, ParentArray, options)...)
+			}
+			continue
+		}
+		differences = append(differences, Diff{
+			Path:       newPath,
+			Type:       KeyOnlyInFirst,
+			Value1:     elem1,
+			Value2:     nil,
+			ParentType: ParentArray,
+		})
+	}
+
+	for j, elem2 := range arr2 {
+		if match2[j] < 0 {
+			differences = append(differences, Diff{
+				Path:       fmt.Sprintf("%s[%d]", path, j),
+				Type:       KeyOnlyInSecond,
+				Value1:     nil,
+				Value2:     elem2,
+				ParentType: ParentArray,
+			})
+		}
+	}
+
+	if options.ArrayMatches != nil {
+		match := ArrayMatch{Path: path, Pairs: [][2]int{}}
+		for i, j := range match1 {
+			if j >= 0 {
+				match.Pairs = append(match.Pairs, [2]int{i, j})
+			}
+		}
+		*options.ArrayMatches = append(*options.ArrayMatches, match)
+	}
+
+	return differences
+}
+
+// matchRemaining pairs the elements not yet paired in match1 and match2 by
+// minimum-cost assignment, recording the pairs worth making in both
+func matchRemaining(arr1, arr2 []interface{}, match1, match2 []int, path string, options CompareOptions) {
+	var rest1, rest2 []int
+	for i, j := range match1 {
+		if j < 0 {
+			rest1 = append(rest1, i)
+		}
+	}
+	for j, i := range match2 {
+		if i < 0 {
+			rest2 = append(rest2, j)
+		}
+	}
+	if len(rest1) == 0 || len(rest2) == 0 || len(rest1) > maxOptimalMatch || len(rest2) > maxOptimalMatch {
+		return
+	}
+
+	// The differences found while weighing pairs are only counted, not reported or recorded
+	options.OnDiff = nil
+	options.FuzzyMatches = nil
+	options.Promotions = nil
+	options.SampledArrays = nil
+	options.ArrayKeys = nil
+	options.ArrayMatches = nil
+
+	// Costs are scaled so that the number of differences always dominates the
+	// distance between the paired indices, which only breaks ties
+	n := len(rest1)
+	if len(rest2) > n {
+		n = len(rest2)
+	}
+	scale := int64(len(arr1)+len(arr2)) * int64(len(arr1)+len(arr2))
+	scale++
+
+	cost := make([][]int64, n)
+	for r := range cost {
+		cost[r] = make([]int64, n)
+		for c := range cost[r] {
+			if r >= len(rest1) || c >= len(rest2) {
+				cost[r][c] = scale // An element left unpaired
+				continue
+			}
+			i, j := rest1[r], rest2[c]
+			distance := int64(i - j)
+			if distance < 0 {
+				distance = -distance
+			}
+			cost[r][c] = int64(pairCost(arr1[i], arr2[j], fmt.Sprintf("%s[%d]", path, i), options))*scale + distance
+		}
+	}
+
+	for r, c := range minCostAssignment(cost) {
+		if r >= len(rest1) || c >= len(rest2) || cost[r][c] >= 2*scale {
+			continue
+		}
+		i, j := rest1[r], rest2[c]
+		match1[i], match2[j] = j, i
+	}
+}
+
+// pairCost returns the number of differences reported by pairing two
+// elements, capped at 2, the cost of leaving both unpaired. Elements other
+// than two objects or two arrays are only worth pairing when equal.
+func pairCost(elem1, elem2 interface{}, path string, options CompareOptions) int {
+	if valuesEqual(elem1, elem2, path, options) {
+		return 0
+	}
+	if !isComplex(elem1) || jsonTypeName(elem1) != jsonTypeName(elem2) {
+		return 2
+	}
+	if n := len(findDifferencesWithParent(elem1, elem2, path, ParentArray, options)); n < 2 {
+		return n
+	}
+	return 2
+}
+
+// minCostAssignment solves the assignment problem for a square cost matrix
+// with the Hungarian algorithm, returning the column assigned to each row.
+// The result depends only on the costs, so equal inputs give equal pairings.
+func minCostAssignment(cost [][]int64) []int {
+	n := len(cost)
+	const inf = int64(1) << 62
+
+	// Potentials and the matching use 1-based indices, with column 0 as a sentinel
+	u := make([]int64, n+1)
+	v := make([]int64, n+1)
+	rowOf := make([]int, n+1)
+	way := make([]int, n+1)
+
+	for r := 1; r <= n; r++ {
+		rowOf[0] = r
+		c0 := 0
+		minv := make([]int64, n+1)
+		used := make([]bool, n+1)
+		for c := range minv {
+			minv[c] = inf
+		}
+		for {
+			used[c0] = true
+			r0, delta, c1 := rowOf[c0], inf, 0
+			for c := 1; c <= n; c++ {
+				if used[c] {
+					continue
+				}
+				reduced := cost[r0-1][c-1] - u[r0] - v[c]
+				if reduced < minv[c] {
+					minv[c], way[c] = reduced, c0
+				}
+				if minv[c] < delta {
+					delta, c1 = minv[c], c
+				}
+			}
+			for c := 0; c <= n; c++ {
+				if used[c] {
+					u[rowOf[c]] += delta
+					v[c] -= delta
+				} else {
+					minv[c] -= delta
+				}
+			}
+			c0 = c1
+			if rowOf[c0] == 0 {
+				break
+			}
+		}
+		for c0 != 0 {
+			c1 := way[c0]
+			rowOf[c0] = rowOf[c1]
+			c0 = c1
+		}
+	}
+
+	assignment := make([]int, n)
+	for c := 1; c <= n; c++ {
+		assignment[rowOf[c]-1] = c - 1
+	}
+	return assignment
+}
+
+// prefixArrayMatches prepends prefix to the path of every match
+func prefixArrayMatches(matches []ArrayMatch, prefix string) []ArrayMatch {
+	for i := range matches {
+		matches[i].Path = prefixPath(prefix, matches[i].Path)
+	}
+	return matches
+}
+
+// formatArrayMatchReport renders how the elements of each array compared
+// regardless of order were paired, e.g. "tags: [0]->[2] [1]->[0]"
+func formatArrayMatchReport(matches []ArrayMatch, indexBase int) string {
+	if len(matches) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\nPaired the elements of %d arrays regardless of order:\n", len(matches))
+	for _, match := range matches {
+		pairs := make([]string, len(match.Pairs))
+		for k, pair := range match.Pairs {
+			pairs[k] = fmt.Sprintf("[%d]->[%d]", pair[0]+indexBase, pair[1]+indexBase)
+		}
+		if len(pairs) == 0 {
+			pairs = []string{"no elements paired"}
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", displayPath(match.Path), strings.Join(pairs, " "))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIgnoreOrderPaths(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"tags":    []interface{}{"a", "b", "c", "c"},
+		"steps":   []interface{}{"build", "test"},
+		"users":   []interface{}{map[string]interface{}{"roles": []interface{}{"admin", "dev"}}},
+		"removed": []interface{}{1.0, 2.0},
+	}
+	obj2 := map[string]interface{}{
+		"tags":    []interface{}{"c", "a", "b", "d"},
+		"steps":   []interface{}{"test", "build"},
+		"users":   []interface{}{map[string]interface{}{"roles": []interface{}{"dev", "admin"}}},
+		"removed": []interface{}{2.0, 1.0},
+	}
+	options := CompareOptions{IgnoreOrderPaths: []string{"tags", "users[*].roles", "removed"}}
+
+	differences := findDifferencesWithOptions(obj1, obj2, "", options)
+
+	// Only the positional steps array and the unmatched tags are reported
+	expected := map[string]DiffType{
+		"steps[0]": ValueMismatch,
+		"steps[1]": ValueMismatch,
+		"tags[3]":  KeyOnlyInFirst,
+	}
+	var addedTag bool
+	for _, diff := range differences {
+		if diff.Path == "tags[3]" && diff.Type == KeyOnlyInSecond {
+			if diff.Value2 != "d" {
+				t.Errorf("Expected d added at tags[3], got %v", diff.Value2)
+			}
+			addedTag = true
+			continue
+		}
+		want, ok := expected[diff.Path]
+		if !ok || diff.Type != want {
+			t.Errorf("Unexpected difference %s (%s)", diff.Path, diff.Type)
+			continue
+		}
+		delete(expected, diff.Path)
+	}
+	if !addedTag || len(expected) > 0 {
+		t.Errorf("Missing differences: %v (tag added reported: %v)", expected, addedTag)
+	}
+}
+
+func TestIgnoreOrderWithArrayKey(t *testing.T) {
+	// Arrays with an inferred key are matched by it; others fall back to order-insensitive matching
+	obj1 := map[string]interface{}{"items": []interface{}{
+		map[string]interface{}{"id": 1.0, "qty": 1.0},
+		map[string]interface{}{"id": 2.0, "qty": 2.0},
+	}}
+	obj2 := map[string]interface{}{"items": []interface{}{
+		map[string]interface{}{"id": 2.0, "qty": 3.0},
+		map[string]interface{}{"id": 1.0, "qty": 1.0},
+	}}
+
+	differences := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{AutoArrayKey: true, IgnoreOrderPaths: []string{"items"}})
+	if len(differences) != 1 || differences[0].Path != "items[1].qty" {
+		t.Errorf("Expected the keyed match to report items[1].qty, got %v", differences)
+	}
+
+	// Without a key the changed element is still paired, since that reports fewer differences
+	differences = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{IgnoreOrderPaths: []string{"items"}})
+	if len(differences) != 1 || differences[0].Path != "items[1].qty" {
+		t.Errorf("Expected the changed element to be paired, got %v", differences)
+	}
+}
+
+func TestUnorderedArrayMatching(t *testing.T) {
+	obj := func(a, b float64) map[string]interface{} {
+		return map[string]interface{}{"a": a, "b": b}
+	}
+
+	// Each element is paired with the element it differs from least, wherever it is
+	var matches []ArrayMatch
+	options := CompareOptions{IgnoreOrderPaths: []string{""}, ArrayMatches: &matches}
+	arr1 := []interface{}{obj(1, 1), obj(2, 2), "x"}
+	arr2 := []interface{}{"y", obj(2, 9), obj(1, 8)}
+	var got []string
+	for _, diff := range findDifferencesWithOptions(arr1, arr2, "", options) {
+		got = append(got, formatDiff(diff))
+	}
+	expected := []string{
+		"[0].b: value mismatch - 1 vs 8",
+		"[1].b: value mismatch - 2 vs 9",
+		"[2]: key exists only in first file",
+		"[0]: key exists only in second file",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if report := formatArrayMatchReport(matches, 1); !strings.Contains(report, ".: [1]->[3] [2]->[2]\n") {
+		t.Errorf("Unexpected match report %q", report)
+	}
+
+	// Elements differing in two or more places are reported as removed and added
+	differences := findDifferencesWithOptions([]interface{}{obj(1, 1)}, []interface{}{obj(2, 2)}, "", CompareOptions{IgnoreOrderPaths: []string{""}})
+	if len(differences) != 2 || differences[0].Type != KeyOnlyInFirst || differences[1].Type != KeyOnlyInSecond {
+		t.Errorf("Expected the element as removed and added, got %v", differences)
+	}
+
+	// Equally good pairings go to the closest index, every time
+	for n := 0; n < 10; n++ {
+		differences = findDifferencesWithOptions(
+			[]interface{}{obj(1, 1), obj(1, 1)},
+			[]interface{}{obj(1, 3), obj(1, 2), obj(1, 4)},
+			"", CompareOptions{IgnoreOrderPaths: []string{""}})
+		got = got[:0]
+		for _, diff := range differences {
+			got = append(got, formatDiff(diff))
+		}
+		expected = []string{
+			"[0].b: value mismatch - 1 vs 3",
+			"[1].b: value mismatch - 1 vs 2",
+			"[2]: key exists only in second file",
+		}
+		if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+			t.Fatalf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestMinCostAssignment(t *testing.T) {
+	cost := [][]int64{
+		{4, 1, 3},
+		{2, 0, 5},
+		{3, 2, 2},
+	}
+	assignment := minCostAssignment(cost)
+	total := int64(0)
+	for r, c := range assignment {
+		total += cost[r][c]
+	}
+	if total != 5 {
+		t.Errorf("Expected a total cost of 5, got %d with %v", total, assignment)
+	}
+}
@@ -0,0 +1,383 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// marshalDifferences renders differences as indented JSON.
+// encoding/json writes map keys in sorted order, so nested Value1/Value2
+// objects serialize identically across runs and the output is safe to use
+// as a golden file.
+//
+// Non-finite numbers, which JSON cannot represent, are written as the strings
+// "NaN", "Infinity" and "-Infinity".
+func marshalDifferences(differences []Diff) ([]byte, error) {
+	encoded := make([]Diff, len(differences))
+	for i, diff := range differences {
+		diff.Value1 = encodeNonFinite(diff.Value1)
+		diff.Value2 = encodeNonFinite(diff.Value2)
+		encoded[i] = diff
+	}
+	return json.MarshalIndent(encoded, "", "  ")
+}
+
+// writeDifferencesJSON writes differences as indented JSON to filePath
+func writeDifferencesJSON(differences []Diff, filePath string) error {
+	outputJSON, err := marshalDifferences(differences)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, outputJSON, 0644)
+}
+
+// writeDifferencesJSONL writes each difference as compact JSON on its own
+// line, for line-based tools and log pipelines. Non-finite numbers are
+// written as strings, as in marshalDifferences.
+func writeDifferencesJSONL(w io.Writer, differences []Diff) error {
+	for _, diff := range differences {
+		diff.Value1 = encodeNonFinite(diff.Value1)
+		diff.Value2 = encodeNonFinite(diff.Value2)
+		line, err := json.Marshal(diff)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshalOptions renders the effective comparison options as indented JSON.
+// Map options are written with sorted keys and type normalizers by name; the
+// fuzzy match recorder is internal state and is left out. Unset lists are
+// written as [] and unset maps as {} rather than null.
+func marshalOptions(options CompareOptions) ([]byte, error) {
+	if options.IgnoreKeyNames == nil {
+		options.IgnoreKeyNames = []string{}
+	}
+	if options.IgnoreWhen == nil {
+		options.IgnoreWhen = []ConditionalIgnore{}
+	}
+	if options.DerivedFields == nil {
+		options.DerivedFields = []DerivedField{}
+	}
+	if options.TypeNormalizers == nil {
+		options.TypeNormalizers = map[string]Normalizer{}
+	}
+	if options.DefaultExtraTypes == nil {
+		options.DefaultExtraTypes = map[string]bool{}
+	}
+	for _, list := range []*[]string{&options.KeyStyles, &options.IgnorePaths, &options.OnlyPaths, &options.IgnoreOrderPaths} {
+		if *list == nil {
+			*list = []string{}
+		}
+	}
+	return json.MarshalIndent(options, "", "  ")
+}
+
+// formatDiffText renders a difference in the human-readable console format
+func formatDiffText(diff Diff) string {
+	switch diff.Type {
+	case ValueMismatch:
+		if diff.Detail != "" {
+			return fmt.Sprintf("%s: value mismatch (%s)\n- %v\n+ %v\n", diff.Path, diff.Detail, diff.Value1, diff.Value2)
+		}
+		return fmt.Sprintf("%s: value mismatch\n- %v\n+ %v\n", diff.Path, diff.Value1, diff.Value2)
+	case KeyOnlyInFirst:
+		return fmt.Sprintf("%s: key exists only in first file\n", diff.Path)
+	case KeyOnlyInSecond:
+		return fmt.Sprintf("%s: key exists only in second file\n", diff.Path)
+	case ArrayLength:
+		return fmt.Sprintf("%s: array length mismatch\n- %v\n+ %v\n", diff.Path, diff.Value1, diff.Value2)
+	case TypeMismatch:
+		return fmt.Sprintf("%s: type mismatch\n- %v\n+ %v\n", diff.Path, diff.Value1, diff.Value2)
+	case DocumentCount:
+		return fmt.Sprintf("document count mismatch\n- %v\n+ %v\n", diff.Value1, diff.Value2)
+	case KeyCaseMismatch:
+		return fmt.Sprintf("%s: key case mismatch\n- %v\n+ %v\n", diff.Path, diff.Value1, diff.Value2)
+	case ArrayDiffsTruncated:
+		return fmt.Sprintf("... and %v more differing elements in %s\n", diff.Value1, diff.Path)
+	case DepthExceeded:
+		return fmt.Sprintf("%s: nesting deeper than %v levels, not compared\n", diff.Path, diff.Value1)
+	case Moved:
+		return fmt.Sprintf("%s: moved from %v\n", diff.Path, diff.Value1)
+	case RequiredMissing:
+		return fmt.Sprintf("%s: required key missing from second file\n", diff.Path)
+	case OneofChanged:
+		return fmt.Sprintf("%s: oneof branch changed\n- %v\n+ %v\n", diff.Path, diff.Value1, diff.Value2)
+	default:
+		return ""
+	}
+}
+
+// filterPresenceDiffs keeps only the differences for keys that exist in one
+// file but not the other, including missing required keys, dropping value,
+// type and array length differences
+func filterPresenceDiffs(differences []Diff) []Diff {
+	filtered := []Diff{}
+	for _, diff := range differences {
+		if diff.Type == KeyOnlyInFirst || diff.Type == KeyOnlyInSecond || diff.Type == RequiredMissing {
+			filtered = append(filtered, diff)
+		}
+	}
+	return filtered
+}
+
+// filterAdditions keeps only the differences for what the second file adds:
+// keys and elements only in the second file, and arrays that grew
+func filterAdditions(differences []Diff) []Diff {
+	filtered := []Diff{}
+	for _, diff := range differences {
+		if diff.Type == KeyOnlyInSecond || (diff.Type == ArrayLength && arrayGrew(diff)) {
+			filtered = append(filtered, diff)
+		}
+	}
+	return filtered
+}
+
+// filterRemovals keeps only the differences for what the second file drops:
+// keys and elements only in the first file, missing required keys, and
+// arrays that shrank
+func filterRemovals(differences []Diff) []Diff {
+	filtered := []Diff{}
+	for _, diff := range differences {
+		if diff.Type == KeyOnlyInFirst || diff.Type == RequiredMissing || (diff.Type == ArrayLength && arrayShrank(diff)) {
+			filtered = append(filtered, diff)
+		}
+	}
+	return filtered
+}
+
+// arrayShrank reports whether an ArrayLength difference is for an array that
+// is shorter in the second file
+func arrayShrank(diff Diff) bool {
+	len1, ok1 := diff.Value1.(int)
+	len2, ok2 := diff.Value2.(int)
+	return ok1 && ok2 && len2 < len1
+}
+
+// arrayGrew reports whether an ArrayLength difference is for an array that
+// is longer in the second file
+func arrayGrew(diff Diff) bool {
+	len1, ok1 := diff.Value1.(int)
+	len2, ok2 := diff.Value2.(int)
+	return ok1 && ok2 && len2 > len1
+}
+
+// formatStructureDelta renders a key presence difference as "+ path" for a key
+// added in the second file or "- path" for a key removed from it
+func formatStructureDelta(diff Diff) string {
+	switch diff.Type {
+	case KeyOnlyInFirst, RequiredMissing:
+		return fmt.Sprintf("- %s\n", diff.Path)
+	case KeyOnlyInSecond:
+		return fmt.Sprintf("+ %s\n", diff.Path)
+	default:
+		return ""
+	}
+}
+
+// headDifferences splits differences into the first n to show in detail and the rest.
+// An n of 0 or less shows every difference.
+func headDifferences(differences []Diff, n int) ([]Diff, []Diff) {
+	if n <= 0 || len(differences) <= n {
+		return differences, nil
+	}
+	return differences[:n], differences[n:]
+}
+
+// formatRemainingSummary tallies the differences that weren't shown by type,
+// given their counts, e.g.
+// "... and 12 more differences (value_mismatch: 5, key_only_in_second: 7)"
+func formatRemainingSummary(stats Stats) string {
+	if stats.Total == 0 {
+		return ""
+	}
+
+	// List types in their declaration order so the summary is stable
+	var parts []string
+	for t := ValueMismatch; t.String() != "unknown"; t++ {
+		if stats.ByType[t] > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", t, stats.ByType[t]))
+		}
+	}
+
+	return fmt.Sprintf("... and %d more differences (%s)\n", stats.Total, strings.Join(parts, ", "))
+}
+
+// gitHubAnnotationLevel returns the GitHub Actions annotation level for a
+// difference: structural changes are errors, value changes are warnings
+func gitHubAnnotationLevel(diffType DiffType) string {
+	switch diffType {
+	case KeyOnlyInFirst, KeyOnlyInSecond, ArrayLength, TypeMismatch, DocumentCount, DepthExceeded, RequiredMissing, OneofChanged:
+		return "error"
+	default:
+		return "warning"
+	}
+}
+
+// gitHubEscaper escapes annotation messages for GitHub Actions workflow commands
+var gitHubEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+
+// gitHubPropertyEscaper escapes annotation properties such as the file name
+var gitHubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+// formatGitHubAnnotation renders a difference as a GitHub Actions workflow
+// command, e.g. "::warning file=new.json::age: value mismatch (30 -> 31)",
+// so it shows up as an annotation on the pull request
+func formatGitHubAnnotation(diff Diff, file string) string {
+	var message string
+	switch diff.Type {
+	case ValueMismatch:
+		message = fmt.Sprintf("%s: value mismatch (%v -> %v)", diff.Path, diff.Value1, diff.Value2)
+		if diff.Detail != "" {
+			message += fmt.Sprintf(" (%s)", diff.Detail)
+		}
+	case KeyOnlyInFirst:
+		message = fmt.Sprintf("%s: key exists only in first file", diff.Path)
+	case KeyOnlyInSecond:
+		message = fmt.Sprintf("%s: key exists only in second file", diff.Path)
+	case ArrayLength:
+		message = fmt.Sprintf("%s: array length mismatch (%v -> %v)", diff.Path, diff.Value1, diff.Value2)
+	case TypeMismatch:
+		message = fmt.Sprintf("%s: type mismatch (%v -> %v)", diff.Path, diff.Value1, diff.Value2)
+	case DocumentCount:
+		message = fmt.Sprintf("document count mismatch (%v -> %v)", diff.Value1, diff.Value2)
+	case KeyCaseMismatch:
+		message = fmt.Sprintf("%s: key case mismatch (%v -> %v)", diff.Path, diff.Value1, diff.Value2)
+	case ArrayDiffsTruncated:
+		message = fmt.Sprintf("... and %v more differing elements in %s", diff.Value1, diff.Path)
+	case DepthExceeded:
+		message = fmt.Sprintf("%s: nesting deeper than %v levels, not compared", diff.Path, diff.Value1)
+	case Moved:
+		message = fmt.Sprintf("%s: moved from %v", diff.Path, diff.Value1)
+	case RequiredMissing:
+		message = fmt.Sprintf("%s: required key missing from second file", diff.Path)
+	case OneofChanged:
+		message = fmt.Sprintf("%s: oneof branch changed (%v -> %v)", diff.Path, diff.Value1, diff.Value2)
+	default:
+		return ""
+	}
+
+	// Recorded locations let the annotation point at the line in the second file
+	location := ""
+	if diff.Loc2 != nil {
+		location = fmt.Sprintf(",line=%d,col=%d", diff.Loc2.Line, diff.Loc2.Column)
+	}
+
+	return fmt.Sprintf("::%s file=%s%s::%s\n", gitHubAnnotationLevel(diff.Type),
+		gitHubPropertyEscaper.Replace(file), location, gitHubEscaper.Replace(message))
+}
+
+// porcelainCodes are the single-letter codes used by -porcelain. They are part
+// of a stable interface and must not change.
+var porcelainCodes = map[DiffType]string{
+	ValueMismatch:   "M",
+	KeyCaseMismatch: "M",
+	OneofChanged:    "M",
+	KeyOnlyInSecond: "A",
+	KeyOnlyInFirst:  "D",
+	RequiredMissing: "D",
+	TypeMismatch:    "T",
+	ArrayLength:     "L",
+	DocumentCount:   "L",
+	Moved:           "R",
+	DepthExceeded:   "X",
+}
+
+var porcelainPathEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+// formatPorcelain renders a difference as one stable, tab-separated line:
+// "<code> <path>\t<value1>\t<value2>". Values are compact JSON and a value
+// missing from one side is left empty. Array summaries have no line.
+func formatPorcelain(diff Diff) (string, error) {
+	code, ok := porcelainCodes[diff.Type]
+	if !ok {
+		return "", nil
+	}
+
+	value1, err := porcelainValue(diff.Value1, diff.Type != KeyOnlyInSecond)
+	if err != nil {
+		return "", err
+	}
+	value2, err := porcelainValue(diff.Value2, diff.Type != KeyOnlyInFirst && diff.Type != RequiredMissing)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s %s\t%s\t%s\n", code, porcelainPathEscaper.Replace(diff.Path), value1, value2), nil
+}
+
+// porcelainValue encodes a value as compact JSON on one line; JSON string
+// escaping already keeps tabs and newlines out of the output
+func porcelainValue(value interface{}, present bool) (string, error) {
+	if !present {
+		return "", nil
+	}
+
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(encodeNonFinite(value)); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// limitedWriter passes writes through to w until limit bytes have been
+// written. A write that would go past the limit is discarded whole, along
+// with every write after it, so output is never cut off mid-difference.
+// A limit of 0 means no limit.
+type limitedWriter struct {
+	w         io.Writer
+	limit     int
+	written   int
+	truncated bool
+}
+
+// newLimitedWriter returns a limitedWriter writing to w
+func newLimitedWriter(w io.Writer, limit int) *limitedWriter {
+	return &limitedWriter{w: w, limit: limit}
+}
+
+// Write implements io.Writer. Discarded writes still report success so
+// callers keep going; Truncated tells whether anything was dropped.
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if lw.truncated || (lw.limit > 0 && lw.written+len(p) > lw.limit) {
+		lw.truncated = true
+		return len(p), nil
+	}
+	n, err := lw.w.Write(p)
+	lw.written += n
+	return n, err
+}
+
+// Truncated reports whether any output was discarded
+func (lw *limitedWriter) Truncated() bool {
+	return lw.truncated
+}
+
+// stdoutOutputs reports whether -output-json writes its differences to stdout
+// and whether stdout carries machine-readable output at all, in which case
+// human-readable output is kept off it. files are where the other file
+// outputs are written, with "-" for stdout, and streaming is whether an
+// output that always goes to stdout, such as -output-sse, is on. Only
+// -output-json's own destination decides where its differences go.
+func stdoutOutputs(outputJSON string, files []string, streaming bool) (bool, bool) {
+	jsonToStdout := outputJSON == "-"
+	machineOutput := jsonToStdout || streaming
+	for _, file := range files {
+		if file == "-" {
+			machineOutput = true
+		}
+	}
+	return jsonToStdout, machineOutput
+}
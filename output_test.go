@@ -0,0 +1,348 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputJSONDeterministic(t *testing.T) {
+	// Nested objects carried in Value1/Value2 must serialize with a stable key order
+	obj1 := map[string]interface{}{
+		"removed": map[string]interface{}{"z": 1.0, "y": 2.0, "x": map[string]interface{}{"c": true, "b": false, "a": nil}},
+		"changed": []interface{}{map[string]interface{}{"q": "1", "p": "2"}},
+	}
+	obj2 := map[string]interface{}{
+		"added":   map[string]interface{}{"m": "x", "l": "y", "k": "z"},
+		"changed": []interface{}{map[string]interface{}{"q": "1", "p": "3"}, "extra"},
+	}
+
+	dir := t.TempDir()
+	var outputs [][]byte
+	for i := 0; i < 2; i++ {
+		diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{})
+		outputPath := filepath.Join(dir, fmt.Sprintf("diff%d.json", i))
+		if err := writeDifferencesJSON(diffs, outputPath); err != nil {
+			t.Fatalf("Failed to write differences: %v", err)
+		}
+		output, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read differences: %v", err)
+		}
+		outputs = append(outputs, output)
+	}
+
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Errorf("Expected byte-identical output across runs, got:\n%s\nand:\n%s", outputs[0], outputs[1])
+	}
+	if !bytes.Contains(outputs[0], []byte(`"k": "z",`+"\n"+`      "l": "y",`)) {
+		t.Errorf("Expected nested keys in sorted order, got:\n%s", outputs[0])
+	}
+}
+
+func TestMarshalOptions(t *testing.T) {
+	var matches []FuzzyMatch
+	options := CompareOptions{
+		IgnoreCase:      true,
+		LevenshteinKeys: map[string]bool{"name": true},
+		IgnoreExtraAt:   map[string]bool{"": true},
+		IgnoreWhen:      []ConditionalIgnore{{Field: "status", Value: "cancelled", Path: "discount"}},
+		FuzzyMatches:    &matches,
+	}
+
+	optionsJSON, err := marshalOptions(options)
+	if err != nil {
+		t.Fatalf("Failed to marshal options: %v", err)
+	}
+
+	for _, expected := range []string{
+		`"IgnoreCase": true`,
+		`"LevenshteinKeys": {`,
+		`"name": true`,
+		`"IgnoreExtraAt": {`,
+		`"Field": "status"`,
+	} {
+		if !bytes.Contains(optionsJSON, []byte(expected)) {
+			t.Errorf("Expected options JSON to contain %s, got:\n%s", expected, optionsJSON)
+		}
+	}
+	for _, name := range []string{"IgnoreKeyNames", "IgnorePaths", "OnlyPaths", "IgnoreOrderPaths", "DerivedFields"} {
+		if !bytes.Contains(optionsJSON, []byte(`"`+name+`": []`)) {
+			t.Errorf("Expected unset list %s to be written as [], got:\n%s", name, optionsJSON)
+		}
+	}
+	for _, name := range []string{"TypeNormalizers", "DefaultExtraTypes"} {
+		if !bytes.Contains(optionsJSON, []byte(`"`+name+`": {}`)) {
+			t.Errorf("Expected unset map %s to be written as {}, got:\n%s", name, optionsJSON)
+		}
+	}
+
+	// Type normalizers are written by name, in the order they run
+	options.TypeNormalizers = make(map[string]Normalizer)
+	for _, spec := range []string{"string:trim", "string:lower", "number:round2"} {
+		if err := addTypeNormalizer(options.TypeNormalizers, spec); err != nil {
+			t.Fatalf("addTypeNormalizer(%q) returned error: %v", spec, err)
+		}
+	}
+	optionsJSON, err = marshalOptions(options)
+	if err != nil {
+		t.Fatalf("Failed to marshal options: %v", err)
+	}
+	if expected := "\"TypeNormalizers\": {\n    \"number\": [\n      \"round2\"\n    ],\n    \"string\": [\n      \"trim\",\n      \"lower\"\n    ]\n  }"; !bytes.Contains(optionsJSON, []byte(expected)) {
+		t.Errorf("Expected options JSON to contain %s, got:\n%s", expected, optionsJSON)
+	}
+	if bytes.Contains(optionsJSON, []byte("FuzzyMatches")) {
+		t.Errorf("Expected the fuzzy match recorder to be left out, got:\n%s", optionsJSON)
+	}
+}
+
+func TestWriteDifferencesJSONL(t *testing.T) {
+	differences := []Diff{
+		{Path: "age", Type: ValueMismatch, Value1: 30.0, Value2: 31.0, ParentType: ParentObject},
+		{Path: "tags[1]", Type: KeyOnlyInSecond, Value2: map[string]interface{}{"b": 1.0, "a": math.Inf(1)}, ParentType: ParentArray},
+	}
+
+	var buf bytes.Buffer
+	if err := writeDifferencesJSONL(&buf, differences); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one line per difference, got %q", buf.String())
+	}
+	for i, line := range lines {
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+			t.Fatalf("Line %d is not valid JSON: %v", i, err)
+		}
+		if decoded["path"] != differences[i].Path || decoded["type"] != differences[i].Type.String() {
+			t.Errorf("Line %d = %s", i, line)
+		}
+	}
+	if !strings.Contains(lines[1], `"value2":{"a":"Infinity","b":1}`) {
+		t.Errorf("Expected a compact value with non-finite numbers encoded, got %s", lines[1])
+	}
+}
+
+func TestLimitedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	out := newLimitedWriter(&buf, 10)
+
+	for _, s := range []string{"abcd\n", "efgh\n", "ij\n", "k\n"} {
+		if _, err := fmt.Fprint(out, s); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Whole writes are kept until one would pass the limit; nothing after it is written
+	if buf.String() != "abcd\nefgh\n" || !out.Truncated() {
+		t.Errorf("Expected the output to stop after 10 bytes, got %q (truncated %v)", buf.String(), out.Truncated())
+	}
+
+	buf.Reset()
+	out = newLimitedWriter(&buf, 0)
+	fmt.Fprint(out, "unlimited\n")
+	if buf.String() != "unlimited\n" || out.Truncated() {
+		t.Errorf("Expected no limit with 0, got %q", buf.String())
+	}
+}
+
+func TestFormatGitHubAnnotation(t *testing.T) {
+	testCases := []struct {
+		diff     Diff
+		expected string
+	}{
+		{
+			Diff{Path: "age", Type: ValueMismatch, Value1: 30.0, Value2: 31.0},
+			"::warning file=examples/example2.json::age: value mismatch (30 -> 31)\n",
+		},
+		{
+			Diff{Path: "address.zip", Type: KeyOnlyInFirst, Value1: "10001"},
+			"::error file=examples/example2.json::address.zip: key exists only in first file\n",
+		},
+		{
+			Diff{Path: "hobbies", Type: ArrayLength, Value1: 3, Value2: 2},
+			"::error file=examples/example2.json::hobbies: array length mismatch (3 -> 2)\n",
+		},
+		{
+			Diff{Path: "bio", Type: ValueMismatch, Value1: "100%\nsure", Value2: "no"},
+			"::warning file=examples/example2.json::bio: value mismatch (100%25%0Asure -> no)\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		if annotation := formatGitHubAnnotation(tc.diff, "examples/example2.json"); annotation != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, annotation)
+		}
+	}
+
+	// Commas and colons in the file name would end the property
+	if annotation := formatGitHubAnnotation(Diff{Path: "a", Type: TypeMismatch}, "C:\\a,b.json"); annotation[:30] != "::error file=C%3A\\a%2Cb.json::" {
+		t.Errorf("Expected the file name to be escaped, got %q", annotation)
+	}
+}
+
+func TestStructureDelta(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"name":    "John",
+		"age":     30.0,
+		"address": map[string]interface{}{"zip": "10001"},
+		"tags":    []interface{}{"a"},
+	}
+	obj2 := map[string]interface{}{
+		"name":    "Jane",
+		"age":     "30",
+		"address": map[string]interface{}{"postcode": "10001"},
+		"tags":    []interface{}{"a", "b"},
+		"email":   "jane@example.com",
+	}
+
+	diffs := filterPresenceDiffs(findDifferencesWithOptions(obj1, obj2, "", CompareOptions{}))
+
+	var delta string
+	for _, diff := range diffs {
+		delta += formatStructureDelta(diff)
+	}
+	expected := "+ address.postcode\n- address.zip\n+ email\n"
+	if delta != expected {
+		t.Errorf("Expected structure delta %q, got %q", expected, delta)
+	}
+}
+
+func TestFilterAdditions(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"name":    "app",
+		"removed": true,
+		"hosts":   []interface{}{"a"},
+		"ports":   []interface{}{80.0, 443.0},
+	}
+	obj2 := map[string]interface{}{
+		"name":  "app2",
+		"added": map[string]interface{}{"x": 1.0},
+		"hosts": []interface{}{"a", "b"},
+		"ports": []interface{}{80.0},
+	}
+
+	differences := filterAdditions(findDifferencesWithOptions(obj1, obj2, "", CompareOptions{}))
+	if len(differences) != 2 {
+		t.Fatalf("Expected 2 additions, got %v", differences)
+	}
+	if differences[0].Path != "added" || differences[0].Type != KeyOnlyInSecond {
+		t.Errorf("Expected added key first, got %s (%s)", differences[0].Path, differences[0].Type)
+	}
+	if differences[1].Path != "hosts" || differences[1].Type != ArrayLength {
+		t.Errorf("Expected the grown hosts array, got %s (%s)", differences[1].Path, differences[1].Type)
+	}
+}
+
+func TestFilterRemovals(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"name":    "app",
+		"removed": true,
+		"hosts":   []interface{}{"a"},
+		"ports":   []interface{}{80.0, 443.0},
+	}
+	obj2 := map[string]interface{}{
+		"name":  "app2",
+		"added": map[string]interface{}{"x": 1.0},
+		"hosts": []interface{}{"a", "b"},
+		"ports": []interface{}{80.0},
+	}
+
+	differences := filterRemovals(findDifferencesWithOptions(obj1, obj2, "", CompareOptions{}))
+	if len(differences) != 2 {
+		t.Fatalf("Expected 2 removals, got %v", differences)
+	}
+	if differences[0].Path != "ports" || differences[0].Type != ArrayLength {
+		t.Errorf("Expected the shrunk ports array first, got %s (%s)", differences[0].Path, differences[0].Type)
+	}
+	if differences[1].Path != "removed" || differences[1].Type != KeyOnlyInFirst {
+		t.Errorf("Expected removed key, got %s (%s)", differences[1].Path, differences[1].Type)
+	}
+}
+
+func TestHeadDifferences(t *testing.T) {
+	differences := []Diff{
+		{Path: "a", Type: ValueMismatch},
+		{Path: "b", Type: KeyOnlyInSecond},
+		{Path: "c", Type: ValueMismatch},
+		{Path: "d", Type: KeyOnlyInSecond},
+		{Path: "e", Type: TypeMismatch},
+	}
+
+	shown, remaining := headDifferences(differences, 2)
+	if len(shown) != 2 || len(remaining) != 3 || remaining[0].Path != "c" {
+		t.Fatalf("Unexpected split: %v / %v", shown, remaining)
+	}
+
+	expected := "... and 3 more differences (value_mismatch: 1, key_only_in_second: 1, type_mismatch: 1)\n"
+	if summary := formatRemainingSummary(newStats(differences).without(shown)); summary != expected {
+		t.Errorf("Expected summary %q, got %q", expected, summary)
+	}
+
+	for _, n := range []int{0, 5, 10} {
+		shown, remaining := headDifferences(differences, n)
+		if len(shown) != 5 || remaining != nil || formatRemainingSummary(newStats(differences).without(shown)) != "" {
+			t.Errorf("Expected every difference to be shown with n=%d", n)
+		}
+	}
+}
+
+func TestFormatPorcelain(t *testing.T) {
+	tests := []struct {
+		diff     Diff
+		expected string
+	}{
+		{Diff{Path: "name", Type: ValueMismatch, Value1: "a\tb", Value2: "<c>"}, "M name\t\"a\\tb\"\t\"<c>\"\n"},
+		{Diff{Path: "user", Type: KeyOnlyInFirst, Value1: map[string]interface{}{"b": 1.0, "a": nil}}, "D user\t{\"a\":null,\"b\":1}\t\n"},
+		{Diff{Path: "tags[2]", Type: KeyOnlyInSecond, Value2: nil}, "A tags[2]\t\tnull\n"},
+		{Diff{Path: "age", Type: TypeMismatch, Value1: 30.0, Value2: "30"}, "T age\t30\t\"30\"\n"},
+		{Diff{Path: "items", Type: ArrayLength, Value1: 2, Value2: 3}, "L items\t2\t3\n"},
+		{Diff{Path: "odd\tkey", Type: ValueMismatch, Value1: true, Value2: false}, "M odd\\tkey\ttrue\tfalse\n"},
+		{Diff{Path: "deep", Type: DepthExceeded, Value1: 3, Value2: 3}, "X deep\t3\t3\n"},
+		{Diff{Path: "items", Type: ArrayDiffsTruncated, Value1: 5}, ""},
+	}
+
+	for _, test := range tests {
+		line, err := formatPorcelain(test.diff)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if line != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, line)
+		}
+	}
+}
+
+func TestStdoutOutputs(t *testing.T) {
+	tests := []struct {
+		outputJSON    string
+		files         []string
+		streaming     bool
+		jsonToStdout  bool
+		machineOutput bool
+	}{
+		{"-", nil, false, true, true},
+		{"diff.json", nil, false, false, false},
+		{"", nil, true, false, true},
+		// Another output on stdout doesn't send -output-json there too
+		{"diff.json", []string{"-"}, false, false, true},
+		{"diff.json", []string{"delta.json"}, false, false, false},
+	}
+	for _, tt := range tests {
+		jsonToStdout, machineOutput := stdoutOutputs(tt.outputJSON, tt.files, tt.streaming)
+		if jsonToStdout != tt.jsonToStdout || machineOutput != tt.machineOutput {
+			t.Errorf("stdoutOutputs(%q, %q, %v) = %v, %v, want %v, %v", tt.outputJSON, tt.files, tt.streaming,
+				jsonToStdout, machineOutput, tt.jsonToStdout, tt.machineOutput)
+		}
+	}
+}
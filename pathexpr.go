@@ -0,0 +1,270 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// matchPathExpr reports whether a reported path lies at or under a path
+// expression. Expressions are paths such as "users[0].name" extended with:
+//   - "*" (or any path.Match glob) in place of a key, matching one key, e.g. "sensitive.*"
+//   - "[*]" in place of an index, matching any element, e.g. "users[*].id"
+//   - "{a,b}" alternatives, e.g. "users[*].{name,email}"
+//   - a leading "!" negating the whole expression, e.g. "!sensitive"
+//
+// The empty expression (or ".") is the root and matches every path.
+func matchPathExpr(expr, reported string) bool {
+	if strings.HasPrefix(expr, "!") {
+		return !matchPathExpr(expr[1:], reported)
+	}
+	pathTokens, err := splitPathTokens(reported)
+	if err != nil {
+		return false
+	}
+	alternatives, err := expandBraces(expr)
+	if err != nil {
+		return false
+	}
+	for _, alt := range alternatives {
+		if alt == "." {
+			alt = ""
+		}
+		patternTokens, err := splitPathTokens(alt)
+		if err == nil && matchPathTokens(patternTokens, pathTokens) {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePathExpr checks that a path expression is well formed
+func validatePathExpr(expr string) error {
+	alternatives, err := expandBraces(strings.TrimPrefix(expr, "!"))
+	if err != nil {
+		return err
+	}
+	for _, alt := range alternatives {
+		if alt == "." {
+			continue
+		}
+		tokens, err := splitPathTokens(alt)
+		if err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			if strings.HasPrefix(token, "[") {
+				continue
+			}
+			if _, err := path.Match(token, ""); err != nil {
+				return fmt.Errorf("invalid path expression %q: bad pattern %q", expr, token)
+			}
+		}
+	}
+	return nil
+}
+
+// expandBraces expands every "{a,b}" group in expr into the list of
+// expressions it stands for. Groups cannot be nested.
+func expandBraces(expr string) ([]string, error) {
+	start := strings.Index(expr, "{")
+	if start < 0 {
+		if strings.Contains(expr, "}") {
+			return nil, fmt.Errorf("invalid path expression %q: unmatched }", expr)
+		}
+		return []string{expr}, nil
+	}
+	end := strings.Index(expr[start:], "}")
+	if end < 0 {
+		return nil, fmt.Errorf("invalid path expression %q: unmatched {", expr)
+	}
+	end += start
+	group := expr[start+1 : end]
+	if strings.Contains(group, "{") {
+		return nil, fmt.Errorf("invalid path expression %q: nested braces", expr)
+	}
+
+	rest, err := expandBraces(expr[end+1:])
+	if err != nil {
+		return nil, err
+	}
+	var expanded []string
+	for _, choice := range strings.Split(group, ",") {
+		for _, tail := range rest {
+			expanded = append(expanded, expr[:start]+choice+tail)
+		}
+	}
+	return expanded, nil
+}
+
+// splitPathTokens splits a path into its keys and "[n]" index tokens, e.g.
+// "items[3].name" into "items", "[3]", "name". An index may be "*".
+func splitPathTokens(p string) ([]string, error) {
+	var tokens []string
+	if p == "" {
+		return tokens, nil
+	}
+
+	for _, part := range strings.Split(p, ".") {
+		if part == "" {
+			return nil, fmt.Errorf("invalid path %q: empty key", p)
+		}
+		key := part
+		rest := ""
+		if i := strings.Index(part, "["); i >= 0 {
+			key, rest = part[:i], part[i:]
+		}
+		if key != "" {
+			tokens = append(tokens, key)
+		}
+		for rest != "" {
+			end := strings.Index(rest, "]")
+			if !strings.HasPrefix(rest, "[") || end < 0 {
+				return nil, fmt.Errorf("invalid path %q: malformed index", p)
+			}
+			index := rest[1:end]
+			if n, err := strconv.Atoi(index); index != "*" && (err != nil || n < 0) {
+				return nil, fmt.Errorf("invalid path %q: bad index %q", p, index)
+			}
+			tokens = append(tokens, rest[:end+1])
+			rest = rest[end+1:]
+		}
+	}
+	return tokens, nil
+}
+
+// matchPathTokens reports whether the pattern tokens match a prefix of the
+// path tokens, so a pattern matches a path and everything under it
+func matchPathTokens(pattern, tokens []string) bool {
+	if len(pattern) > len(tokens) {
+		return false
+	}
+	for i, p := range pattern {
+		token := tokens[i]
+		pIndex, tIndex := strings.HasPrefix(p, "["), strings.HasPrefix(token, "[")
+		switch {
+		case pIndex != tIndex:
+			return false
+		case pIndex:
+			if p != "[*]" && p != token {
+				return false
+			}
+		default:
+			if matched, err := path.Match(p, token); err != nil || !matched {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// filterPathExprs removes the differences matching any of the ignore
+// expressions and, if only is not empty, those matching none of the only
+// expressions
+func filterPathExprs(differences []Diff, ignore, only []string) []Diff {
+	if len(ignore) == 0 && len(only) == 0 {
+		return differences
+	}
+	filtered := []Diff{}
+	for _, diff := range differences {
+		if pathReported(diff.Path, ignore, only) {
+			filtered = append(filtered, diff)
+		}
+	}
+	return filtered
+}
+
+// pathReported reports whether a difference at path passes the ignore and
+// only expressions
+func pathReported(path string, ignore, only []string) bool {
+	return !matchAnyPathExpr(ignore, path) && (len(only) == 0 || matchAnyPathExpr(only, path))
+}
+
+// matchAnyPathExpr reports whether a reported path matches one of the expressions
+func matchAnyPathExpr(exprs []string, reported string) bool {
+	for _, expr := range exprs {
+		if matchPathExpr(expr, reported) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupScoped returns the entry of a path-scoped option map for path. Keys
+// are path expressions, as for matchPathExpr, except that they match the path
+// itself and not the paths under it; so "items[*].price" scopes an option to
+// the price of every element of items. Of several matching keys the most
+// specific wins: the exact path, then the key matching the most tokens
+// literally, then the longest key, such as "*_id" over "*", then the first in
+// sorted order.
+func lookupScoped[V any](scoped map[string]V, path string) (V, bool) {
+	if val, ok := scoped[path]; ok {
+		return val, true
+	}
+
+	matched, best, found := "", 0, false
+	for key := range scoped {
+		// Keys without a wildcard, alternatives or negation only match exactly
+		if !strings.ContainsAny(key, "*?{!") {
+			continue
+		}
+		specificity, ok := matchScopedExpr(key, path)
+		if ok && (!found || specificity > best || (specificity == best && moreSpecificKey(key, matched))) {
+			matched, best, found = key, specificity, true
+		}
+	}
+	val := scoped[matched]
+	return val, found
+}
+
+// moreSpecificKey breaks a tie between two scoped keys matching as many
+// tokens literally, preferring the longer and then the first in sorted order
+func moreSpecificKey(key, other string) bool {
+	if len(key) != len(other) {
+		return len(key) > len(other)
+	}
+	return key < other
+}
+
+// matchScopedExpr reports whether a path expression matches path exactly,
+// and how specific the match is: the number of keys and indices it matched
+// literally, or -1 for a negated expression
+func matchScopedExpr(expr, reported string) (int, bool) {
+	if strings.HasPrefix(expr, "!") {
+		_, matched := matchScopedExpr(expr[1:], reported)
+		return -1, !matched
+	}
+	pathTokens, err := splitPathTokens(reported)
+	if err != nil {
+		return 0, false
+	}
+	alternatives, err := expandBraces(expr)
+	if err != nil {
+		return 0, false
+	}
+
+	best, found := 0, false
+	for _, alt := range alternatives {
+		if alt == "." {
+			alt = ""
+		}
+		patternTokens, err := splitPathTokens(alt)
+		if err != nil || len(patternTokens) != len(pathTokens) || !matchPathTokens(patternTokens, pathTokens) {
+			continue
+		}
+		literal := 0
+		for _, p := range patternTokens {
+			if p != "[*]" && (strings.HasPrefix(p, "[") || !strings.ContainsAny(p, "*?[\\")) {
+				literal++
+			}
+		}
+		if !found || literal > best {
+			best, found = literal, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMatchPathExpr(t *testing.T) {
+	tests := []struct {
+		expr string
+		path string
+		want bool
+	}{
+		{"", "anything", true},
+		{".", "a.b", true},
+		{"users", "users", true},
+		{"users", "users[0].name", true},
+		{"users", "usersX", false},
+		{"users[0]", "users[1]", false},
+		{"users[*]", "users[7].name", true},
+		{"users[*]", "users.name", false},
+		{"users[*].id", "users[2].id", true},
+		{"users[*].id", "users[2].idx", false},
+		{"sensitive.*", "sensitive.ssn", true},
+		{"sensitive.*", "sensitive", false},
+		{"sensitive.*", "sensitive[0]", false},
+		{"addr*", "address.city", true},
+		{"users[*].{name,email}", "users[0].email", true},
+		{"users[*].{name,email}", "users[0].phone", false},
+		{"{a,b}.{c,d}", "b.c", true},
+		{"!sensitive", "sensitive.ssn", false},
+		{"!sensitive", "public.name", true},
+		{"!users[*].{name,email}", "users[0].phone", true},
+		{"a{", "a", false},
+	}
+
+	for _, tc := range tests {
+		if got := matchPathExpr(tc.expr, tc.path); got != tc.want {
+			t.Errorf("matchPathExpr(%q, %q) = %v, want %v", tc.expr, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestValidatePathExpr(t *testing.T) {
+	for _, expr := range []string{"", ".", "a.b[3]", "users[*].{name,email}", "!secret.*"} {
+		if err := validatePathExpr(expr); err != nil {
+			t.Errorf("validatePathExpr(%q) = %v, want nil", expr, err)
+		}
+	}
+	for _, expr := range []string{"a..b", "a[x]", "a{b", "a}b", "a{b{c}}", "a[", "[a"} {
+		if err := validatePathExpr(expr); err == nil {
+			t.Errorf("validatePathExpr(%q) = nil, want an error", expr)
+		}
+	}
+}
+
+func TestIgnoreAndOnlyPaths(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{"name": "a", "email": "a@x", "age": 1.0},
+		},
+		"sensitive": map[string]interface{}{"ssn": "1"},
+	}
+	obj2 := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{"name": "b", "email": "b@x", "age": 2.0},
+		},
+		"sensitive": map[string]interface{}{"ssn": "2"},
+	}
+
+	paths := func(diffs []Diff) []string {
+		var result []string
+		for _, diff := range diffs {
+			result = append(result, diff.Path)
+		}
+		return result
+	}
+
+	ignored := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{IgnorePaths: []string{"users[*].{name,email}"}})
+	if got := paths(ignored); !reflect.DeepEqual(got, []string{"sensitive.ssn", "users[0].age"}) {
+		t.Errorf("with -ignore-path got %v", got)
+	}
+
+	only := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{OnlyPaths: []string{"!sensitive"}})
+	if got := paths(only); !reflect.DeepEqual(got, []string{"users[0].age", "users[0].email", "users[0].name"}) {
+		t.Errorf("with -only-path got %v", got)
+	}
+
+	tagged := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{PathTags: map[string][]string{"users[*].email": {"pii"}}})
+	for _, diff := range tagged {
+		if want := diff.Path == "users[0].email"; hasTag(diff, "pii") != want {
+			t.Errorf("%s tagged pii = %v, want %v", diff.Path, !want, want)
+		}
+	}
+}
+
+func TestLookupScoped(t *testing.T) {
+	scoped := map[string]string{
+		"items[*].price": "any",
+		"items[0].price": "first",
+		"items[*].*":     "glob",
+		"[*][*]":         "nested",
+	}
+	tests := []struct {
+		path  string
+		want  string
+		found bool
+	}{
+		{"items[0].price", "first", true},
+		{"items[3].price", "any", true},
+		{"items[3].name", "glob", true},
+		{"items.price", "", false},
+		{"items[3].price.amount", "", false},
+		{"[1][2]", "nested", true},
+		{"[1]", "", false},
+	}
+	for _, tt := range tests {
+		got, found := lookupScoped(scoped, tt.path)
+		if got != tt.want || found != tt.found {
+			t.Errorf("lookupScoped(%q) = %q, %v, want %q, %v", tt.path, got, found, tt.want, tt.found)
+		}
+	}
+
+	// Of several index patterns the first in sorted order wins
+	tied := map[string]int{"a[0][*]": 1, "a[*][0]": 2}
+	if got, _ := lookupScoped(tied, "a[0][0]"); got != 2 {
+		t.Errorf("lookupScoped tie = %d, want 2", got)
+	}
+
+	// Keys are path expressions; the one matching the most tokens literally wins
+	exprs := map[string]string{
+		"users.*":              "any",
+		"users.*_id":           "id",
+		"users.{name,email}":   "contact",
+		"users.admin.name":     "admin",
+		"!users.*":             "other",
+		"users[*].{name,role}": "element",
+	}
+	tests = []struct {
+		path  string
+		want  string
+		found bool
+	}{
+		{"users.name", "contact", true},
+		{"users.admin.name", "admin", true},
+		{"users.group_id", "id", true},
+		{"users.age", "any", true},
+		{"users[2].role", "element", true},
+		{"users", "other", true},
+		{"users.admin.age", "other", true},
+	}
+	for _, tt := range tests {
+		got, found := lookupScoped(exprs, tt.path)
+		if got != tt.want || found != tt.found {
+			t.Errorf("lookupScoped(%q) = %q, %v, want %q, %v", tt.path, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestScopedOptionsOnArrayElements(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"price": 1.00, "version": "1.2"},
+			map[string]interface{}{"price": 2.00, "version": "2.0"},
+		},
+	}
+	obj2 := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"price": 1.01, "version": "1.2.0"},
+			map[string]interface{}{"price": 2.50, "version": "2"},
+		},
+	}
+	options := CompareOptions{
+		NumericRules: map[string]NumericRule{"items[*].price": {Places: -1, AbsTolerance: 0.01}},
+		SemverKeys:   map[string]bool{"items[*].version": true},
+	}
+	diffs := findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(diffs) != 1 || diffs[0].Path != "items[1].price" {
+		t.Errorf("got %v, want only items[1].price", diffs)
+	}
+
+	required := map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": 1.0}}}
+	diffs = findDifferencesWithOptions(required, map[string]interface{}{"items": []interface{}{map[string]interface{}{}}}, "", CompareOptions{
+		RequiredKeys: map[string]map[string]bool{"items[*]": {"id": true}},
+	})
+	if len(diffs) != 1 || diffs[0].Type != RequiredMissing {
+		t.Errorf("got %v, want one required_missing", diffs)
+	}
+
+	// Globs, alternatives and negation scope options like any path expression
+	obj1 = map[string]interface{}{"prices": map[string]interface{}{"net": 1.00, "gross": 2.00}, "notes": "Hello", "name": "Jon"}
+	obj2 = map[string]interface{}{"prices": map[string]interface{}{"net": 1.01, "gross": 2.01}, "notes": "hello", "name": "John"}
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		NumericRules:         map[string]NumericRule{"prices.*": {Places: -1, AbsTolerance: 0.05}},
+		RegexMatches:         map[string]string{"{notes,title}": "(?i)^hello$"},
+		LevenshteinKeys:      map[string]bool{"!prices.*": true},
+		LevenshteinThreshold: 1,
+	})
+	if len(diffs) != 0 {
+		t.Errorf("got %v, want no differences with options scoped by globs", diffs)
+	}
+}
+
+func TestNumericArrayElements(t *testing.T) {
+	arr1 := []interface{}{1.0, json.Number("2"), "3", 4.0}
+	arr2 := []interface{}{1.0, json.Number("2"), 3.0, "4.5"}
+
+	diffs := findDifferencesWithOptions(arr1, arr2, "", CompareOptions{IgnoreNumericType: true})
+	if len(diffs) != 1 || diffs[0].Path != "[3]" || diffs[0].Type != ValueMismatch {
+		t.Errorf("with -ignore-numeric-type got %v, want a value mismatch at [3]", diffs)
+	}
+
+	diffs = findDifferencesWithOptions(arr1, arr2, "", CompareOptions{})
+	var paths []string
+	for _, diff := range diffs {
+		paths = append(paths, diff.Path)
+	}
+	if !reflect.DeepEqual(paths, []string{"[2]", "[3]"}) {
+		t.Errorf("without -ignore-numeric-type got paths %v, want [2] and [3]", paths)
+	}
+
+	rule := CompareOptions{IgnoreNumericType: true, NumericRules: map[string]NumericRule{"[*]": {Places: -1, AbsTolerance: 0.5}}}
+	if diffs := findDifferencesWithOptions(arr1, arr2, "", rule); len(diffs) != 0 {
+		t.Errorf("with a numeric rule on every element got %v", diffs)
+	}
+}
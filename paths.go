@@ -0,0 +1,196 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is one step of a parsed path: either an object key or an array index
+type pathSegment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// parsePath splits a reported path such as "data.items[3].name" into segments
+func parsePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			if path == "" {
+				break
+			}
+			return nil, fmt.Errorf("invalid path %q: empty key", path)
+		}
+
+		// Split off any trailing array indices, e.g. items[3][0]
+		key := part
+		var indices []string
+		if i := strings.Index(part, "["); i >= 0 {
+			key = part[:i]
+			rest := part[i:]
+			for rest != "" {
+				end := strings.Index(rest, "]")
+				if !strings.HasPrefix(rest, "[") || end < 0 {
+					return nil, fmt.Errorf("invalid path %q: malformed index", path)
+				}
+				indices = append(indices, rest[1:end])
+				rest = rest[end+1:]
+			}
+		}
+
+		if key != "" {
+			segments = append(segments, pathSegment{Key: key})
+		}
+		for _, index := range indices {
+			n, err := strconv.Atoi(index)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid path %q: bad index %q", path, index)
+			}
+			segments = append(segments, pathSegment{Index: n, IsIndex: true})
+		}
+	}
+
+	return segments, nil
+}
+
+// lookupPath returns the value at path within data.
+// The boolean result is false if the path does not exist.
+func lookupPath(data interface{}, path string) (interface{}, bool, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	current := data
+	for _, segment := range segments {
+		if segment.IsIndex {
+			arr, ok := current.([]interface{})
+			if !ok || segment.Index >= len(arr) {
+				return nil, false, nil
+			}
+			current = arr[segment.Index]
+		} else {
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false, nil
+			}
+			val, ok := obj[segment.Key]
+			if !ok {
+				return nil, false, nil
+			}
+			current = val
+		}
+	}
+
+	return current, true, nil
+}
+
+// unwrapDocument returns the value at path within data, failing if it doesn't exist
+func unwrapDocument(data interface{}, path string) (interface{}, error) {
+	val, ok, err := lookupPath(data, path)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("path %q not found", path)
+	}
+	return val, nil
+}
+
+// unwrapEither extracts the value at path from whichever documents contain it,
+// leaving a document without the envelope untouched. This lines up a bare
+// array with the same array wrapped in a paginated response.
+func unwrapEither(data1, data2 interface{}, path string) (interface{}, interface{}, error) {
+	val1, ok1, err := lookupPath(data1, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	val2, ok2, err := lookupPath(data2, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !ok1 && !ok2 {
+		return nil, nil, fmt.Errorf("path %q not found in either document", path)
+	}
+	if ok1 {
+		data1 = val1
+	}
+	if ok2 {
+		data2 = val2
+	}
+	return data1, data2, nil
+}
+
+// parentPath returns the path of the object or array containing path
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "]") {
+		if i := strings.LastIndex(path, "["); i >= 0 {
+			return path[:i]
+		}
+	}
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// isUnderPath reports whether path is parent itself or lies inside it
+func isUnderPath(path, parent string) bool {
+	return parent == "" || path == parent ||
+		strings.HasPrefix(path, parent+".") || strings.HasPrefix(path, parent+"[")
+}
+
+// rebaseIndices adds base to every array index in a reported path, so that
+// "hobbies[0]" becomes "hobbies[1]" with a base of 1
+func rebaseIndices(path string, base int) string {
+	if base == 0 || !strings.Contains(path, "[") {
+		return path
+	}
+
+	var sb strings.Builder
+	for {
+		open := strings.Index(path, "[")
+		if open < 0 {
+			break
+		}
+		end := strings.Index(path[open:], "]")
+		if end < 0 {
+			break
+		}
+		end += open
+		sb.WriteString(path[:open+1])
+		if n, err := strconv.Atoi(path[open+1 : end]); err == nil && n >= 0 {
+			sb.WriteString(strconv.Itoa(n + base))
+		} else {
+			sb.WriteString(path[open+1 : end])
+		}
+		sb.WriteString("]")
+		path = path[end+1:]
+	}
+	sb.WriteString(path)
+	return sb.String()
+}
+
+// applyIndexBase renders the array indices in every difference's path with the
+// given base. The old and new paths held by a Moved difference are rebased too.
+func applyIndexBase(differences []Diff, base int) []Diff {
+	for i := range differences {
+		differences[i].Path = rebaseIndices(differences[i].Path, base)
+		if differences[i].Type == Moved {
+			if from, ok := differences[i].Value1.(string); ok {
+				differences[i].Value1 = rebaseIndices(from, base)
+			}
+			if to, ok := differences[i].Value2.(string); ok {
+				differences[i].Value2 = rebaseIndices(to, base)
+			}
+		}
+	}
+	return differences
+}
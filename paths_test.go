@@ -0,0 +1,103 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLookupPath(t *testing.T) {
+	data := map[string]interface{}{
+		"data": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"name": "a"},
+				[]interface{}{"x", "y"},
+			},
+		},
+	}
+
+	tests := []struct {
+		path     string
+		expected interface{}
+		found    bool
+	}{
+		{"", data, true},
+		{"data.items[0].name", "a", true},
+		{"data.items[1][1]", "y", true},
+		{"data.items[2]", nil, false},
+		{"data.missing", nil, false},
+		{"data.items.name", nil, false},
+	}
+
+	for _, tt := range tests {
+		val, found, err := lookupPath(data, tt.path)
+		if err != nil {
+			t.Errorf("lookupPath(%q) returned error: %v", tt.path, err)
+			continue
+		}
+		if found != tt.found || !reflect.DeepEqual(val, tt.expected) {
+			t.Errorf("lookupPath(%q) = %v, %v, want %v, %v", tt.path, val, found, tt.expected, tt.found)
+		}
+	}
+
+	for _, path := range []string{"data..items", "items[x]", "items[0"} {
+		if _, _, err := lookupPath(data, path); err == nil {
+			t.Errorf("Expected lookupPath(%q) to fail", path)
+		}
+	}
+}
+
+func TestUnwrapEither(t *testing.T) {
+	full := []interface{}{"a", "b"}
+	page := map[string]interface{}{
+		"page":  1.0,
+		"items": []interface{}{"a", "c"},
+	}
+
+	data1, data2, err := unwrapEither(full, page, "items")
+	if err != nil {
+		t.Fatalf("Failed to unwrap: %v", err)
+	}
+
+	diffs := findDifferencesWithOptions(data1, data2, "", CompareOptions{})
+	if len(diffs) != 1 || diffs[0].Path != "[1]" {
+		t.Errorf("Expected a single difference at [1], got %v", diffs)
+	}
+
+	if _, _, err := unwrapEither(full, full, "items"); err == nil {
+		t.Error("Expected an error when neither document contains the path")
+	}
+}
+
+func TestRebaseIndices(t *testing.T) {
+	tests := map[string]string{
+		"":                   "",
+		"name":               "name",
+		"hobbies[0]":         "hobbies[1]",
+		"[9]":                "[10]",
+		"data[0][2].tags[1]": "data[1][3].tags[2]",
+		"doc[0].items[x]":    "doc[1].items[x]",
+	}
+	for path, want := range tests {
+		if got := rebaseIndices(path, 1); got != want {
+			t.Errorf("rebaseIndices(%q, 1) = %q, want %q", path, got, want)
+		}
+	}
+
+	if got := rebaseIndices("hobbies[0]", 0); got != "hobbies[0]" {
+		t.Errorf("Expected a base of 0 to leave the path alone, got %q", got)
+	}
+
+	diffs := applyIndexBase([]Diff{
+		{Path: "hobbies[0]", Type: ValueMismatch, Value1: "a", Value2: "b"},
+		{Path: "items[2]", Type: Moved, Value1: "items[0]", Value2: "items[2]"},
+	}, 1)
+	if diffs[0].Path != "hobbies[1]" || diffs[0].Value1 != "a" {
+		t.Errorf("Expected only the path to be rebased, got %v", diffs[0])
+	}
+	if diffs[1].Path != "items[3]" || diffs[1].Value1 != "items[1]" || diffs[1].Value2 != "items[3]" {
+		t.Errorf("Expected both paths of a move to be rebased, got %v", diffs[1])
+	}
+}
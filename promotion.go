@@ -0,0 +1,60 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Promotion records two values that were only equal once converted to a
+// common numeric type, such as 1 and "1" under IgnoreNumericType, or the
+// json.Numbers 1 and 1.0 from a caller that keeps numbers as written. They
+// are not differences, so they are reported apart.
+type Promotion struct {
+	Path   string
+	Value1 interface{}
+	Value2 interface{}
+}
+
+// numericPromotion returns a Promotion for two values found equal if they are
+// the same number written as different types or in different forms, or nil
+func numericPromotion(val1, val2 interface{}, path string, options CompareOptions) *Promotion {
+	if !(options.IgnoreNumericType || options.CoerceLeftNumStrings) || jsonEqual(val1, val2) {
+		return nil
+	}
+	num1, ok1 := convertToFloat64(val1)
+	num2, ok2 := convertToFloat64(val2)
+	if !ok1 || !ok2 || num1 != num2 {
+		// Numbers equal only within a tolerance are fuzzy matches instead
+		return nil
+	}
+	return &Promotion{Path: path, Value1: val1, Value2: val2}
+}
+
+// prefixPromotions prepends prefix to the path of every promotion
+func prefixPromotions(promotions []Promotion, prefix string) []Promotion {
+	for i := range promotions {
+		promotions[i].Path = prefixPath(prefix, promotions[i].Path)
+	}
+	return promotions
+}
+
+// formatPromotionReport renders the values that were only equal as numbers,
+// e.g. `tags[1]: 1 == "1"`, or "" if there are none
+func formatPromotionReport(promotions []Promotion) string {
+	if len(promotions) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\nEqual only once converted to numbers (%d):\n", len(promotions))
+	for _, promotion := range promotions {
+		encoded1, _ := porcelainValue(promotion.Value1, true)
+		encoded2, _ := porcelainValue(promotion.Value2, true)
+		fmt.Fprintf(&sb, "%s: %s == %s\n", displayPath(promotion.Path), encoded1, encoded2)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPromotions(t *testing.T) {
+	arr1 := []interface{}{json.Number("1"), json.Number("1"), json.Number("1.0"), "2", 3.0}
+	arr2 := []interface{}{json.Number("1"), json.Number("1.0"), "1", 2.0, 3.5}
+
+	var promotions []Promotion
+	options := CompareOptions{IgnoreNumericType: true, Promotions: &promotions}
+	diffs := findDifferencesWithOptions(arr1, arr2, "", options)
+
+	// Promotions are recorded apart and add no differences
+	if len(diffs) != 1 || diffs[0].Path != "[4]" {
+		t.Errorf("Expected only [4] to differ, got %v", diffs)
+	}
+	want := []Promotion{
+		{Path: "[1]", Value1: json.Number("1"), Value2: json.Number("1.0")},
+		{Path: "[2]", Value1: json.Number("1.0"), Value2: "1"},
+		{Path: "[3]", Value1: "2", Value2: 2.0},
+	}
+	if !reflect.DeepEqual(promotions, want) {
+		t.Errorf("Unexpected promotions:\n got: %v\nwant: %v", promotions, want)
+	}
+
+	if got, want := formatPromotionReport(promotions[1:2]), "\nEqual only once converted to numbers (1):\n[2]: 1.0 == \"1\"\n\n"; got != want {
+		t.Errorf("formatPromotionReport = %q, want %q", got, want)
+	}
+
+	// Numbers equal only within a tolerance are not promotions
+	promotions = nil
+	options.FloatTolerance = 1
+	findDifferencesWithOptions(arr1, arr2, "", options)
+	if len(promotions) != 3 {
+		t.Errorf("Expected the same 3 promotions with a tolerance, got %v", promotions)
+	}
+
+	// Nothing is promoted when numeric types count
+	promotions = nil
+	findDifferencesWithOptions(arr1, arr2, "", CompareOptions{Promotions: &promotions})
+	if len(promotions) != 0 {
+		t.Errorf("Expected no promotions without -ignore-numeric-type, got %v", promotions)
+	}
+}
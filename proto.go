@@ -0,0 +1,110 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// applyProtoPreset enables the equivalences of the proto3 canonical JSON mapping:
+// int64 values encoded as strings equal numbers, and omitted fields equal their
+// default values. Enum names are matched to numbers through EnumValues.
+func applyProtoPreset(options CompareOptions) CompareOptions {
+	options.IgnoreNumericType = true
+	options.TreatMissingAsDefault = true
+	return options
+}
+
+// parseEnumValues parses an enum mapping in the form path:NAME=number,...,
+// e.g. "status:ACTIVE=1,INACTIVE=2"
+func parseEnumValues(spec string) (string, map[string]float64, error) {
+	path, mapping, ok := cutLast(spec, ":")
+	if !ok || path == "" {
+		return "", nil, fmt.Errorf("invalid proto-enum %q, expected path:NAME=number,...", spec)
+	}
+
+	values, err := parseEnumMapping(mapping)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid proto-enum %q: %v", spec, err)
+	}
+	return path, values, nil
+}
+
+// parseEnumMapping parses comma-separated NAME=number pairs
+func parseEnumMapping(mapping string) (map[string]float64, error) {
+	values := make(map[string]float64)
+	for _, pair := range strings.Split(mapping, ",") {
+		name, number, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("expected NAME=number, got %q", pair)
+		}
+
+		num, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return nil, fmt.Errorf("enum value for %s is not a number: %q", name, number)
+		}
+		values[name] = num
+	}
+	return values, nil
+}
+
+// enumNumber resolves an enum value, given either by name or by number
+func enumNumber(val interface{}, names map[string]float64) (float64, bool) {
+	if num, ok := numberValue(val); ok {
+		return num, true
+	}
+	if name, ok := val.(string); ok {
+		num, ok := names[name]
+		return num, ok
+	}
+	return 0, false
+}
+
+// compareEnumValues compares two enum values, each given by name or number
+// Returns whether they are equal, and whether both values could be resolved
+func compareEnumValues(val1, val2 interface{}, names map[string]float64) (bool, bool) {
+	num1, ok1 := enumNumber(val1, names)
+	num2, ok2 := enumNumber(val2, names)
+	if !ok1 || !ok2 {
+		return false, false
+	}
+	return num1 == num2, true
+}
+
+// isDefaultValue reports whether val is the proto3 default for its type,
+// which the canonical JSON mapping omits
+func isDefaultValue(val interface{}, path string, options CompareOptions) bool {
+	switch v := val.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case json.Number:
+		num, ok := numberValue(v)
+		return ok && num == 0
+	case string:
+		if v == "" {
+			return true
+		}
+		names, _ := lookupScoped(options.EnumValues, path)
+		if num, ok := enumNumber(v, names); ok {
+			return num == 0
+		}
+		if options.IgnoreNumericType {
+			num, ok := convertToFloat64(v)
+			return ok && num == 0
+		}
+		return false
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
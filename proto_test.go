@@ -0,0 +1,67 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProtoPreset(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"id":     "9007199254740993",
+		"status": "ACTIVE",
+		"kind":   "UNKNOWN",
+		"count":  0.0,
+		"tags":   []interface{}{},
+		"name":   "widget",
+	}
+	obj2 := map[string]interface{}{
+		"id":      9007199254740993.0,
+		"status":  1.0,
+		"enabled": false,
+		"name":    "widget",
+	}
+
+	options := applyProtoPreset(CompareOptions{
+		EnumValues: map[string]map[string]float64{
+			"status": {"UNKNOWN": 0, "ACTIVE": 1},
+			"kind":   {"UNKNOWN": 0, "WIDGET": 1},
+		},
+	})
+	diffs := findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(diffs) != 0 {
+		t.Errorf("Expected no differences in proto mode, got %d: %v", len(diffs), diffs)
+	}
+
+	// Non-default values are still reported when missing
+	obj2["extra"] = "value"
+	obj2["status"] = 2.0
+	diffs = findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(diffs) != 2 || diffs[0].Path != "extra" || diffs[1].Path != "status" {
+		t.Errorf("Expected differences at extra and status, got %v", diffs)
+	}
+
+	// Without the preset every omitted field is a difference
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{})
+	if len(diffs) != 7 {
+		t.Errorf("Expected 7 differences without proto mode, got %d: %v", len(diffs), diffs)
+	}
+}
+
+func TestParseEnumValues(t *testing.T) {
+	path, values, err := parseEnumValues("order.status:UNKNOWN=0, ACTIVE=1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != "order.status" || len(values) != 2 || values["ACTIVE"] != 1 {
+		t.Errorf("Unexpected enum mapping %s %v", path, values)
+	}
+
+	for _, spec := range []string{"status", ":A=1", "status:ACTIVE", "status:ACTIVE=one"} {
+		if _, _, err := parseEnumValues(spec); err == nil || !strings.Contains(err.Error(), "invalid proto-enum") {
+			t.Errorf("Expected an error for %q, got %v", spec, err)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// redactedPlaceholder returns the masked form of a value, keeping only its length.
+// Strings report their length in characters, other values the length of their JSON encoding.
+func redactedPlaceholder(val interface{}) string {
+	if str, ok := val.(string); ok {
+		return fmt.Sprintf("<redacted len=%d>", utf8.RuneCountInString(str))
+	}
+	encoded, _ := json.Marshal(val)
+	return fmt.Sprintf("<redacted len=%d>", len(encoded))
+}
+
+// leafKey returns the last key name in a path, ignoring trailing array indices
+func leafKey(path string) string {
+	if i := strings.Index(path, "["); i >= 0 && !strings.Contains(path[i:], ".") {
+		path = path[:i]
+	}
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// matchesRedactField reports whether path falls under one of the redacted fields.
+// A field matches by full path, by leaf key name, or as an ancestor of path.
+func matchesRedactField(path string, fields []string) bool {
+	leaf := leafKey(path)
+	for _, field := range fields {
+		if path == field || leaf == field ||
+			strings.HasPrefix(path, field+".") || strings.HasPrefix(path, field+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue masks val if it lives at a redacted path. Complex values are
+// copied with any nested redacted fields masked, so reporting a whole object
+// does not leak the fields inside it. An empty field list redacts everything.
+func redactValue(val interface{}, path string, fields []string) interface{} {
+	if val == nil {
+		return nil
+	}
+	if len(fields) == 0 || matchesRedactField(path, fields) {
+		return redactedPlaceholder(val)
+	}
+
+	switch v := val.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			redacted[key] = redactValue(child, joinPath(path, key), fields)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, child := range v {
+			redacted[i] = redactValue(child, fmt.Sprintf("%s[%d]", path, i), fields)
+		}
+		return redacted
+	default:
+		return val
+	}
+}
+
+// redactDifferences masks the document values carried by differences, leaving
+// paths and types intact. Values the tool derives itself, such as array lengths
+// and type names, are not document data and are left as-is.
+func redactDifferences(differences []Diff, fields []string) []Diff {
+	for i, diff := range differences {
+		switch diff.Type {
+		case ValueMismatch, KeyOnlyInFirst, KeyOnlyInSecond, RequiredMissing:
+			differences[i].Value1 = redactValue(diff.Value1, diff.Path, fields)
+			differences[i].Value2 = redactValue(diff.Value2, diff.Path, fields)
+
+			// An explanation of the comparison, such as how two versions compare, quotes the values
+			if len(fields) == 0 || matchesRedactField(diff.Path, fields) {
+				differences[i].Detail = ""
+			}
+		case OneofChanged:
+			// The field names are schema, not data; only their values are masked
+			differences[i].Value1 = redactOneofField(diff.Value1, diff.Path, fields)
+			differences[i].Value2 = redactOneofField(diff.Value2, diff.Path, fields)
+		}
+	}
+	return differences
+}
+
+// redactOneofField masks the value of the set field held by a OneofChanged
+// difference for the object at path, keeping the field's name
+func redactOneofField(val interface{}, path string, fields []string) interface{} {
+	set, ok := val.(map[string]interface{})
+	if !ok {
+		return redactValue(val, path, fields)
+	}
+	redacted := make(map[string]interface{}, len(set))
+	for field, fieldVal := range set {
+		redacted[field] = redactValue(fieldVal, joinPath(path, field), fields)
+	}
+	return redacted
+}
+
+// redactArchiveEntries masks the values in the differences of each archive entry
+func redactArchiveEntries(results []ArchiveEntryDiff, fields []string) []ArchiveEntryDiff {
+	for i := range results {
+		results[i].Differences = redactDifferences(results[i].Differences, fields)
+	}
+	return results
+}
+
+// redactThreeWay masks the values in the differences each side of a
+// three-way comparison made to the base
+func redactThreeWay(results []ThreeWayDiff, fields []string) []ThreeWayDiff {
+	for i := range results {
+		results[i].Left = redactDifferences(results[i].Left, fields)
+		results[i].Right = redactDifferences(results[i].Right, fields)
+	}
+	return results
+}
+
+// redactDocument masks the values of a whole document, such as the merged
+// output. Objects and arrays keep their shape so their keys stay readable;
+// an empty field list masks every scalar.
+func redactDocument(val interface{}, path string, fields []string) interface{} {
+	if len(fields) > 0 && matchesRedactField(path, fields) {
+		return redactValue(val, path, fields)
+	}
+
+	switch v := val.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			redacted[key] = redactDocument(child, joinPath(path, key), fields)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, child := range v {
+			redacted[i] = redactDocument(child, fmt.Sprintf("%s[%d]", path, i), fields)
+		}
+		return redacted
+	default:
+		return redactValue(val, path, fields)
+	}
+}
+
+// redactJSONDiffPatch masks the values in a jsondiffpatch delta for the value
+// at path. The markers that say what kind of change each entry is are kept.
+func redactJSONDiffPatch(delta interface{}, path string, fields []string) interface{} {
+	switch d := delta.(type) {
+	case map[string]interface{}:
+		isArray := d["_t"] == "a"
+		redacted := make(map[string]interface{}, len(d))
+		for key, child := range d {
+			switch {
+			case isArray && key == "_t":
+				redacted[key] = child
+			case isArray:
+				redacted[key] = redactJSONDiffPatch(child, fmt.Sprintf("%s[%s]", path, strings.TrimPrefix(key, "_")), fields)
+			default:
+				redacted[key] = redactJSONDiffPatch(child, joinPath(path, key), fields)
+			}
+		}
+		return redacted
+	case []interface{}:
+		// [new], [old, new] or [old, 0, 0]; the zeros of a deletion are markers
+		redacted := make([]interface{}, len(d))
+		copy(redacted, d)
+		values := len(d)
+		if values == 3 {
+			values = 1
+		}
+		for i := 0; i < values; i++ {
+			redacted[i] = redactValue(d[i], path, fields)
+		}
+		return redacted
+	default:
+		return delta
+	}
+}
+
+// redactFuzzyMatches masks the values of the matches in the threshold report
+func redactFuzzyMatches(matches []FuzzyMatch, fields []string) []FuzzyMatch {
+	for i, match := range matches {
+		matches[i].Value1 = redactValue(match.Value1, match.Path, fields)
+		matches[i].Value2 = redactValue(match.Value2, match.Path, fields)
+	}
+	return matches
+}
+
+// redactPromotions masks the values of the promotions in the -show-promotions report
+func redactPromotions(promotions []Promotion, fields []string) []Promotion {
+	for i, promotion := range promotions {
+		promotions[i].Value1 = redactValue(promotion.Value1, promotion.Path, fields)
+		promotions[i].Value2 = redactValue(promotion.Value2, promotion.Path, fields)
+	}
+	return promotions
+}
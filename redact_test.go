@@ -0,0 +1,227 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactDifferences(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"name":  "John",
+		"email": "john@example.com",
+		"tags":  []interface{}{"a", "b"},
+		"user":  map[string]interface{}{"ssn": "123-45-6789", "city": "Boston"},
+	}
+	obj2 := map[string]interface{}{
+		"name":  "Jane",
+		"email": "jane@example.org",
+		"tags":  []interface{}{"a", "b", "c"},
+	}
+
+	// Redacting everything masks all document values but keeps paths and types
+	diffs := redactDifferences(findDifferencesWithOptions(obj1, obj2, "", CompareOptions{}), nil)
+	expected := []Diff{
+		{Path: "email", Type: ValueMismatch, Value1: "<redacted len=16>", Value2: "<redacted len=16>", ParentType: ParentObject, Severity: SeverityWarning},
+		{Path: "name", Type: ValueMismatch, Value1: "<redacted len=4>", Value2: "<redacted len=4>", ParentType: ParentObject, Severity: SeverityWarning},
+		{Path: "tags", Type: ArrayLength, Value1: 2, Value2: 3, ParentType: ParentObject, Severity: SeverityError},
+		{Path: "user", Type: KeyOnlyInFirst, Value1: "<redacted len=37>", Value2: nil, ParentType: ParentObject, Severity: SeverityError},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Unexpected redacted differences:\n got: %v\nwant: %v", diffs, expected)
+	}
+
+	// Redacting specific fields masks them wherever they appear, including nested values
+	diffs = redactDifferences(findDifferencesWithOptions(obj1, obj2, "", CompareOptions{}), []string{"email", "ssn"})
+	if diffs[0].Value1 != "<redacted len=16>" {
+		t.Errorf("Expected email to be redacted, got %v", diffs[0].Value1)
+	}
+	if diffs[1].Value1 != "John" || diffs[1].Value2 != "Jane" {
+		t.Errorf("Expected name to be left as-is, got %v and %v", diffs[1].Value1, diffs[1].Value2)
+	}
+	user := diffs[3].Value1.(map[string]interface{})
+	if user["ssn"] != "<redacted len=11>" || user["city"] != "Boston" {
+		t.Errorf("Expected only the nested ssn to be redacted, got %v", user)
+	}
+
+	// A missing required key carries the value from the first file
+	diffs = redactDifferences(findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		RequiredKeys: map[string]map[string]bool{"": {"user": true}},
+	}), []string{"ssn"})
+	if diffs[3].Type != RequiredMissing || diffs[3].Value1.(map[string]interface{})["ssn"] != "<redacted len=11>" {
+		t.Errorf("Expected the ssn of the missing required key to be redacted, got %v", diffs[3])
+	}
+
+	// A changed oneof keeps the names of the set fields but masks their values
+	payment1 := map[string]interface{}{"payment": map[string]interface{}{"card": "4111"}}
+	payment2 := map[string]interface{}{"payment": map[string]interface{}{"iban": "DE89"}}
+	diffs = redactDifferences(findDifferencesWithOptions(payment1, payment2, "", CompareOptions{
+		OneofGroups: map[string][][]string{"payment": {{"card", "iban"}}},
+	}), nil)
+	if len(diffs) != 1 || diffs[0].Type != OneofChanged ||
+		!reflect.DeepEqual(diffs[0].Value1, map[string]interface{}{"card": "<redacted len=4>"}) ||
+		!reflect.DeepEqual(diffs[0].Value2, map[string]interface{}{"iban": "<redacted len=4>"}) {
+		t.Errorf("Expected the oneof field values to be redacted, got %v", diffs)
+	}
+
+	// Explanations quoting masked values are dropped
+	version1 := map[string]interface{}{"version": "1.2", "name": "a"}
+	version2 := map[string]interface{}{"version": "1.3", "name": "b"}
+	semver := CompareOptions{SemverKeys: map[string]bool{"version": true}}
+	diffs = findDifferencesWithOptions(version1, version2, "", semver)
+	if diffs[1].Detail == "" {
+		t.Fatalf("Expected a semver detail before redaction, got %v", diffs[1])
+	}
+	diffs = redactDifferences(diffs, nil)
+	if diffs[1].Detail != "" {
+		t.Errorf("Expected the semver detail to be dropped, got %q", diffs[1].Detail)
+	}
+	diffs = redactDifferences(findDifferencesWithOptions(version1, version2, "", semver), []string{"name"})
+	if diffs[1].Detail == "" {
+		t.Errorf("Expected the detail of an unredacted field to be kept, got %v", diffs[1])
+	}
+
+	// The original documents are never modified
+	if obj1["user"].(map[string]interface{})["ssn"] != "123-45-6789" {
+		t.Error("Redaction modified the source document")
+	}
+}
+
+func TestRedactArchiveEntries(t *testing.T) {
+	entries1 := map[string][]byte{"user.json": []byte(`{"ssn": "123-45-6789", "city": "Boston"}`)}
+	entries2 := map[string][]byte{"user.json": []byte(`{"ssn": "987-65-4321", "city": "Denver"}`)}
+
+	results := redactArchiveEntries(compareArchives(entries1, entries2, ReadOptions{Concise: true}, CompareOptions{}), []string{"ssn"})
+	report := formatArchiveEntry(results[0])
+	if strings.Contains(report, "123-45-6789") || strings.Contains(report, "987-65-4321") {
+		t.Errorf("Archive report leaks a redacted value:\n%s", report)
+	}
+	if !strings.Contains(report, "Boston") {
+		t.Errorf("Expected fields that aren't redacted to be shown:\n%s", report)
+	}
+}
+
+func TestRedactThreeWay(t *testing.T) {
+	base := map[string]interface{}{"ssn": "123-45-6789", "name": "John"}
+	left := map[string]interface{}{"ssn": "111-11-1111", "name": "John"}
+	right := map[string]interface{}{"ssn": "222-22-2222", "name": "Johnny"}
+
+	var report strings.Builder
+	for _, result := range redactThreeWay(compareThreeWay(base, left, right, CompareOptions{}), nil) {
+		report.WriteString(formatThreeWayDiff(result))
+	}
+	for _, value := range []string{"123-45-6789", "111-11-1111", "222-22-2222", "Johnny"} {
+		if strings.Contains(report.String(), value) {
+			t.Errorf("Three-way report leaks %q:\n%s", value, report.String())
+		}
+	}
+	if !strings.Contains(report.String(), "[CONFLICT] ssn") {
+		t.Errorf("Expected the conflict to still be reported:\n%s", report.String())
+	}
+}
+
+func TestRedactJSONDiffPatch(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"ssn":  "123-45-6789",
+		"city": "Boston",
+		"ids":  []interface{}{"a", "b"},
+		"old":  "gone",
+	}
+	obj2 := map[string]interface{}{
+		"ssn":  "987-65-4321",
+		"city": "Denver",
+		"ids":  []interface{}{"a", "c", "d"},
+		"new":  "here",
+	}
+
+	delta, _ := jsonDiffPatchDelta(obj1, obj2, "", CompareOptions{})
+	redacted := redactJSONDiffPatch(delta, "", []string{"ssn", "ids"})
+	expected := map[string]interface{}{
+		"ssn":  []interface{}{"<redacted len=11>", "<redacted len=11>"},
+		"city": []interface{}{"Boston", "Denver"},
+		"ids": map[string]interface{}{
+			"_t": "a",
+			"_1": []interface{}{"<redacted len=1>", 0, 0},
+			"1":  []interface{}{"<redacted len=1>"},
+			"2":  []interface{}{"<redacted len=1>"},
+		},
+		"old": []interface{}{"gone", 0, 0},
+		"new": []interface{}{"here"},
+	}
+	if !reflect.DeepEqual(redacted, expected) {
+		t.Errorf("Unexpected redacted delta:\n got: %v\nwant: %v", redacted, expected)
+	}
+
+	// Redacting everything keeps the deletion markers
+	redacted = redactJSONDiffPatch(delta, "", nil)
+	if got := redacted.(map[string]interface{})["old"]; !reflect.DeepEqual(got, []interface{}{"<redacted len=4>", 0, 0}) {
+		t.Errorf("Expected a masked deletion, got %v", got)
+	}
+}
+
+func TestRedactDocument(t *testing.T) {
+	doc := map[string]interface{}{
+		"name": "John",
+		"user": map[string]interface{}{"ssn": "123-45-6789", "age": 30.0},
+		"tags": []interface{}{"a"},
+	}
+
+	// Redacting fields masks only them
+	expected := map[string]interface{}{
+		"name": "John",
+		"user": map[string]interface{}{"ssn": "<redacted len=11>", "age": 30.0},
+		"tags": []interface{}{"a"},
+	}
+	if got := redactDocument(doc, "", []string{"ssn"}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected redacted document:\n got: %v\nwant: %v", got, expected)
+	}
+
+	// Redacting everything masks each scalar but keeps the keys and shape
+	expected = map[string]interface{}{
+		"name": "<redacted len=4>",
+		"user": map[string]interface{}{"ssn": "<redacted len=11>", "age": "<redacted len=2>"},
+		"tags": []interface{}{"<redacted len=1>"},
+	}
+	if got := redactDocument(doc, "", nil); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected redacted document:\n got: %v\nwant: %v", got, expected)
+	}
+}
+
+func TestRedactFuzzyMatches(t *testing.T) {
+	obj1 := map[string]interface{}{"name": "Jonathan", "price": 10.0}
+	obj2 := map[string]interface{}{"name": "Jonathon", "price": 10.001}
+
+	var matches []FuzzyMatch
+	options := CompareOptions{
+		LevenshteinKeys:      map[string]bool{"name": true},
+		LevenshteinThreshold: 2,
+		FloatTolerance:       0.01,
+		FuzzyMatches:         &matches,
+	}
+	findDifferencesWithOptions(obj1, obj2, "", options)
+
+	report := formatThresholdReport(redactFuzzyMatches(matches, []string{"name"}), 0)
+	if strings.Contains(report, "Jonathan") || strings.Contains(report, "Jonathon") {
+		t.Errorf("Threshold report leaks a redacted value:\n%s", report)
+	}
+	if !strings.Contains(report, "name: levenshtein distance 1 of 2") || !strings.Contains(report, "10 vs 10.001") {
+		t.Errorf("Expected distances and values that aren't redacted to be shown:\n%s", report)
+	}
+}
+
+func TestRedactPromotions(t *testing.T) {
+	obj1 := map[string]interface{}{"ids": []interface{}{1.0, 2.0}}
+	obj2 := map[string]interface{}{"ids": []interface{}{"1", 2.0}}
+
+	var promotions []Promotion
+	findDifferencesWithOptions(obj1, obj2, "", CompareOptions{IgnoreNumericType: true, Promotions: &promotions})
+
+	report := formatPromotionReport(redactPromotions(promotions, nil))
+	expected := "\nEqual only once converted to numbers (1):\nids[0]: \"<redacted len=1>\" == \"<redacted len=1>\"\n\n"
+	if report != expected {
+		t.Errorf("Unexpected redacted promotion report %q, expected %q", report, expected)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// refResolver inlines "$ref" pointers, loading referenced files on demand
+type refResolver struct {
+	docs       map[string]interface{} // Parsed documents keyed by absolute file path
+	active     map[string]bool        // References currently being resolved, used to detect cycles
+	allowFiles bool                   // Whether references may load other files
+}
+
+// resolveRefs replaces every {"$ref": "..."} object in data with the fragment it points to.
+// Local references ("#/definitions/item") are resolved against data itself. File
+// references are only followed if allowFiles is set: a relative file
+// ("common.json#/item") is found next to filePath and an absolute one is used as is.
+// Remote references are not supported. Sibling keys of a "$ref" are ignored, as in
+// JSON Reference.
+func resolveRefs(data interface{}, filePath string, allowFiles bool) (interface{}, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %v", err)
+	}
+
+	resolver := &refResolver{
+		docs:       map[string]interface{}{absPath: data},
+		active:     make(map[string]bool),
+		allowFiles: allowFiles,
+	}
+	return resolver.resolve(data, absPath)
+}
+
+// resolve walks node, which belongs to the document at docPath, inlining references
+func (r *refResolver) resolve(node interface{}, docPath string) (interface{}, error) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			return r.resolveRef(ref, docPath)
+		}
+		resolved := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			child, err := r.resolve(val, docPath)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = child
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, val := range v {
+			child, err := r.resolve(val, docPath)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = child
+		}
+		return resolved, nil
+	default:
+		return node, nil
+	}
+}
+
+// resolveRef loads the fragment a single reference points to and resolves it in turn
+func (r *refResolver) resolveRef(ref string, docPath string) (interface{}, error) {
+	filePart, pointer, _ := strings.Cut(ref, "#")
+
+	targetPath := docPath
+	switch {
+	case filePart == "":
+	case strings.Contains(filePart, "://"):
+		return nil, fmt.Errorf("remote $ref %q is not supported", ref)
+	case !r.allowFiles:
+		return nil, fmt.Errorf("$ref %q refers to another file; use -resolve-file-refs to follow it", ref)
+	case filepath.IsAbs(filePart):
+		targetPath = filepath.Clean(filePart)
+	default:
+		targetPath = filepath.Join(filepath.Dir(docPath), filePart)
+	}
+
+	key := targetPath + "#" + pointer
+	if r.active[key] {
+		return nil, fmt.Errorf("circular $ref %q", ref)
+	}
+
+	doc, err := r.load(targetPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve $ref %q: %w", ref, err)
+	}
+
+	fragment, err := evaluatePointer(doc, pointer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve $ref %q: %v", ref, err)
+	}
+
+	r.active[key] = true
+	defer delete(r.active, key)
+
+	return r.resolve(fragment, targetPath)
+}
+
+// load returns the parsed document at path, reading it the first time it is referenced
+func (r *refResolver) load(path string) (interface{}, error) {
+	if doc, ok := r.docs[path]; ok {
+		return doc, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFileRead, err)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w in %s: %w", ErrInvalidJSON, path, err)
+	}
+
+	r.docs[path] = doc
+	return doc, nil
+}
+
+// evaluatePointer returns the value a JSON Pointer (RFC 6901) refers to within doc.
+// Only the empty pointer refers to the whole document; "/" refers to the key "".
+func evaluatePointer(doc interface{}, pointer string) (interface{}, error) {
+	if pointer == "" {
+		return doc, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	current := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		switch v := current.(type) {
+		case map[string]interface{}:
+			val, ok := v[token]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found", token)
+			}
+			current = val
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, fmt.Errorf("invalid array index %q", token)
+			}
+			current = v[index]
+		default:
+			return nil, fmt.Errorf("cannot descend into %q", token)
+		}
+	}
+
+	return current, nil
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveRefs(t *testing.T) {
+	dir := t.TempDir()
+
+	common := `{"definitions": {"address": {"city": "Boston"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "common.json"), []byte(common), 0644); err != nil {
+		t.Fatalf("Failed to write common.json: %v", err)
+	}
+
+	doc := `{
+		"definitions": {"name": {"first": "John"}},
+		"name": {"$ref": "#/definitions/name"},
+		"address": {"$ref": "common.json#/definitions/address"},
+		"list": [{"$ref": "#/definitions/name/first"}]
+	}`
+	mainPath := filepath.Join(dir, "main.json")
+	if err := os.WriteFile(mainPath, []byte(doc), 0644); err != nil {
+		t.Fatalf("Failed to write main.json: %v", err)
+	}
+
+	file, err := readAndValidateJSONWithOptions(mainPath, ReadOptions{Concise: true, ResolveFileRefs: true})
+	if err != nil {
+		t.Fatalf("Failed to resolve refs: %v", err)
+	}
+
+	data := file.Data.(map[string]interface{})
+	if !reflect.DeepEqual(data["name"], map[string]interface{}{"first": "John"}) {
+		t.Errorf("Local $ref not inlined, got %v", data["name"])
+	}
+	if !reflect.DeepEqual(data["address"], map[string]interface{}{"city": "Boston"}) {
+		t.Errorf("File-relative $ref not inlined, got %v", data["address"])
+	}
+	if !reflect.DeepEqual(data["list"], []interface{}{"John"}) {
+		t.Errorf("$ref inside array not inlined, got %v", data["list"])
+	}
+}
+
+func TestResolveFileRefsGated(t *testing.T) {
+	dir := t.TempDir()
+	commonPath := filepath.Join(dir, "common.json")
+	if err := os.WriteFile(commonPath, []byte(`{"city": "Boston"}`), 0644); err != nil {
+		t.Fatalf("Failed to write common.json: %v", err)
+	}
+	docPath := filepath.Join(t.TempDir(), "doc.json")
+
+	relative := map[string]interface{}{"address": map[string]interface{}{"$ref": "common.json#"}}
+	if _, err := resolveRefs(relative, filepath.Join(dir, "doc.json"), false); err == nil || !strings.Contains(err.Error(), "-resolve-file-refs") {
+		t.Errorf("Expected file $ref to be rejected without -resolve-file-refs, got %v", err)
+	}
+
+	// An absolute file part is used as is, not joined to the document's directory
+	absolute := map[string]interface{}{"address": map[string]interface{}{"$ref": commonPath + "#/city"}}
+	resolved, err := resolveRefs(absolute, docPath, true)
+	if err != nil {
+		t.Fatalf("Failed to resolve absolute file $ref: %v", err)
+	}
+	if got := resolved.(map[string]interface{})["address"]; got != "Boston" {
+		t.Errorf("Absolute file $ref not inlined, got %v", got)
+	}
+
+	remote := map[string]interface{}{"a": map[string]interface{}{"$ref": "https://example.com/schema.json#/a"}}
+	if _, err := resolveRefs(remote, docPath, true); err == nil || !strings.Contains(err.Error(), "remote") {
+		t.Errorf("Expected remote $ref to be rejected, got %v", err)
+	}
+}
+
+func TestResolveRefsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     map[string]interface{}
+		expected string
+	}{
+		{
+			name: "Circular reference",
+			data: map[string]interface{}{
+				"a": map[string]interface{}{"$ref": "#/b"},
+				"b": map[string]interface{}{"$ref": "#/a"},
+			},
+			expected: "circular",
+		},
+		{
+			name: "Self reference",
+			data: map[string]interface{}{
+				"node": map[string]interface{}{
+					"child": map[string]interface{}{"$ref": "#/node"},
+				},
+			},
+			expected: "circular",
+		},
+		{
+			name: "Missing target",
+			data: map[string]interface{}{
+				"a": map[string]interface{}{"$ref": "#/missing"},
+			},
+			expected: "not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := resolveRefs(tt.data, filepath.Join(t.TempDir(), "doc.json"), true)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestEvaluatePointer(t *testing.T) {
+	doc := map[string]interface{}{
+		"":    "empty key",
+		"a/b": "slash",
+		"m~n": "tilde",
+		"list": []interface{}{
+			map[string]interface{}{"": "nested empty key"},
+		},
+	}
+
+	tests := []struct {
+		pointer  string
+		expected interface{}
+	}{
+		{"", doc},
+		{"/", "empty key"},
+		{"/a~1b", "slash"},
+		{"/m~0n", "tilde"},
+		{"/list/0/", "nested empty key"},
+	}
+
+	for _, tt := range tests {
+		got, err := evaluatePointer(doc, tt.pointer)
+		if err != nil {
+			t.Errorf("evaluatePointer(%q) failed: %v", tt.pointer, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("evaluatePointer(%q) = %v, expected %v", tt.pointer, got, tt.expected)
+		}
+	}
+
+	// Without a key "", "/" does not fall back to the root
+	if _, err := evaluatePointer(map[string]interface{}{"a": 1.0}, "/"); err == nil {
+		t.Error("Expected an error for \"/\" without a key \"\"")
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseRequiredKeys parses a path:key1,key2 specification of the keys an
+// object must keep, using . for the root
+func parseRequiredKeys(spec string) (string, []string, error) {
+	path, list, found := cutLast(spec, ":")
+	if !found || path == "" || list == "" {
+		return "", nil, fmt.Errorf("invalid required keys %q, expected path:key1,key2", spec)
+	}
+	if path == "." {
+		path = ""
+	}
+	if err := validatePathExpr(path); err != nil {
+		return "", nil, err
+	}
+
+	keys := strings.Split(list, ",")
+	for _, key := range keys {
+		if key == "" {
+			return "", nil, fmt.Errorf("invalid required keys %q, key names must not be empty", spec)
+		}
+	}
+	return path, keys, nil
+}
+
+// addRequiredKeys parses spec and adds its keys to required
+func addRequiredKeys(required map[string]map[string]bool, spec string) error {
+	path, keys, err := parseRequiredKeys(spec)
+	if err != nil {
+		return err
+	}
+	if required[path] == nil {
+		required[path] = make(map[string]bool)
+	}
+	for _, key := range keys {
+		required[path][key] = true
+	}
+	return nil
+}
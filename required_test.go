@@ -0,0 +1,51 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"testing"
+)
+
+func TestRequiredKeys(t *testing.T) {
+	required := make(map[string]map[string]bool)
+	for _, spec := range []string{"user:id,email", ".:version"} {
+		if err := addRequiredKeys(required, spec); err != nil {
+			t.Fatalf("addRequiredKeys(%q) returned error: %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"user", "user:", ":id", "user:id,,email"} {
+		if _, _, err := parseRequiredKeys(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+
+	obj1 := map[string]interface{}{
+		"version": 1.0,
+		"user":    map[string]interface{}{"id": 1.0, "email": "a@example.com", "nickname": "al"},
+		"other":   map[string]interface{}{"id": 2.0},
+	}
+	obj2 := map[string]interface{}{
+		"user":  map[string]interface{}{"email": "a@example.com"},
+		"other": map[string]interface{}{},
+	}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{RequiredKeys: required})
+	expected := map[string]DiffType{
+		"other.id":      KeyOnlyInFirst,
+		"user.id":       RequiredMissing,
+		"user.nickname": KeyOnlyInFirst,
+		"version":       RequiredMissing,
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d differences, got %v", len(expected), diffs)
+	}
+	for _, diff := range diffs {
+		if diff.Type != expected[diff.Path] {
+			t.Errorf("Expected %s to be %s, got %s", diff.Path, expected[diff.Path], diff.Type)
+		}
+		if diff.Type == RequiredMissing && diff.Severity != SeverityCritical {
+			t.Errorf("Expected %s to be critical, got %s", diff.Path, diff.Severity)
+		}
+	}
+}
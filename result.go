@@ -0,0 +1,82 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+// Result is the outcome of comparing two JSON values with Compare
+type Result struct {
+	Diffs   []Diff         // Differences found, after IgnorePaths and OnlyPaths
+	Equal   bool           // True if no differences were found
+	Partial bool           // True if Options.Context was done before the comparison finished, so Diffs may be incomplete
+	Stats   Stats          // Counts of the differences
+	Options CompareOptions // Options the comparison ran with
+}
+
+// Stats counts the differences of a comparison
+type Stats struct {
+	Total      int
+	ByType     map[DiffType]int
+	BySeverity map[Severity]int
+}
+
+// Compare compares two JSON values and returns the differences along with
+// whether the values are equal and counts of the differences, so callers can
+// summarize a comparison without scanning the differences again.
+// FindDifferences remains for callers that only need the differences.
+func Compare(a, b interface{}, options CompareOptions) Result {
+	return newResult(findDifferencesWithOptions(a, b, "", options), options)
+}
+
+// newResult returns the result of a comparison run with options that found
+// differences
+func newResult(differences []Diff, options CompareOptions) Result {
+	result := Result{
+		Partial: options.Context != nil && options.Context.Err() != nil,
+		Options: options,
+	}
+	return result.withDiffs(differences)
+}
+
+// withDiffs returns the result with its differences replaced, e.g. by those
+// left after filtering, and its equality and counts updated to match
+func (r Result) withDiffs(differences []Diff) Result {
+	r.Diffs = differences
+	r.Equal = len(differences) == 0
+	r.Stats = newStats(differences)
+	return r
+}
+
+// newStats counts differences by type and by severity
+func newStats(differences []Diff) Stats {
+	stats := Stats{
+		Total:      len(differences),
+		ByType:     make(map[DiffType]int),
+		BySeverity: make(map[Severity]int),
+	}
+	for _, diff := range differences {
+		stats.ByType[diff.Type]++
+		stats.BySeverity[diff.Severity]++
+	}
+	return stats
+}
+
+// without returns the counts with those of differences taken away, e.g. to
+// count the differences that weren't shown
+func (s Stats) without(differences []Diff) Stats {
+	remaining := Stats{
+		Total:      s.Total - len(differences),
+		ByType:     make(map[DiffType]int, len(s.ByType)),
+		BySeverity: make(map[Severity]int, len(s.BySeverity)),
+	}
+	for diffType, count := range s.ByType {
+		remaining.ByType[diffType] = count
+	}
+	for severity, count := range s.BySeverity {
+		remaining.BySeverity[severity] = count
+	}
+	for _, diff := range differences {
+		remaining.ByType[diff.Type]--
+		remaining.BySeverity[diff.Severity]--
+	}
+	return remaining
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	obj1 := map[string]interface{}{"name": "Ada", "age": 36.0, "city": "London"}
+	obj2 := map[string]interface{}{"name": "ada", "age": "36", "email": "ada@example.com"}
+	options := CompareOptions{IgnoreCaseValues: true}
+
+	result := Compare(obj1, obj2, options)
+	if result.Equal || len(result.Diffs) != 3 || result.Partial {
+		t.Fatalf("Expected three differences, got %+v", result)
+	}
+	if result.Stats.Total != 3 || result.Stats.ByType[ValueMismatch] != 1 || result.Stats.ByType[KeyOnlyInFirst] != 1 || result.Stats.ByType[KeyOnlyInSecond] != 1 {
+		t.Errorf("Unexpected counts by type %+v", result.Stats)
+	}
+	if result.Stats.BySeverity[SeverityError] != 2 || result.Stats.BySeverity[SeverityWarning] != 1 {
+		t.Errorf("Unexpected counts by severity %+v", result.Stats.BySeverity)
+	}
+	if !result.Options.IgnoreCaseValues {
+		t.Error("Expected the result to carry the options")
+	}
+
+	// Compare agrees with FindDifferences
+	if diffs := FindDifferences(obj1, obj2, "", false, true, false, false, false, false, nil, nil, 0); len(diffs) != len(result.Diffs) {
+		t.Errorf("Expected FindDifferences to find %d differences, got %d", len(result.Diffs), len(diffs))
+	}
+
+	result = Compare(obj1, obj1, options)
+	if !result.Equal || result.Stats.Total != 0 || result.Diffs == nil {
+		t.Errorf("Expected equal values, got %+v", result)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	options.Context = ctx
+	if result = Compare(obj1, obj2, options); !result.Partial {
+		t.Error("Expected a comparison with a done context to be partial")
+	}
+}
+
+func TestResultWithDiffs(t *testing.T) {
+	obj1 := map[string]interface{}{"a": 1.0, "b": 2.0, "c": 3.0}
+	obj2 := map[string]interface{}{"a": 2.0, "b": 3.0}
+	result := Compare(obj1, obj2, CompareOptions{})
+
+	// Filtering keeps the result consistent with the differences left
+	filtered := result.withDiffs(result.Diffs[:1])
+	if filtered.Equal || filtered.Stats.Total != 1 || filtered.Stats.ByType[KeyOnlyInFirst] != 0 {
+		t.Errorf("Unexpected filtered result %+v", filtered)
+	}
+	if empty := result.withDiffs(nil); !empty.Equal || empty.Stats.Total != 0 {
+		t.Errorf("Expected a result without differences to be equal, got %+v", empty)
+	}
+
+	// Counts of the differences not shown
+	hidden := result.Stats.without(result.Diffs[:2])
+	if hidden.Total != 1 || hidden.ByType[ValueMismatch] != 0 || hidden.ByType[KeyOnlyInFirst] != 1 || hidden.BySeverity[SeverityError] != 1 {
+		t.Errorf("Unexpected remaining counts %+v", hidden)
+	}
+	if result.Stats.Total != 3 || result.Stats.ByType[ValueMismatch] != 2 {
+		t.Errorf("Expected the original counts to be unchanged, got %+v", result.Stats)
+	}
+}
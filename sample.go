@@ -0,0 +1,64 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// ArraySample records an array whose elements were only partly compared
+type ArraySample struct {
+	Path     string
+	Compared int // Number of index-aligned elements compared
+	Total    int // Number of index-aligned elements in both arrays
+}
+
+// sampleIndices returns the indices of the elements to compare out of n
+// index-aligned elements, in ascending order. When n is no more than size,
+// every index is returned. The choice depends only on seed and path, so runs
+// are reproducible regardless of the order arrays are visited in.
+func sampleIndices(n, size int, seed int64, path string) []int {
+	if size <= 0 || n <= size {
+		indices := make([]int, n)
+		for i := range indices {
+			indices[i] = i
+		}
+		return indices
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(path))
+	rng := rand.New(rand.NewSource(seed ^ int64(hash.Sum64())))
+
+	indices := rng.Perm(n)[:size]
+	sort.Ints(indices)
+	return indices
+}
+
+// prefixArraySamples prepends prefix to the path of every sample
+func prefixArraySamples(samples []ArraySample, prefix string) []ArraySample {
+	for i := range samples {
+		samples[i].Path = prefixPath(prefix, samples[i].Path)
+	}
+	return samples
+}
+
+// formatSampleReport renders which arrays were sampled
+func formatSampleReport(samples []ArraySample) string {
+	if len(samples) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\nSampled %d arrays; differences in elements that were not compared are not reported:\n", len(samples))
+	for _, sample := range samples {
+		fmt.Fprintf(&sb, "%s: compared %d of %d elements\n", sample.Path, sample.Compared, sample.Total)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSampleIndices(t *testing.T) {
+	// Short arrays are compared in full
+	if indices := sampleIndices(3, 5, 0, "items"); !reflect.DeepEqual(indices, []int{0, 1, 2}) {
+		t.Errorf("Expected every index, got %v", indices)
+	}
+	if indices := sampleIndices(3, 0, 0, "items"); len(indices) != 3 {
+		t.Errorf("Expected every index without sampling, got %v", indices)
+	}
+
+	indices := sampleIndices(1000, 10, 42, "items")
+	if len(indices) != 10 {
+		t.Fatalf("Expected 10 sampled indices, got %v", indices)
+	}
+	for n := 1; n < len(indices); n++ {
+		if indices[n] <= indices[n-1] || indices[n] >= 1000 {
+			t.Errorf("Expected distinct ascending indices, got %v", indices)
+		}
+	}
+
+	// The same seed and path always give the same sample
+	if again := sampleIndices(1000, 10, 42, "items"); !reflect.DeepEqual(indices, again) {
+		t.Errorf("Expected a reproducible sample, got %v and %v", indices, again)
+	}
+	if other := sampleIndices(1000, 10, 43, "items"); reflect.DeepEqual(indices, other) {
+		t.Errorf("Expected a different seed to give a different sample, got %v", other)
+	}
+}
+
+func TestSampleArrays(t *testing.T) {
+	arr1 := make([]interface{}, 100)
+	arr2 := make([]interface{}, 101)
+	for i := range arr1 {
+		arr1[i] = float64(i)
+		arr2[i] = float64(-i - 1) // Every element differs
+	}
+	arr2[100] = 0.0
+
+	var samples []ArraySample
+	diffs := findDifferencesWithOptions(
+		map[string]interface{}{"items": arr1},
+		map[string]interface{}{"items": arr2},
+		"",
+		CompareOptions{SampleArrays: 5, SampleSeed: 7, SampledArrays: &samples},
+	)
+
+	// The length difference plus one difference per sampled element
+	if len(diffs) != 6 || diffs[0].Type != ArrayLength {
+		t.Errorf("Expected a length difference and 5 sampled differences, got %d: %v", len(diffs), diffs)
+	}
+	if len(samples) != 1 || samples[0] != (ArraySample{Path: "items", Compared: 5, Total: 100}) {
+		t.Errorf("Expected items to be recorded as sampled, got %v", samples)
+	}
+}
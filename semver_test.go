@@ -0,0 +1,59 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"testing"
+)
+
+func TestSemverComparison(t *testing.T) {
+	testCases := []struct {
+		name   string
+		val1   interface{}
+		val2   interface{}
+		equal  bool
+		parsed bool
+	}{
+		{"Missing patch", "1.2", "1.2.0", true, true},
+		{"Missing minor and patch", "2", "2.0.0", true, true},
+		{"Leading v", "v1.2.3", "1.2.3", true, true},
+		{"Different patch", "1.2.1", "1.2.0", false, true},
+		{"Prerelease differs", "1.2.0-beta", "1.2.0", false, true},
+		{"Not a version", "latest", "1.2.0", false, false},
+		{"Not a string", 1.2, "1.2.0", false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			equal, parsed := compareSemver(tc.val1, tc.val2)
+			if equal != tc.equal || parsed != tc.parsed {
+				t.Errorf("compareSemver(%v, %v) = %v, %v, want %v, %v",
+					tc.val1, tc.val2, equal, parsed, tc.equal, tc.parsed)
+			}
+		})
+	}
+
+	obj1 := map[string]interface{}{"version": "1.2", "next": "1.3", "channel": "stable", "other": "1.2"}
+	obj2 := map[string]interface{}{"version": "1.2.0", "next": "1.4.0", "channel": "beta", "other": "1.2.0"}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		SemverKeys: map[string]bool{"version": true, "next": true, "channel": true},
+	})
+
+	// Non-semver strings fall back to string comparison, and unlisted keys aren't parsed
+	expected := map[string]string{
+		"channel": "",
+		"next":    "semver 1.3.0 < 1.4.0",
+		"other":   "",
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d differences, got %d: %v", len(expected), len(diffs), diffs)
+	}
+	for _, diff := range diffs {
+		detail, ok := expected[diff.Path]
+		if !ok || diff.Detail != detail {
+			t.Errorf("Unexpected difference at %s with detail %q", diff.Path, diff.Detail)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Severity classifies how serious a difference is, from least to most severe
+type Severity int
+
+// Enum values for Severity
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+// MarshalJSON implements the json.Marshaler interface for Severity
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for Severity
+func (s *Severity) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	parsed, err := parseSeverity(name)
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// String returns the string representation of a Severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// parseSeverity parses a severity name such as "critical"
+func parseSeverity(name string) (Severity, error) {
+	for s := SeverityInfo; s.String() != "unknown"; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return SeverityInfo, fmt.Errorf("unknown severity %q, expected info, warning, error or critical", name)
+}
+
+// defaultSeverity derives a difference's severity from its type:
+// structural changes are errors, value changes are warnings
+func defaultSeverity(diffType DiffType) Severity {
+	switch diffType {
+	case KeyOnlyInFirst, KeyOnlyInSecond, ArrayLength, TypeMismatch, DocumentCount, DepthExceeded, OneofChanged:
+		return SeverityError
+	case RequiredMissing:
+		return SeverityCritical
+	case ArrayDiffsTruncated:
+		return SeverityInfo
+	default:
+		return SeverityWarning
+	}
+}
+
+// assignSeverities sets the severity of every difference from its type, or
+// from the override for the most specific path expression in overrides
+// matching it
+func assignSeverities(differences []Diff, overrides map[string]Severity) []Diff {
+	for i := range differences {
+		differences[i].Severity = defaultSeverity(differences[i].Type)
+
+		matched := -1
+		for path, severity := range overrides {
+			if matchPathExpr(path, differences[i].Path) && len(path) > matched {
+				differences[i].Severity = severity
+				matched = len(path)
+			}
+		}
+	}
+	return differences
+}
+
+// hasSeverity reports whether any difference is at least as severe as minimum
+func hasSeverity(differences []Diff, minimum Severity) bool {
+	for _, diff := range differences {
+		if diff.Severity >= minimum {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSeverity(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"name":    "John",
+		"price":   map[string]interface{}{"amount": 10.0, "currency": "USD"},
+		"address": map[string]interface{}{"city": "New York"},
+	}
+	obj2 := map[string]interface{}{
+		"name":    "Jane",
+		"price":   map[string]interface{}{"amount": 12.0, "currency": "EUR"},
+		"address": map[string]interface{}{},
+	}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		SeverityOverrides: map[string]Severity{
+			"price":          SeverityCritical,
+			"price.currency": SeverityInfo,
+		},
+	})
+
+	expected := map[string]Severity{
+		"address.city":   SeverityError,    // Derived from the type
+		"name":           SeverityWarning,  // Derived from the type
+		"price.amount":   SeverityCritical, // Overridden by its parent path
+		"price.currency": SeverityInfo,     // The most specific override wins
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d differences, got %v", len(expected), diffs)
+	}
+	for _, diff := range diffs {
+		if diff.Severity != expected[diff.Path] {
+			t.Errorf("Expected %s to have severity %s, got %s", diff.Path, expected[diff.Path], diff.Severity)
+		}
+	}
+
+	if !hasSeverity(diffs, SeverityCritical) || hasSeverity(diffs[:2], SeverityCritical) {
+		t.Error("Unexpected result from hasSeverity")
+	}
+
+	// Severities round-trip through JSON by name
+	data, err := json.Marshal(diffs[2])
+	if err != nil {
+		t.Fatalf("Failed to marshal difference: %v", err)
+	}
+	var decoded Diff
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Severity != SeverityCritical {
+		t.Errorf("Expected severity to round-trip, got %v (%v) from %s", decoded.Severity, err, data)
+	}
+
+	if _, err := parseSeverity("fatal"); err == nil {
+		t.Error("Expected an unknown severity to be rejected")
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// parseWeight parses a path:weight rule, where path is a path expression and
+// . is the root
+func parseWeight(rule string) (string, float64, error) {
+	path, value, found := cutLast(rule, ":")
+	weight, err := strconv.ParseFloat(value, 64)
+	if !found || err != nil || weight < 0 {
+		return "", 0, fmt.Errorf("invalid weight %q, expected path:weight with a non-negative number", rule)
+	}
+	if path == "." {
+		path = ""
+	}
+	if err := validatePathExpr(path); err != nil {
+		return "", 0, err
+	}
+	return path, weight, nil
+}
+
+// leafWeight returns the weight of the leaf at path: the weight of the most
+// specific path expression in weights matching it, or 1
+func leafWeight(path string, weights map[string]float64) float64 {
+	weight, matched := 1.0, -1
+	for expr, w := range weights {
+		if matchPathExpr(expr, path) && len(expr) > matched {
+			weight, matched = w, len(expr)
+		}
+	}
+	return weight
+}
+
+// SimilarityScore returns how similar two JSON values are, from 0 (nothing
+// in common) to 1 (equal under the comparison options): the weighted
+// fraction of leaf values, in either document, that match a leaf at the same
+// path in the other. Leaves are weighted 1 unless options.Weights says
+// otherwise. Values only equal through a Levenshtein match get partial
+// credit, the fraction of their characters that didn't need an edit.
+func SimilarityScore(a, b interface{}, options CompareOptions) float64 {
+	matched, total := similarity(a, b, "", options)
+	if total == 0 {
+		// Neither document has a weighted leaf; empty containers of one kind are alike
+		if jsonEqual(a, b) || (isComplex(a) && isComplex(b) && jsonTypeName(a) == jsonTypeName(b)) {
+			return 1
+		}
+		return 0
+	}
+	return matched / total
+}
+
+// similarity returns the weight of the matching leaves under two values at
+// path and the total weight of the leaves compared
+func similarity(val1, val2 interface{}, path string, options CompareOptions) (float64, float64) {
+	switch v1 := val1.(type) {
+	case map[string]interface{}:
+		if v2, ok := val2.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(v1)+len(v2))
+			for key := range v1 {
+				keys = append(keys, key)
+			}
+			for key := range v2 {
+				if _, ok := v1[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+
+			var matched, total float64
+			for _, key := range keys {
+				m, t := similarityAt(v1, v2, key, joinPath(path, key), options)
+				matched += m
+				total += t
+			}
+			return matched, total
+		}
+	case []interface{}:
+		if v2, ok := val2.([]interface{}); ok {
+			var matched, total float64
+			for i := 0; i < len(v1) || i < len(v2); i++ {
+				elemPath := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(v2):
+					total += leafWeights(v1[i], elemPath, options.Weights)
+				case i >= len(v1):
+					total += leafWeights(v2[i], elemPath, options.Weights)
+				default:
+					m, t := similarity(v1[i], v2[i], elemPath, options)
+					matched += m
+					total += t
+				}
+			}
+			return matched, total
+		}
+	}
+
+	// Values of different kinds share nothing; count the larger side
+	if isComplex(val1) || isComplex(val2) {
+		w1, w2 := leafWeights(val1, path, options.Weights), leafWeights(val2, path, options.Weights)
+		if w1 > w2 {
+			return 0, w1
+		}
+		return 0, w2
+	}
+
+	weight := leafWeight(path, options.Weights)
+	equal, match := compareValues(val1, val2, path, options)
+	switch {
+	case !equal:
+		return 0, weight
+	case match != nil && match.Kind == FuzzyLevenshtein:
+		return weight * stringSimilarity(val1.(string), val2.(string), match.Distance), weight
+	default:
+		return weight, weight
+	}
+}
+
+// similarityAt compares the values at key in two objects, counting a key
+// in only one of them as unmatched
+func similarityAt(obj1, obj2 map[string]interface{}, key, path string, options CompareOptions) (float64, float64) {
+	val1, ok1 := obj1[key]
+	val2, ok2 := obj2[key]
+	switch {
+	case !ok2:
+		return 0, leafWeights(val1, path, options.Weights)
+	case !ok1:
+		return 0, leafWeights(val2, path, options.Weights)
+	default:
+		return similarity(val1, val2, path, options)
+	}
+}
+
+// leafWeights returns the total weight of the leaves of a value at path. An
+// empty object or array counts as one leaf.
+func leafWeights(val interface{}, path string, weights map[string]float64) float64 {
+	var total float64
+	switch v := val.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return leafWeight(path, weights)
+		}
+		for key, elem := range v {
+			total += leafWeights(elem, joinPath(path, key), weights)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return leafWeight(path, weights)
+		}
+		for i, elem := range v {
+			total += leafWeights(elem, fmt.Sprintf("%s[%d]", path, i), weights)
+		}
+	default:
+		return leafWeight(path, weights)
+	}
+	return total
+}
+
+// stringSimilarity returns the fraction of two strings' characters that
+// needed no edit, given the edit distance between them
+func stringSimilarity(str1, str2 string, distance float64) float64 {
+	longest := utf8.RuneCountInString(str1)
+	if n := utf8.RuneCountInString(str2); n > longest {
+		longest = n
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - distance/float64(longest)
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimilarityScore(t *testing.T) {
+	record1 := map[string]interface{}{"id": 1.0, "name": "Alice", "city": "Leeds", "tags": []interface{}{"a", "b"}}
+	record2 := map[string]interface{}{"id": 1.0, "name": "Alicia", "city": "York", "tags": []interface{}{"a"}}
+
+	tests := []struct {
+		name    string
+		a, b    interface{}
+		options CompareOptions
+		want    float64
+	}{
+		{"equal", record1, record1, CompareOptions{}, 1},
+		{"empty", map[string]interface{}{}, map[string]interface{}{}, CompareOptions{}, 1},
+		{"nothing shared", map[string]interface{}{"a": 1.0}, []interface{}{1.0}, CompareOptions{}, 0},
+		// id and tags[0] match out of id, name, city, tags[0] and tags[1]
+		{"unweighted", record1, record2, CompareOptions{}, 2.0 / 5},
+		{"weighted", record1, record2, CompareOptions{Weights: map[string]float64{"id": 5, "tags": 0}}, 5.0 / 7},
+		{"most specific weight", record1, record2, CompareOptions{Weights: map[string]float64{"": 0, "id": 1}}, 1},
+		// Alice and Alicia are 2 edits apart out of 6 characters, so name scores 4/6
+		{"levenshtein credit", record1, record2, CompareOptions{LevenshteinKeys: map[string]bool{"name": true}, LevenshteinThreshold: 2}, (2 + 4.0/6) / 5},
+		{"ignore case", map[string]interface{}{"a": "X"}, map[string]interface{}{"a": "x"}, CompareOptions{IgnoreCaseValues: true}, 1},
+	}
+
+	for _, tc := range tests {
+		if got := SimilarityScore(tc.a, tc.b, tc.options); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s: SimilarityScore = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseWeight(t *testing.T) {
+	path, weight, err := parseWeight("users[*].id:2.5")
+	if err != nil || path != "users[*].id" || weight != 2.5 {
+		t.Errorf("parseWeight = %q, %v, %v", path, weight, err)
+	}
+	if path, _, err := parseWeight(".:0"); err != nil || path != "" {
+		t.Errorf("Expected . to be the root, got %q (%v)", path, err)
+	}
+	for _, rule := range []string{"id", "id:x", "id:-1", "a..b:1"} {
+		if _, _, err := parseWeight(rule); err == nil {
+			t.Errorf("Expected an error for %q", rule)
+		}
+	}
+}
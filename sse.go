@@ -0,0 +1,64 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// writeSSEEvent writes one Server-Sent Event with a JSON-encoded payload
+func writeSSEEvent(w io.Writer, event string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// writeDifferencesSSE writes each difference as a "diff" Server-Sent Event,
+// followed by a "done" event carrying the total count. After every event,
+// flush is called if it is not nil so clients see differences immediately.
+func writeDifferencesSSE(w io.Writer, differences []Diff, flush func()) error {
+	for _, diff := range differences {
+		diff.Value1 = encodeNonFinite(diff.Value1)
+		diff.Value2 = encodeNonFinite(diff.Value2)
+		if err := writeSSEEvent(w, "diff", diff); err != nil {
+			return err
+		}
+		if flush != nil {
+			flush()
+		}
+	}
+
+	err := writeSSEEvent(w, "done", map[string]int{"differences": len(differences)})
+	if flush != nil {
+		flush()
+	}
+	return err
+}
+
+// ServeDiff compares a and b and streams the differences to an HTTP client
+// as Server-Sent Events, for use from a web front-end:
+//
+//	http.HandleFunc("/diff", func(w http.ResponseWriter, r *http.Request) {
+//		ServeDiff(w, before, after, CompareOptions{})
+//	})
+func ServeDiff(w http.ResponseWriter, a, b interface{}, opts CompareOptions) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	var flush func()
+	if flusher, ok := w.(http.Flusher); ok {
+		flush = flusher.Flush
+	}
+
+	// Write errors mean the client went away, so there is no one left to tell
+	_ = writeDifferencesSSE(w, findDifferencesWithOptions(a, b, "", opts), flush)
+}
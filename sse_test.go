@@ -0,0 +1,45 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeDiff(t *testing.T) {
+	a := map[string]interface{}{"name": "John", "age": 30.0}
+	b := map[string]interface{}{"name": "Jane", "age": 30.0, "email": "jane@example.com"}
+
+	recorder := httptest.NewRecorder()
+	ServeDiff(recorder, a, b, CompareOptions{})
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %q", contentType)
+	}
+	if !recorder.Flushed {
+		t.Error("Expected events to be flushed")
+	}
+
+	expected := "event: diff\n" +
+		`data: {"path":"email","type":"key_only_in_second","value1":null,"value2":"jane@example.com","parentType":"object","severity":"error"}` + "\n\n" +
+		"event: diff\n" +
+		`data: {"path":"name","type":"value_mismatch","value1":"John","value2":"Jane","parentType":"object","severity":"warning"}` + "\n\n" +
+		"event: done\n" +
+		`data: {"differences":2}` + "\n\n"
+	if body := recorder.Body.String(); body != expected {
+		t.Errorf("Unexpected event stream:\n%s\nwant:\n%s", body, expected)
+	}
+}
+
+func TestWriteDifferencesSSE(t *testing.T) {
+	var sb strings.Builder
+	if err := writeDifferencesSSE(&sb, []Diff{}, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sb.String() != "event: done\ndata: {\"differences\":0}\n\n" {
+		t.Errorf("Expected only a done event, got %q", sb.String())
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// parseTagRule parses a path:tag rule, where path is a path expression and
+// . is the root
+func parseTagRule(rule string) (string, string, error) {
+	path, tag, found := cutLast(rule, ":")
+	if !found || tag == "" {
+		return "", "", fmt.Errorf("invalid tag %q, expected path:tag", rule)
+	}
+	if path == "." {
+		path = ""
+	}
+	if err := validatePathExpr(path); err != nil {
+		return "", "", err
+	}
+	return path, tag, nil
+}
+
+// assignTags sets the tags of every difference to those of all the path
+// expressions in pathTags matching it, sorted and without duplicates
+func assignTags(differences []Diff, pathTags map[string][]string) []Diff {
+	if len(pathTags) == 0 {
+		return differences
+	}
+	for i := range differences {
+		seen := make(map[string]bool)
+		var tags []string
+		for path, pathTag := range pathTags {
+			if !matchPathExpr(path, differences[i].Path) {
+				continue
+			}
+			for _, tag := range pathTag {
+				if !seen[tag] {
+					seen[tag] = true
+					tags = append(tags, tag)
+				}
+			}
+		}
+		sort.Strings(tags)
+		differences[i].Tags = tags
+	}
+	return differences
+}
+
+// filterTags keeps only the differences with at least one of the given tags
+func filterTags(differences []Diff, tags []string) []Diff {
+	filtered := []Diff{}
+	for _, diff := range differences {
+		for _, tag := range tags {
+			if hasTag(diff, tag) {
+				filtered = append(filtered, diff)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// hasTag reports whether a difference has the given tag
+func hasTag(diff Diff, tag string) bool {
+	for _, t := range diff.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// formatTags renders a difference's tags as a "[tag1, tag2] " prefix for the
+// console output, or returns "" if it has none
+func formatTags(diff Diff) string {
+	if len(diff.Tags) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(diff.Tags, ", ") + "] "
+}
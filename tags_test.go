@@ -0,0 +1,54 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"billing": map[string]interface{}{"amount": float64(10), "currency": "USD"},
+		"name":    "Alice",
+	}
+	obj2 := map[string]interface{}{
+		"billing": map[string]interface{}{"amount": float64(12), "currency": "USD"},
+		"name":    "Bob",
+	}
+
+	pathTags := make(map[string][]string)
+	for _, rule := range []string{"billing:financial", "billing.amount:pricing", ".:customer", "billing:financial"} {
+		path, tag, err := parseTagRule(rule)
+		if err != nil {
+			t.Fatalf("parseTagRule(%q) returned error: %v", rule, err)
+		}
+		pathTags[path] = append(pathTags[path], tag)
+	}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{PathTags: pathTags})
+	got := make(map[string][]string)
+	for _, diff := range diffs {
+		got[diff.Path] = diff.Tags
+	}
+	expected := map[string][]string{
+		"billing.amount": {"customer", "financial", "pricing"},
+		"name":           {"customer"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected tags %v, got %v", expected, got)
+	}
+
+	filtered := filterTags(diffs, []string{"financial"})
+	if len(filtered) != 1 || filtered[0].Path != "billing.amount" {
+		t.Errorf("Expected only billing.amount with tag financial, got %v", filtered)
+	}
+	if prefix := formatTags(filtered[0]); prefix != "[customer, financial, pricing] " {
+		t.Errorf("Unexpected tag prefix %q", prefix)
+	}
+
+	if _, _, err := parseTagRule("billing"); err == nil {
+		t.Error("Expected an error for a rule without a tag")
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MergeStatus says which side of a three-way comparison changed a path
+type MergeStatus int
+
+// Enum values for MergeStatus
+const (
+	ChangedLeft MergeStatus = iota
+	ChangedRight
+	ChangedBoth // Both sides made the same change
+	Conflict    // Both sides changed overlapping paths differently
+)
+
+// String returns the string representation of a MergeStatus
+func (s MergeStatus) String() string {
+	switch s {
+	case ChangedLeft:
+		return "left"
+	case ChangedRight:
+		return "right"
+	case ChangedBoth:
+		return "both"
+	case Conflict:
+		return "conflict"
+	default:
+		return "unknown"
+	}
+}
+
+// ThreeWayDiff is a change from the base at Path, with the differences each
+// side reported against the base. A conflict may group several differences
+// whose paths overlap, e.g. a key edited on one side inside an object removed
+// on the other; Path is then the outermost of them.
+type ThreeWayDiff struct {
+	Path   string
+	Status MergeStatus
+	Left   []Diff
+	Right  []Diff
+}
+
+// pathsOverlap reports whether one path is the other or contains it
+func pathsOverlap(path1, path2 string) bool {
+	return isUnderPath(path1, path2) || isUnderPath(path2, path1)
+}
+
+// compareThreeWay compares left and right against their common base and
+// correlates the two sets of differences by path. Paths changed on one side
+// only, changed identically on both, and changed differently on both
+// (conflicts) are reported, sorted by path; unchanged paths are not.
+func compareThreeWay(base, left, right interface{}, options CompareOptions) []ThreeWayDiff {
+	leftDiffs := findDifferencesWithOptions(base, left, "", options)
+	rightDiffs := findDifferencesWithOptions(base, right, "", options)
+
+	// sameChange reports whether both sides hold an equal value at path now.
+	// The differences it finds are not reported.
+	checkOptions := options
+	checkOptions.OnDiff = nil
+	sameChange := func(path string) bool {
+		val1, found1, err1 := lookupPath(left, path)
+		val2, found2, err2 := lookupPath(right, path)
+		if err1 != nil || err2 != nil || found1 != found2 {
+			return false
+		}
+		return !found1 || len(findDifferencesWithOptions(val1, val2, path, checkOptions)) == 0
+	}
+
+	var results []ThreeWayDiff
+	rightGroup := make([]int, len(rightDiffs))
+	for i := range rightGroup {
+		rightGroup[i] = -1
+	}
+
+	for _, l := range leftDiffs {
+		var overlaps []int
+		for i, r := range rightDiffs {
+			if pathsOverlap(l.Path, r.Path) {
+				overlaps = append(overlaps, i)
+			}
+		}
+
+		if len(overlaps) == 0 {
+			results = append(results, ThreeWayDiff{Path: l.Path, Status: ChangedLeft, Left: []Diff{l}})
+			continue
+		}
+
+		r := overlaps[0]
+		if len(overlaps) == 1 && rightGroup[r] < 0 && rightDiffs[r].Path == l.Path && sameChange(l.Path) {
+			rightGroup[r] = len(results)
+			results = append(results, ThreeWayDiff{Path: l.Path, Status: ChangedBoth, Left: []Diff{l}, Right: []Diff{rightDiffs[r]}})
+			continue
+		}
+
+		// Join any group an overlapping right difference already belongs to
+		g := -1
+		for _, r := range overlaps {
+			if rightGroup[r] >= 0 {
+				g = rightGroup[r]
+				break
+			}
+		}
+		if g < 0 {
+			g = len(results)
+			results = append(results, ThreeWayDiff{Path: l.Path})
+		}
+		group := &results[g]
+		group.Status = Conflict
+		group.Left = append(group.Left, l)
+		if isUnderPath(group.Path, l.Path) {
+			group.Path = l.Path
+		}
+		for _, r := range overlaps {
+			if rightGroup[r] < 0 {
+				rightGroup[r] = g
+				group.Right = append(group.Right, rightDiffs[r])
+				if isUnderPath(group.Path, rightDiffs[r].Path) {
+					group.Path = rightDiffs[r].Path
+				}
+			}
+		}
+	}
+
+	for i, r := range rightDiffs {
+		if rightGroup[i] < 0 {
+			results = append(results, ThreeWayDiff{Path: r.Path, Status: ChangedRight, Right: []Diff{r}})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+	return results
+}
+
+// countConflicts returns the number of conflicting changes
+func countConflicts(results []ThreeWayDiff) int {
+	conflicts := 0
+	for _, result := range results {
+		if result.Status == Conflict {
+			conflicts++
+		}
+	}
+	return conflicts
+}
+
+// formatThreeWayDiff renders a change in the human-readable console format.
+// One-sided and identical changes are tagged [left], [right] or [both];
+// conflicts are tagged [CONFLICT] and list each side's differences.
+func formatThreeWayDiff(result ThreeWayDiff) string {
+	var sb strings.Builder
+	switch result.Status {
+	case ChangedLeft:
+		for _, diff := range result.Left {
+			sb.WriteString("[left] " + formatDiffText(diff))
+		}
+	case ChangedRight:
+		for _, diff := range result.Right {
+			sb.WriteString("[right] " + formatDiffText(diff))
+		}
+	case ChangedBoth:
+		sb.WriteString("[both] " + formatDiffText(result.Left[0]))
+	case Conflict:
+		fmt.Fprintf(&sb, "[CONFLICT] %s\n", displayPath(result.Path))
+		for _, side := range []struct {
+			name  string
+			diffs []Diff
+		}{{"left", result.Left}, {"right", result.Right}} {
+			for _, diff := range side.diffs {
+				text := strings.TrimSuffix(formatDiffText(diff), "\n")
+				fmt.Fprintf(&sb, "  %s: %s\n", side.name, strings.ReplaceAll(text, "\n", "\n  "))
+			}
+		}
+	}
+	return sb.String()
+}
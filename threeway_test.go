@@ -0,0 +1,76 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"testing"
+)
+
+func TestCompareThreeWay(t *testing.T) {
+	base := map[string]interface{}{
+		"name":    "John",
+		"age":     30.0,
+		"email":   "john@example.com",
+		"address": map[string]interface{}{"city": "New York", "zip": "10001"},
+		"tags":    []interface{}{"a"},
+	}
+	left := map[string]interface{}{
+		"name":    "Johnny",
+		"age":     31.0,
+		"email":   "johnny@example.com",
+		"address": map[string]interface{}{"city": "Boston", "zip": "02101"},
+		"tags":    []interface{}{"a"},
+	}
+	right := map[string]interface{}{
+		"name":  "John",
+		"age":   31.0,
+		"email": "j@example.com",
+		"tags":  []interface{}{"a", "b"},
+	}
+
+	results := compareThreeWay(base, left, right, CompareOptions{})
+
+	expected := []struct {
+		path   string
+		status MergeStatus
+		left   int
+		right  int
+	}{
+		{"address", Conflict, 2, 1}, // Both edits on the left conflict with the removal on the right
+		{"age", ChangedBoth, 1, 1},
+		{"email", Conflict, 1, 1},
+		{"name", ChangedLeft, 1, 0},
+		{"tags", ChangedRight, 0, 1},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d changes, got %v", len(expected), results)
+	}
+	for i, exp := range expected {
+		result := results[i]
+		if result.Path != exp.path || result.Status != exp.status || len(result.Left) != exp.left || len(result.Right) != exp.right {
+			t.Errorf("Expected %s %s with %d/%d differences, got %s %s with %d/%d",
+				exp.path, exp.status, exp.left, exp.right, result.Path, result.Status, len(result.Left), len(result.Right))
+		}
+	}
+	if conflicts := countConflicts(results); conflicts != 2 {
+		t.Errorf("Expected 2 conflicts, got %d", conflicts)
+	}
+
+	conflict := "[CONFLICT] email\n  left: email: value mismatch\n  - john@example.com\n  + johnny@example.com\n  right: email: value mismatch\n  - john@example.com\n  + j@example.com\n"
+	if got := formatThreeWayDiff(results[2]); got != conflict {
+		t.Errorf("Expected %q, got %q", conflict, got)
+	}
+	if got := formatThreeWayDiff(results[3]); got != "[left] name: value mismatch\n- John\n+ Johnny\n" {
+		t.Errorf("Unexpected output %q", got)
+	}
+
+	// Options decide whether both sides made the same change
+	right["name"] = "JOHNNY"
+	results = compareThreeWay(base, left, right, CompareOptions{IgnoreCaseValues: true})
+	for _, result := range results {
+		if result.Path == "name" && result.Status != ChangedBoth {
+			t.Errorf("Expected name to be changed identically when ignoring case, got %s", result.Status)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Comparators that match values within a threshold
+const (
+	FuzzyLevenshtein    = "levenshtein"
+	FuzzyFloatTolerance = "float-tolerance"
+)
+
+// FuzzyMatch records two values that were only considered equal because
+// the distance between them was within a comparator's threshold
+type FuzzyMatch struct {
+	Path      string
+	Kind      string // Comparator that matched the values, e.g. "levenshtein"
+	Value1    interface{}
+	Value2    interface{}
+	Distance  float64 // How far apart the values were
+	Threshold float64 // Largest distance the comparator accepts
+}
+
+// Margin returns how much further apart the values could have been and still matched
+func (m FuzzyMatch) Margin() float64 {
+	return m.Threshold - m.Distance
+}
+
+// newFuzzyMatch returns a FuzzyMatch for two values, or nil if they were an
+// exact match and the threshold played no part
+func newFuzzyMatch(path, kind string, val1, val2 interface{}, distance, threshold float64) *FuzzyMatch {
+	if !(distance > 0) || threshold <= 0 {
+		return nil
+	}
+	return &FuzzyMatch{
+		Path:      path,
+		Kind:      kind,
+		Value1:    val1,
+		Value2:    val2,
+		Distance:  distance,
+		Threshold: threshold,
+	}
+}
+
+// prefixFuzzyMatches prepends prefix to the path of every match
+func prefixFuzzyMatches(matches []FuzzyMatch, prefix string) []FuzzyMatch {
+	for i := range matches {
+		matches[i].Path = prefixPath(prefix, matches[i].Path)
+	}
+	return matches
+}
+
+// tightestMatches returns up to n matches ordered by how close they came to
+// their threshold. Margins are compared relative to the threshold so that
+// different comparators rank fairly against each other.
+func tightestMatches(matches []FuzzyMatch, n int) []FuzzyMatch {
+	sorted := append([]FuzzyMatch(nil), matches...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ratio1 := sorted[i].Margin() / sorted[i].Threshold
+		ratio2 := sorted[j].Margin() / sorted[j].Threshold
+		if ratio1 != ratio2 {
+			return ratio1 < ratio2
+		}
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	if n > 0 && len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// formatThresholdReport renders the tightest of matches for the console
+func formatThresholdReport(matches []FuzzyMatch, n int) string {
+	if len(matches) == 0 {
+		return "\nNo values were matched within a threshold.\n\n"
+	}
+
+	tightest := tightestMatches(matches, n)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\nClosest threshold matches (%d of %d):\n", len(tightest), len(matches))
+	for _, match := range tightest {
+		fmt.Fprintf(&sb, "- %s: %s distance %.6g of %.6g (margin %.6g): %v vs %v\n",
+			match.Path, match.Kind, match.Distance, match.Threshold, match.Margin(), match.Value1, match.Value2)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFuzzyMatches(t *testing.T) {
+	obj1 := map[string]interface{}{
+		"name":  "Jo",
+		"city":  "New York",
+		"price": 10.0,
+		"tax":   1.0,
+		"total": "11.05",
+	}
+	obj2 := map[string]interface{}{
+		"name":  "John",
+		"city":  "New York",
+		"price": 10.09,
+		"tax":   1.0,
+		"total": 11.0,
+	}
+
+	var matches []FuzzyMatch
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		LevenshteinKeys:      map[string]bool{"name": true, "city": true},
+		LevenshteinThreshold: 3,
+		FloatTolerance:       0.1,
+		IgnoreNumericType:    true,
+		FuzzyMatches:         &matches,
+	})
+	if len(diffs) != 0 {
+		t.Fatalf("Expected no differences, got %d: %v", len(diffs), diffs)
+	}
+
+	// Exact matches are not recorded
+	if len(matches) != 3 {
+		t.Fatalf("Expected 3 fuzzy matches, got %d: %v", len(matches), matches)
+	}
+
+	// price used 0.09 of 0.1, total 0.05 of 0.1 and name 2 of 3
+	tightest := tightestMatches(matches, 2)
+	if len(tightest) != 2 || tightest[0].Path != "price" || tightest[1].Path != "name" {
+		t.Errorf("Unexpected tightest matches: %v", tightest)
+	}
+	if tightest[1].Kind != FuzzyLevenshtein || tightest[1].Margin() != 1 {
+		t.Errorf("Expected name to be a levenshtein match with margin 1, got %v", tightest[1])
+	}
+
+	report := formatThresholdReport(matches, 2)
+	if !strings.Contains(report, "(2 of 3)") ||
+		!strings.Contains(report, "- name: levenshtein distance 2 of 3 (margin 1): Jo vs John") {
+		t.Errorf("Unexpected report:\n%s", report)
+	}
+
+	// Without a recorder nothing is collected and results are unchanged
+	diffs = findDifferencesWithOptions(obj1, obj2, "", CompareOptions{FloatTolerance: 0.01})
+	if len(diffs) != 3 {
+		t.Errorf("Expected 3 differences, got %d: %v", len(diffs), diffs)
+	}
+}
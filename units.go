@@ -0,0 +1,110 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// unitMultipliers maps each supported unit kind to its suffixes and their
+// multipliers. Suffixes are matched case-insensitively for bytes; SI prefixes
+// are case-sensitive because m (milli) and M (mega) differ.
+var unitMultipliers = map[string]map[string]float64{
+	"bytes": {
+		"":    1,
+		"b":   1,
+		"k":   1 << 10,
+		"kb":  1 << 10,
+		"kib": 1 << 10,
+		"m":   1 << 20,
+		"mb":  1 << 20,
+		"mib": 1 << 20,
+		"g":   1 << 30,
+		"gb":  1 << 30,
+		"gib": 1 << 30,
+		"t":   1 << 40,
+		"tb":  1 << 40,
+		"tib": 1 << 40,
+	},
+	"si": {
+		"":  1,
+		"n": 1e-9,
+		"u": 1e-6,
+		"µ": 1e-6,
+		"m": 1e-3,
+		"k": 1e3,
+		"K": 1e3,
+		"M": 1e6,
+		"G": 1e9,
+		"T": 1e12,
+	},
+}
+
+// validUnit reports whether unit is a supported unit kind
+func validUnit(unit string) bool {
+	_, ok := unitMultipliers[unit]
+	return ok
+}
+
+// parseUnitValue converts a value such as "1KB" or "1.5k" into a number in the
+// base unit of the given kind. Plain numbers and numeric strings are returned as is.
+func parseUnitValue(val interface{}, unit string) (float64, bool) {
+	if num, ok := numberValue(val); ok {
+		return num, true
+	}
+	str, ok := val.(string)
+	if !ok {
+		return 0, false
+	}
+	multipliers, ok := unitMultipliers[unit]
+	if !ok {
+		return 0, false
+	}
+
+	// Split the string into its number and suffix
+	str = strings.TrimSpace(str)
+	split := strings.LastIndexFunc(str, func(r rune) bool {
+		return unicode.IsDigit(r) || r == '.'
+	}) + 1
+	number, suffix := str[:split], strings.TrimSpace(str[split:])
+
+	num, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if unit == "bytes" {
+		suffix = strings.ToLower(suffix)
+	}
+	multiplier, ok := multipliers[suffix]
+	if !ok {
+		return 0, false
+	}
+	return num * multiplier, true
+}
+
+// compareUnitValues compares two values after converting them to the base unit
+// Returns whether they are equal, and whether both values could be parsed
+func compareUnitValues(val1, val2 interface{}, unit string) (bool, bool) {
+	num1, ok1 := parseUnitValue(val1, unit)
+	num2, ok2 := parseUnitValue(val2, unit)
+	if !ok1 || !ok2 {
+		return false, false
+	}
+	return num1 == num2, true
+}
+
+// describeUnitValues reports two values in the base unit, e.g. "bytes 1024 != 2048"
+func describeUnitValues(val1, val2 interface{}, unit string) (string, bool) {
+	num1, ok1 := parseUnitValue(val1, unit)
+	num2, ok2 := parseUnitValue(val2, unit)
+	if !ok1 || !ok2 {
+		return "", false
+	}
+	return fmt.Sprintf("%s %s != %s", unit,
+		strconv.FormatFloat(num1, 'f', -1, 64), strconv.FormatFloat(num2, 'f', -1, 64)), true
+}
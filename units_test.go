@@ -0,0 +1,72 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"testing"
+)
+
+func TestParseUnitValue(t *testing.T) {
+	testCases := []struct {
+		val      interface{}
+		unit     string
+		expected float64
+		ok       bool
+	}{
+		{"1024", "bytes", 1024, true},
+		{1024.0, "bytes", 1024, true},
+		{"1KB", "bytes", 1024, true},
+		{"1 kib", "bytes", 1024, true},
+		{"1.5MB", "bytes", 1.5 * 1024 * 1024, true},
+		{"2G", "bytes", 2 << 30, true},
+		{"1.5k", "si", 1500, true},
+		{"250m", "si", 0.25, true},
+		{"3M", "si", 3e6, true},
+		{"1XB", "bytes", 0, false},
+		{"large", "bytes", 0, false},
+		{"1KB", "furlongs", 0, false},
+		{true, "bytes", 0, false},
+	}
+
+	for _, tc := range testCases {
+		num, ok := parseUnitValue(tc.val, tc.unit)
+		if ok != tc.ok || num != tc.expected {
+			t.Errorf("parseUnitValue(%v, %s) = %v, %v, want %v, %v", tc.val, tc.unit, num, ok, tc.expected, tc.ok)
+		}
+	}
+}
+
+func TestUnitKeys(t *testing.T) {
+	obj1 := map[string]interface{}{"size": "1KB", "limit": "2MB", "label": "1KB"}
+	obj2 := map[string]interface{}{"size": 1024.0, "limit": "1MB", "label": "1024"}
+
+	diffs := findDifferencesWithOptions(obj1, obj2, "", CompareOptions{
+		UnitKeys: map[string]string{"size": "bytes", "limit": "bytes"},
+	})
+
+	// Keys without a unit are compared as before
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 differences, got %d: %v", len(diffs), diffs)
+	}
+	if diffs[0].Path != "label" || diffs[0].Detail != "" {
+		t.Errorf("Expected a plain difference at label, got %v", diffs[0])
+	}
+	if diffs[1].Path != "limit" || diffs[1].Detail != "bytes 2097152 != 1048576" {
+		t.Errorf("Expected a normalized difference at limit, got %v", diffs[1])
+	}
+}
+
+func TestUnitKeysRedacted(t *testing.T) {
+	obj1 := map[string]interface{}{"limit": "2MB"}
+	obj2 := map[string]interface{}{"limit": "1MB"}
+	options := CompareOptions{UnitKeys: map[string]string{"limit": "bytes"}}
+
+	// The detail gives the parsed sizes, so it is dropped along with the values
+	for _, fields := range [][]string{nil, {"limit"}} {
+		diffs := redactDifferences(findDifferencesWithOptions(obj1, obj2, "", options), fields)
+		if len(diffs) != 1 || diffs[0].Detail != "" || diffs[0].Value1 != "<redacted len=3>" {
+			t.Errorf("Expected the redacted limit without a detail, got %v", diffs)
+		}
+	}
+}
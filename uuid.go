@@ -0,0 +1,43 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import "strings"
+
+// normalizeUUID returns the canonical lowercase, hyphenated form of a UUID
+// written with any case, with or without hyphens, braces or a "urn:uuid:"
+// prefix. The boolean result is false if s is not a UUID.
+func normalizeUUID(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "urn:uuid:")
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		s = s[1 : len(s)-1]
+	}
+	hex := strings.ReplaceAll(s, "-", "")
+	if len(hex) != 32 || strings.Trim(hex, "0123456789abcdef") != "" {
+		return "", false
+	}
+	// Hyphens, if any, must be in the usual places
+	canonical := hex[:8] + "-" + hex[8:12] + "-" + hex[12:16] + "-" + hex[16:20] + "-" + hex[20:]
+	if hex != s && s != canonical {
+		return "", false
+	}
+	return canonical, true
+}
+
+// compareUUID compares two values as UUIDs, ignoring case and formatting.
+// The second result is false if either value is not a UUID string.
+func compareUUID(val1, val2 interface{}) (bool, bool) {
+	str1, isStr1 := val1.(string)
+	str2, isStr2 := val2.(string)
+	if !isStr1 || !isStr2 {
+		return false, false // Not comparing strings
+	}
+	uuid1, ok1 := normalizeUUID(str1)
+	uuid2, ok2 := normalizeUUID(str2)
+	if !ok1 || !ok2 {
+		return false, false
+	}
+	return uuid1 == uuid2, true
+}
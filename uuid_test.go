@@ -0,0 +1,53 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import "testing"
+
+func TestNormalizeUUID(t *testing.T) {
+	const canonical = "550e8400-e29b-41d4-a716-446655440000"
+	for _, s := range []string{
+		canonical,
+		"550E8400-E29B-41D4-A716-446655440000",
+		"550e8400e29b41d4a716446655440000",
+		"{550e8400-e29b-41d4-a716-446655440000}",
+		"urn:uuid:550e8400-e29b-41d4-a716-446655440000",
+	} {
+		if got, ok := normalizeUUID(s); !ok || got != canonical {
+			t.Errorf("normalizeUUID(%q) = %q, %v", s, got, ok)
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"not-a-uuid",
+		"550e8400-e29b-41d4-a716-44665544000",   // Too short
+		"550e8400-e29b-41d4-a716-44665544000g",  // Not hex
+		"550e8400e-29b-41d4-a716-446655440000",  // Hyphens out of place
+		"550e-8400-e29b-41d4-a716446655440000",  // Hyphens out of place
+		"550e8400-e29b-41d4-a716-4466554400000", // Too long
+	} {
+		if _, ok := normalizeUUID(s); ok {
+			t.Errorf("Expected %q not to be a UUID", s)
+		}
+	}
+}
+
+func TestUUIDKeys(t *testing.T) {
+	obj1 := map[string]interface{}{"id": "550E8400E29B41D4A716446655440000", "ref": "550E8400E29B41D4A716446655440000", "other": "ABC"}
+	obj2 := map[string]interface{}{"id": "550e8400-e29b-41d4-a716-446655440000", "ref": "550e8400-e29b-41d4-a716-446655440000", "other": "abc"}
+	options := CompareOptions{UUIDKeys: map[string]bool{"id": true, "other": true}}
+
+	// Only listed keys are normalized, and non-UUID strings are compared as usual
+	differences := findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(differences) != 2 || differences[0].Path != "other" || differences[1].Path != "ref" {
+		t.Errorf("Expected other and ref to differ, got %v", differences)
+	}
+
+	obj2["id"] = "550e8400-e29b-41d4-a716-446655440001"
+	differences = findDifferencesWithOptions(obj1, obj2, "", options)
+	if len(differences) != 3 || differences[0].Path != "id" {
+		t.Errorf("Expected different UUIDs to differ, got %v", differences)
+	}
+}
@@ -4,10 +4,15 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/agnivade/levenshtein"
 )
 
@@ -22,61 +27,47 @@ func isComplex(val interface{}) bool {
 }
 
 // compareBooleanValues compares two values as booleans, ignoring their original types
-// Returns true if both values can be converted to booleans and are equal
-func compareBooleanValues(val1, val2 interface{}) (bool, bool) {
-	// Extract boolean values
-	bool1, isBool1 := val1.(bool)
-	boolStr1, isBoolStr1 := val1.(string)
-	bool2, isBool2 := val2.(bool)
-	boolStr2, isBoolStr2 := val2.(string)
-	
-	// Check if we're comparing boolean values
-	if !isBool1 && !isBoolStr1 && !isBool2 && !isBoolStr2 {
-		// Not comparing booleans
-		return false, false
-	}
-	
+// Returns true if both values can be converted to booleans and are equal.
+// With numeric, the numbers 1 and 0 also count as true and false.
+func compareBooleanValues(val1, val2 interface{}, numeric bool) (bool, bool) {
 	// Convert to boolean values
-	var b1, b2 bool
-	var ok1, ok2 bool
-	
-	if isBool1 {
-		b1 = bool1
-		ok1 = true
-	} else if isBoolStr1 {
-		lowerStr := strings.ToLower(boolStr1)
-		if lowerStr == "true" {
-			b1 = true
-			ok1 = true
-		} else if lowerStr == "false" {
-			b1 = false
-			ok1 = true
-		}
-	}
-	
-	if isBool2 {
-		b2 = bool2
-		ok2 = true
-	} else if isBoolStr2 {
-		lowerStr := strings.ToLower(boolStr2)
-		if lowerStr == "true" {
-			b2 = true
-			ok2 = true
-		} else if lowerStr == "false" {
-			b2 = false
-			ok2 = true
-		}
-	}
-	
+	b1, ok1 := booleanValue(val1, numeric)
+	b2, ok2 := booleanValue(val2, numeric)
+
 	// If both values could be converted to booleans, compare them
 	if ok1 && ok2 {
 		return b1 == b2, true
 	}
-	
+
 	// Couldn't convert both values to booleans
 	return false, false
 }
 
+// booleanValue converts a boolean or a "true"/"false" string (in any case) to
+// a boolean. With numeric, the numbers 1 and 0 are converted too; other
+// numbers are not booleans.
+func booleanValue(val interface{}, numeric bool) (bool, bool) {
+	switch v := val.(type) {
+	case bool:
+		return v, true
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+		return false, false
+	}
+
+	if numeric {
+		if num, ok := numberValue(val); ok && (num == 1 || num == 0) {
+			return num == 1, true
+		}
+	}
+	return false, false
+}
+
 // convertToFloat64 attempts to convert a value to float64
 // Returns the converted value and a boolean indicating success
 func convertToFloat64(val interface{}) (float64, bool) {
@@ -91,6 +82,11 @@ func convertToFloat64(val interface{}) (float64, bool) {
 		return float64(v), true
 	case int32:
 		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		if err == nil {
+			return f, true
+		}
 	case string:
 		// Try to parse string as number
 		f, err := strconv.ParseFloat(v, 64)
@@ -101,22 +97,64 @@ func convertToFloat64(val interface{}) (float64, bool) {
 	return 0, false
 }
 
+// numberValue returns the value of a JSON number, whether decoded as float64
+// or kept as json.Number. Unlike convertToFloat64, strings are not numbers.
+func numberValue(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
 // compareNumericValues compares two values as numbers, ignoring their original types
-// Returns true if both values can be converted to numbers and are equal
-func compareNumericValues(val1, val2 interface{}) bool {
+// Returns true if both values can be converted to numbers and are equal within tolerance
+// With leftOnly, only val1 may be a numeric string; val2 must already be a number
+func compareNumericValues(val1, val2 interface{}, tolerance float64, leftOnly bool) bool {
+	if _, isStr2 := val2.(string); leftOnly && isStr2 {
+		// The right side is authoritative, so its strings are never parsed
+		return false
+	}
+
 	// Try to convert both values to float64
 	num1, ok1 := convertToFloat64(val1)
 	num2, ok2 := convertToFloat64(val2)
 
 	// Compare the numeric values if both conversions succeeded
 	if ok1 && ok2 {
-		return num1 == num2
+		return withinTolerance(num1, num2, tolerance)
 	}
 	
 	// Values couldn't be compared as numbers
 	return false
 }
 
+// withinTolerance checks if two numbers differ by no more than tolerance
+// A tolerance of zero requires the numbers to be exactly equal
+func withinTolerance(num1, num2, tolerance float64) bool {
+	// Checked first so infinities of the same sign are equal at any tolerance
+	if tolerance <= 0 || num1 == num2 {
+		return num1 == num2
+	}
+	return math.Abs(num1-num2) <= tolerance
+}
+
+// ErrBadPattern is returned, wrapped with the pattern and the parse error, for
+// a regex pattern that does not compile
+var ErrBadPattern = errors.New("invalid regex pattern")
+
+// compileRegex compiles a regex pattern, wrapping any error in ErrBadPattern
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrBadPattern, pattern, err)
+	}
+	return re, nil
+}
+
 // matchesRegex checks if both values match the given regex pattern
 // Returns true if both values are strings and match the pattern
 func matchesRegex(val1, val2 interface{}, pattern string) (bool, error) {
@@ -129,7 +167,7 @@ func matchesRegex(val1, val2 interface{}, pattern string) (bool, error) {
 	}
 	
 	// Compile the regex pattern
-	re, err := regexp.Compile(pattern)
+	re, err := compileRegex(pattern)
 	if err != nil {
 		return false, err
 	}
@@ -138,6 +176,50 @@ func matchesRegex(val1, val2 interface{}, pattern string) (bool, error) {
 	return re.MatchString(str1) && re.MatchString(str2), nil
 }
 
+// parseSemverPair parses two values as semantic versions
+// Missing minor and patch components are treated as zero (e.g., "1.2" == "1.2.0")
+func parseSemverPair(val1, val2 interface{}) (*semver.Version, *semver.Version, bool) {
+	str1, isStr1 := val1.(string)
+	str2, isStr2 := val2.(string)
+	if !isStr1 || !isStr2 {
+		return nil, nil, false // Not comparing strings
+	}
+
+	v1, err1 := semver.NewVersion(str1)
+	v2, err2 := semver.NewVersion(str2)
+	if err1 != nil || err2 != nil {
+		return nil, nil, false // Not semantic versions
+	}
+	return v1, v2, true
+}
+
+// compareSemver compares two values as semantic versions
+// Returns whether they are equal, and whether both values could be parsed as versions
+func compareSemver(val1, val2 interface{}) (bool, bool) {
+	v1, v2, ok := parseSemverPair(val1, val2)
+	if !ok {
+		return false, false
+	}
+	return v1.Equal(v2), true
+}
+
+// describeSemver reports how two semantic versions compare, e.g. "semver 1.2.0 < 1.3.0"
+func describeSemver(val1, val2 interface{}) (string, bool) {
+	v1, v2, ok := parseSemverPair(val1, val2)
+	if !ok {
+		return "", false
+	}
+
+	op := "=="
+	switch v1.Compare(v2) {
+	case -1:
+		op = "<"
+	case 1:
+		op = ">"
+	}
+	return fmt.Sprintf("semver %s %s %s", v1, op, v2), true
+}
+
 // compareLevenshtein checks if two values are similar using Levenshtein distance
 // Returns true if both values are strings and their Levenshtein distance is within the threshold
 func compareLevenshtein(val1, val2 interface{}, threshold int) bool {
@@ -154,4 +236,75 @@ func compareLevenshtein(val1, val2 interface{}, threshold int) bool {
 	
 	// Return true if distance is within threshold
 	return distance <= threshold
-}
\ No newline at end of file
+}
+// levenshteinDistance computes the Levenshtein distance between two values
+// Returns false if either value is not a string
+func levenshteinDistance(val1, val2 interface{}) (int, bool) {
+	str1, isStr1 := val1.(string)
+	str2, isStr2 := val2.(string)
+	if !isStr1 || !isStr2 {
+		return 0, false // Not comparing strings
+	}
+	return levenshtein.ComputeDistance(str1, str2), true
+}
+
+// numericDistance computes the absolute difference between two values compared as numbers
+// Returns false if either value cannot be converted to a number
+func numericDistance(val1, val2 interface{}) (float64, bool) {
+	num1, ok1 := convertToFloat64(val1)
+	num2, ok2 := convertToFloat64(val2)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	return math.Abs(num1 - num2), true
+}
+
+// ungroupNumber rewrites a numeric string written with locale separators,
+// such as "1,234.56" or "1.234,56", into the plain form convertToFloat64 parses.
+// Groups must be three digits long. Values that aren't such strings are returned as is.
+func ungroupNumber(val interface{}, decimalSep, groupSep string) interface{} {
+	str, ok := val.(string)
+	if !ok || decimalSep == "" || decimalSep == groupSep {
+		return val
+	}
+	str = strings.TrimSpace(str)
+
+	sign := ""
+	if strings.HasPrefix(str, "-") || strings.HasPrefix(str, "+") {
+		sign, str = str[:1], str[1:]
+	}
+
+	intPart, fracPart, hasFrac := strings.Cut(str, decimalSep)
+	if hasFrac && !isDigits(fracPart) {
+		return val
+	}
+
+	groups := []string{intPart}
+	if groupSep != "" {
+		groups = strings.Split(intPart, groupSep)
+	}
+	for i, group := range groups {
+		if !isDigits(group) || (i == 0 && len(groups) > 1 && len(group) > 3) || (i > 0 && len(group) != 3) {
+			return val
+		}
+	}
+
+	number := sign + strings.Join(groups, "")
+	if hasFrac {
+		number += "." + fracPart
+	}
+	return number
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// version is the build version, set when building a release with
+// go build -ldflags "-X main.version=v1.2.3"
+var version = "dev"
+
+// formatVersion is the version of the schema of differences in JSON output.
+// It is bumped whenever the serialized fields of Diff change; version 2 added
+// the source locations loc1 and loc2.
+const formatVersion = 2
+
+// VersionedDifferences is the JSON output written with -json-version, naming
+// the tool and schema versions that produced it
+type VersionedDifferences struct {
+	JSONDiffVersion string          `json:"jsondiffVersion"`
+	FormatVersion   int             `json:"formatVersion"`
+	Differences     json.RawMessage `json:"differences"`
+}
+
+// marshalVersionedDifferences renders differences as indented JSON wrapped
+// in an object with the jsondiff and format versions
+func marshalVersionedDifferences(differences []Diff) ([]byte, error) {
+	encoded, err := marshalDifferences(differences)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(VersionedDifferences{
+		JSONDiffVersion: version,
+		FormatVersion:   formatVersion,
+		Differences:     encoded,
+	}, "", "  ")
+}
+
+// writeVersionedDifferencesJSON writes differences wrapped with the jsondiff
+// and format versions as indented JSON to filePath
+func writeVersionedDifferencesJSON(differences []Diff, filePath string) error {
+	outputJSON, err := marshalVersionedDifferences(differences)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, outputJSON, 0644)
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMarshalVersionedDifferences(t *testing.T) {
+	outputJSON, err := marshalVersionedDifferences([]Diff{{Path: "a", Type: ValueMismatch, Value1: 1.0, Value2: 2.0}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var decoded struct {
+		JSONDiffVersion string                   `json:"jsondiffVersion"`
+		FormatVersion   int                      `json:"formatVersion"`
+		Differences     []map[string]interface{} `json:"differences"`
+	}
+	if err := json.Unmarshal(outputJSON, &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if decoded.JSONDiffVersion != version || decoded.FormatVersion != formatVersion {
+		t.Errorf("Expected versions %s and %d, got %s and %d", version, formatVersion, decoded.JSONDiffVersion, decoded.FormatVersion)
+	}
+	if len(decoded.Differences) != 1 || decoded.Differences[0]["path"] != "a" {
+		t.Errorf("Unexpected differences: %v", decoded.Differences)
+	}
+}
+
+func TestFormatVersionMatchesDiffSchema(t *testing.T) {
+	// If this fails, the serialized fields of Diff changed: bump formatVersion and add its fields
+	outputJSON, err := json.Marshal(Diff{Detail: "x", Tags: []string{"x"}, Loc1: &Location{}, Loc2: &Location{}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(outputJSON, &fields); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	schemas := map[int][]string{
+		1: {"detail", "parentType", "path", "severity", "tags", "type", "value1", "value2"},
+		2: {"detail", "loc1", "loc2", "parentType", "path", "severity", "tags", "type", "value1", "value2"},
+	}
+	if want := schemas[formatVersion]; !reflect.DeepEqual(names, want) {
+		t.Errorf("Diff fields are %v, format version %d has %v", names, formatVersion, want)
+	}
+}
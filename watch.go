@@ -0,0 +1,118 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadWatchFile reads the paths listed in a watch file, one per line.
+// Blank lines and lines starting with # are ignored.
+func loadWatchFile(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watch file: %v", err)
+	}
+	defer file.Close()
+
+	var paths []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := parsePath(line); err != nil {
+			return nil, fmt.Errorf("invalid path %q in watch file: %v", line, err)
+		}
+		paths = append(paths, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read watch file: %v", err)
+	}
+
+	return paths, nil
+}
+
+// pathParentType returns the kind of container the last segment of path is in
+func pathParentType(path string) ParentType {
+	switch {
+	case path == "":
+		return ParentRoot
+	case strings.HasSuffix(path, "]"):
+		return ParentArray
+	default:
+		return ParentObject
+	}
+}
+
+// compareWatchedPaths compares only the values at the given paths, ignoring
+// the rest of both documents. A path missing from one document is reported as
+// a key only in the other; a path missing from both is a value mismatch.
+func compareWatchedPaths(data1, data2 interface{}, paths []string, options CompareOptions) []Diff {
+	differences := []Diff{}
+
+	for _, path := range paths {
+		val1, found1, err1 := lookupPath(data1, path)
+		val2, found2, err2 := lookupPath(data2, path)
+		if err1 != nil || err2 != nil {
+			// Paths are validated when the watch file is loaded
+			continue
+		}
+
+		switch {
+		case found1 && found2:
+			differences = append(differences, findDifferencesWithParent(val1, val2, path, pathParentType(path), options)...)
+		case found1:
+			differences = append(differences, Diff{
+				Path:       path,
+				Type:       KeyOnlyInFirst,
+				Value1:     val1,
+				Value2:     nil,
+				ParentType: pathParentType(path),
+			})
+		case found2:
+			differences = append(differences, Diff{
+				Path:       path,
+				Type:       KeyOnlyInSecond,
+				Value1:     nil,
+				Value2:     val2,
+				ParentType: pathParentType(path),
+			})
+		default:
+			differences = append(differences, Diff{
+				Path:       path,
+				Type:       ValueMismatch,
+				Value1:     nil,
+				Value2:     nil,
+				ParentType: pathParentType(path),
+				Detail:     "not found in either file",
+			})
+		}
+	}
+
+	differences = notifyDiffs(assignTags(assignSeverities(differences, options.SeverityOverrides), options.PathTags), options)
+	return filterPathExprs(differences, options.IgnorePaths, options.OnlyPaths)
+}
+
+// formatWatchReport renders whether each watched path is equal or differs
+func formatWatchReport(paths []string, differences []Diff) string {
+	var sb strings.Builder
+	sb.WriteString("\nWatched paths:\n")
+	for _, path := range paths {
+		status := "equal"
+		for _, diff := range differences {
+			if isUnderPath(diff.Path, path) {
+				status = "differ"
+				break
+			}
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", path, status)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWatchedPaths(t *testing.T) {
+	paths, err := loadWatchFile("examples/watch.txt")
+	if err != nil {
+		t.Fatalf("Failed to load examples/watch.txt: %v", err)
+	}
+	if len(paths) != 4 {
+		t.Fatalf("Expected 4 watched paths, got %v", paths)
+	}
+
+	file1, err := ReadAndValidateJSON("examples/example1.json", true)
+	if err != nil {
+		t.Fatalf("Failed to read examples/example1.json: %v", err)
+	}
+	file2, err := ReadAndValidateJSON("examples/example2.json", true)
+	if err != nil {
+		t.Fatalf("Failed to read examples/example2.json: %v", err)
+	}
+
+	// name and age differ too, but aren't watched
+	diffs := compareWatchedPaths(file1.Data, file2.Data, paths, CompareOptions{})
+	if len(diffs) != 3 {
+		t.Fatalf("Expected 3 differences, got %d: %v", len(diffs), diffs)
+	}
+	if diffs[0].Path != "address.city" || diffs[1].Path != "hobbies[1]" || diffs[1].ParentType != ParentArray {
+		t.Errorf("Unexpected differences: %v", diffs)
+	}
+	if diffs[2].Path != "email" || diffs[2].Detail != "not found in either file" {
+		t.Errorf("Expected email to be reported as missing, got %v", diffs[2])
+	}
+
+	report := formatWatchReport(paths, diffs)
+	for _, expected := range []string{"address.street: equal\n", "address.city: differ\n", "email: differ\n"} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("Expected report to contain %q, got:\n%s", expected, report)
+		}
+	}
+}
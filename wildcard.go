@@ -0,0 +1,11 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+// isWildcard reports whether a value from the first file is the wildcard
+// sentinel, which accepts any value at the same path in the second file
+func isWildcard(val interface{}, options CompareOptions) bool {
+	str, ok := val.(string)
+	return ok && options.WildcardValue != "" && str == options.WildcardValue
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import "testing"
+
+func TestWildcardValue(t *testing.T) {
+	expected := map[string]interface{}{
+		"id":      "<any>",
+		"status":  "ok",
+		"owner":   "<any>",
+		"created": "<any>",
+		"tags":    []interface{}{"<any>", "b"},
+	}
+	actual := map[string]interface{}{
+		"id":     42.0,
+		"status": "ok",
+		"owner":  map[string]interface{}{"name": "Ada"},
+		"tags":   []interface{}{"a", "c"},
+	}
+	options := CompareOptions{WildcardValue: "<any>"}
+
+	diffs := findDifferencesWithOptions(expected, actual, "", options)
+	if len(diffs) != 2 {
+		t.Fatalf("Expected two differences, got %v", diffs)
+	}
+	// The key must still be present
+	if diffs[0].Path != "created" || diffs[0].Type != KeyOnlyInFirst {
+		t.Errorf("Expected created to be missing, got %v", diffs[0])
+	}
+	if diffs[1].Path != "tags[1]" || diffs[1].Type != ValueMismatch {
+		t.Errorf("Expected tags[1] to differ, got %v", diffs[1])
+	}
+
+	// Only the first file holds wildcards
+	if diffs := findDifferencesWithOptions(actual, expected, "", options); len(diffs) != 5 {
+		t.Errorf("Expected a wildcard in the second file to be an ordinary string, got %v", diffs)
+	}
+
+	// Without the option the sentinel is an ordinary string
+	if diffs := findDifferencesWithOptions(expected, actual, "", CompareOptions{}); len(diffs) != 5 {
+		t.Errorf("Expected five differences without the option, got %v", diffs)
+	}
+}
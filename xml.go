@@ -0,0 +1,139 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrInvalidXML is returned, wrapped with details, for a document that is not
+// valid XML. It wraps ErrInvalidJSON, so an invalid XML document is handled like
+// an invalid JSON one.
+var ErrInvalidXML error = invalidXMLError{}
+
+// invalidXMLError is the type of ErrInvalidXML
+type invalidXMLError struct{}
+
+func (invalidXMLError) Error() string { return "invalid XML" }
+func (invalidXMLError) Unwrap() error { return ErrInvalidJSON }
+
+// xmlElement is an element being built while decoding
+type xmlElement struct {
+	name   string
+	fields map[string]interface{}
+	text   strings.Builder
+}
+
+// xmlName renders an element or attribute name. Namespaced names use Clark
+// notation ({uri}local) so that documents using different prefixes for the
+// same namespace map to the same keys.
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return "{" + name.Space + "}" + name.Local
+}
+
+// decodeXML parses an XML document into the same generic structure that
+// encoding/json produces, so the diff engine can compare it unchanged:
+//
+//   - the root element becomes a single-key object: {"root": ...}
+//   - attributes become "@name" keys and are always strings
+//   - child elements become keys; an element repeated under the same parent becomes an array,
+//     as does every element named in arrays, so it has the same shape however often it occurs
+//   - an element with neither attributes nor children becomes its text as a string
+//   - otherwise non-whitespace text is stored under "#text"
+//
+// Namespace declarations (xmlns attributes) are not data and are dropped.
+func decodeXML(data []byte, arrays map[string]bool) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var stack []*xmlElement
+	var root interface{}
+	var rootName string
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidXML, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if len(stack) == 0 && root != nil {
+				return nil, fmt.Errorf("%w: multiple root elements", ErrInvalidXML)
+			}
+
+			element := &xmlElement{name: xmlName(t.Name), fields: make(map[string]interface{})}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				element.fields["@"+xmlName(attr.Name)] = attr.Value
+			}
+			stack = append(stack, element)
+
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+
+		case xml.EndElement:
+			element := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			value := element.value()
+
+			if len(stack) == 0 {
+				root = value
+				rootName = element.name
+				continue
+			}
+			stack[len(stack)-1].addChild(element.name, value, arrays[element.name])
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("%w: no root element", ErrInvalidXML)
+	}
+
+	return map[string]interface{}{rootName: root}, nil
+}
+
+// addChild stores a child element's value, turning repeated names into arrays.
+// Element values are only ever strings or objects, so an existing array always
+// means the name has already been repeated or is always an array.
+func (e *xmlElement) addChild(name string, value interface{}, array bool) {
+	switch existing := e.fields[name].(type) {
+	case nil:
+		if array {
+			e.fields[name] = []interface{}{value}
+		} else {
+			e.fields[name] = value
+		}
+	case []interface{}:
+		e.fields[name] = append(existing, value)
+	default:
+		e.fields[name] = []interface{}{existing, value}
+	}
+}
+
+// value converts the finished element into its generic representation
+func (e *xmlElement) value() interface{} {
+	text := strings.TrimSpace(e.text.String())
+
+	if len(e.fields) == 0 {
+		return text
+	}
+	if text != "" {
+		e.fields["#text"] = text
+	}
+	return e.fields
+}
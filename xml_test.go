@@ -0,0 +1,94 @@
+// Copyright (c) 2023 Chris Sewell
+// Licensed under the MIT License
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeXML(t *testing.T) {
+	data := `<?xml version="1.0"?>
+<user id="42" xmlns:a="urn:example">
+  <name>John</name>
+  <phone type="home">555-1234</phone>
+  <phone type="work">555-5678</phone>
+  <a:note>hello</a:note>
+  <empty/>
+</user>`
+
+	decoded, err := decodeXML([]byte(data), nil)
+	if err != nil {
+		t.Fatalf("Failed to decode XML: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"user": map[string]interface{}{
+			"@id":  "42",
+			"name": "John",
+			"phone": []interface{}{
+				map[string]interface{}{"@type": "home", "#text": "555-1234"},
+				map[string]interface{}{"@type": "work", "#text": "555-5678"},
+			},
+			"{urn:example}note": "hello",
+			"empty":             "",
+		},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Unexpected XML mapping:\n got: %v\nwant: %v", decoded, expected)
+	}
+
+	// Prefixes don't matter, only the namespace they refer to
+	other, err := decodeXML([]byte(`<user id="42" xmlns:b="urn:example"><name>Jane</name><phone type="home">555-1234</phone><phone type="work">555-5678</phone><b:note>hello</b:note><empty></empty></user>`), nil)
+	if err != nil {
+		t.Fatalf("Failed to decode XML: %v", err)
+	}
+	diffs := findDifferencesWithOptions(decoded, other, "", CompareOptions{})
+	if len(diffs) != 1 || diffs[0].Path != "user.name" {
+		t.Errorf("Expected a single difference at user.name, got %v", diffs)
+	}
+}
+
+func TestDecodeXMLArrays(t *testing.T) {
+	one := `<order><item>apple</item></order>`
+	two := `<order><item>apple</item><item>pear</item></order>`
+
+	// By default a single element is a string and repeated ones an array
+	decoded1, _ := decodeXML([]byte(one), nil)
+	decoded2, _ := decodeXML([]byte(two), nil)
+	diffs := findDifferencesWithOptions(decoded1, decoded2, "", CompareOptions{})
+	if len(diffs) != 1 || diffs[0].Type != TypeMismatch {
+		t.Errorf("Expected a type mismatch without -xml-array, got %v", diffs)
+	}
+
+	// Listed elements are always arrays, so the documents only differ in length
+	arrays := map[string]bool{"item": true}
+	decoded1, err := decodeXML([]byte(one), arrays)
+	if err != nil {
+		t.Fatalf("Failed to decode XML: %v", err)
+	}
+	expected := map[string]interface{}{"order": map[string]interface{}{"item": []interface{}{"apple"}}}
+	if !reflect.DeepEqual(decoded1, expected) {
+		t.Errorf("Unexpected XML mapping:\n got: %v\nwant: %v", decoded1, expected)
+	}
+	decoded2, _ = decodeXML([]byte(two), arrays)
+	diffs = findDifferencesWithOptions(decoded1, decoded2, "", CompareOptions{})
+	for _, diff := range diffs {
+		if diff.Type == TypeMismatch {
+			t.Errorf("Expected no type mismatch with -xml-array, got %s", formatDiff(diff))
+		}
+	}
+	if len(diffs) == 0 {
+		t.Error("Expected the extra item to be reported")
+	}
+}
+
+func TestDecodeXMLErrors(t *testing.T) {
+	for _, data := range []string{"", "<a>", "<a></a><b></b>", "not xml"} {
+		if _, err := decodeXML([]byte(data), nil); !errors.Is(err, ErrInvalidXML) || !errors.Is(err, ErrInvalidJSON) || exitCode(err) != exitInvalidJSON {
+			t.Errorf("Expected an invalid XML error decoding %q, got %v", data, err)
+		}
+	}
+}